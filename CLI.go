@@ -2,25 +2,41 @@ package todoapp
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
 )
 
 type CLI struct {
-	todoClient TodoAPIClient
+	todoClient TodoClient
+	profile    CLIProfile
 }
 
-func NewCLI(client TodoAPIClient) *CLI {
+func NewCLI(client TodoClient, profile CLIProfile) *CLI {
 	return &CLI{
 		todoClient: client,
+		profile:    profile,
 	}
 }
 
+// syncingClient is implemented by clients that queue changes while offline,
+// such as OfflineTodoClient.
+type syncingClient interface {
+	Sync() error
+	Pending() []OutboxEntry
+	Conflicts() []SyncConflict
+	ResolveConflict(i int, keepLocal bool) error
+}
+
 func (app *CLI) Start() {
 	slog.Debug("Application started")
 	for {
@@ -34,6 +50,30 @@ func (app *CLI) Start() {
 			"6. Quit",
 		}
 
+		// Options after the fixed ones are numbered in the order they're
+		// shown, as they come and go
+		var syncing syncingClient
+		conflictsOption := ""
+		if s, ok := app.todoClient.(syncingClient); ok {
+			if n := len(s.Pending()); n > 0 {
+				fmt.Printf("(%d change(s) waiting to be sent to the server)\n", n)
+			}
+			if n := len(s.Conflicts()); n > 0 {
+				syncing = s
+				conflictsOption = strconv.Itoa(len(greeting))
+				greeting = append(greeting, fmt.Sprintf("%s. Resolve sync conflicts (%d)", conflictsOption, n))
+			}
+		}
+
+		// Saved views follow the other options
+		viewOptions := map[string]views.View{}
+		savedViews, _ := app.todoClient.GetViews()
+		for _, v := range savedViews {
+			option := strconv.Itoa(len(greeting))
+			viewOptions[option] = v
+			greeting = append(greeting, fmt.Sprintf("%s. Show view: %s", option, v.Name))
+		}
+
 		for _, t := range greeting {
 			fmt.Println(t)
 		}
@@ -51,22 +91,13 @@ func (app *CLI) Start() {
 		switch input {
 		case "1":
 			fmt.Println("*** Your todos are ***")
-			todos, _ := app.todoClient.GetAllTodos()
-			app.showTodos(todos)
-			fmt.Println("Press enter to continue...")
-			fmt.Scanln()
+			app.listTodos(app.todoClient.GetAllTodos())
 		case "2":
 			fmt.Println("*** Your archived/completed todos are ***")
-			todos, _ := app.todoClient.GetTodosByStatus(types.Completed)
-			app.showTodos(todos)
-			fmt.Println("Press enter to continue...")
-			fmt.Scanln()
+			app.listTodos(app.todoClient.GetTodosByStatus(types.Completed))
 		case "3":
 			fmt.Println("*** Your overdue todos are ***")
-			todos, _ := app.todoClient.GetOverdueTodos()
-			app.showTodos(todos)
-			fmt.Println("Press enter to continue...")
-			fmt.Scanln()
+			app.listTodos(app.todoClient.GetOverdueTodos())
 		case "4":
 			app.addNewTodo()
 		case "5":
@@ -75,8 +106,76 @@ func (app *CLI) Start() {
 			fmt.Println("So sad to see you... go.")
 			os.Exit(0)
 		default:
-			fmt.Println("Invalid input.")
-			continue
+			if syncing != nil && input == conflictsOption {
+				app.resolveConflicts(syncing)
+				continue
+			}
+			view, ok := viewOptions[input]
+			if !ok {
+				fmt.Println("Invalid input.")
+				continue
+			}
+			fmt.Printf("*** Your todos in %s are ***\n", view.Name)
+			todos, err := app.todoClient.GetViewTodos(view.Name)
+			switch {
+			case errors.Is(err, ErrOffline):
+				fmt.Println("(The server is unavailable, showing your last fetched todos)")
+			case err != nil:
+				fmt.Println("Could not fetch todos:", err)
+				continue
+			}
+			app.ShowTodoItems(todos, view.ShownColumns())
+			fmt.Println("Press enter to continue...")
+			fmt.Scanln()
+		}
+	}
+}
+
+func (app *CLI) listTodos(todos map[string]types.Todo, err error) {
+	switch {
+	case errors.Is(err, ErrOffline):
+		fmt.Println("(The server is unavailable, showing your last fetched todos)")
+	case err != nil:
+		fmt.Println("Could not fetch todos:", err)
+		return
+	}
+	app.ShowTodos(todos)
+	fmt.Println("Press enter to continue...")
+	fmt.Scanln()
+}
+
+func (app *CLI) resolveConflicts(s syncingClient) {
+	scanner := bufio.NewReader(os.Stdin)
+
+	for len(s.Conflicts()) > 0 {
+		c := s.Conflicts()[0]
+		fmt.Printf("Your offline change (%s %s", c.Entry.Op, c.Entry.Id)
+		if c.Entry.Status != "" {
+			fmt.Printf(" to %s", c.Entry.Status)
+		}
+		fmt.Printf(") could not be applied: %s\n", c.Reason)
+		if c.Server != nil {
+			fmt.Println("The server's copy is:", c.Server.String())
+		}
+
+		fmt.Print("Keep your change (k), discard it (d) or come back later (l)? ")
+		input, _ := scanner.ReadString('\n')
+
+		switch strings.TrimSpace(input) {
+		case "k":
+			if err := s.ResolveConflict(0, true); err != nil {
+				fmt.Println("Could not apply your change:", err)
+				return
+			}
+			fmt.Println("Your change was applied")
+		case "d":
+			if err := s.ResolveConflict(0, false); err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			fmt.Println("Your change was discarded")
+		case "l":
+			return
 		}
 	}
 }
@@ -91,10 +190,40 @@ func (t *CLI) addNewTodo() {
 		desc = strings.TrimSpace(desc)
 	}
 
-	due := t.readDate()
-	t.todoClient.AddTodo(types.NewTodo(desc, due))
+	fmt.Print("Tags: (comma separated, leave blank for none) ")
+	tags, _ := scanner.ReadString('\n')
+
+	fmt.Printf("Priority: (1 to %d, leave blank for none) ", types.MaxPriority)
+	priority, _ := scanner.ReadString('\n')
 
-	fmt.Println("Todo successfully added!")
+	due := t.readDate()
+	todo := types.NewTodo(desc, due)
+	todo.List = t.profile.DefaultList
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			todo.Tags = append(todo.Tags, tag)
+		}
+	}
+	if priority = strings.TrimSpace(priority); priority != "" {
+		var err error
+		if todo.Priority, err = strconv.Atoi(priority); err != nil {
+			fmt.Println("Could not add todo: priority must be a number")
+			return
+		}
+	}
+	if err := todo.Validate(); err != nil {
+		fmt.Println("Could not add todo:", err)
+		return
+	}
+	_, err := t.todoClient.AddTodo(todo)
+	switch {
+	case errors.Is(err, ErrOffline):
+		fmt.Println("The server is unavailable, your todo will be added when it's back.")
+	case err != nil:
+		fmt.Println("Could not add todo:", err)
+	default:
+		fmt.Println("Todo successfully added!")
+	}
 }
 
 // Returning a pointer to time.Time, even though the docs say typically you should pass by value
@@ -126,23 +255,30 @@ func (t *CLI) updateTodo() {
 	scanner := bufio.NewReader(os.Stdin)
 	var input string
 	var id string
-	var err error
 
-	todos, _ := t.todoClient.GetAllTodos()
-	t.showTodos(todos)
+	todos, err := t.todoClient.GetAllTodos()
+	if err != nil && !errors.Is(err, ErrOffline) {
+		fmt.Println("Could not fetch todos:", err)
+		return
+	}
+	t.ShowTodos(todos)
 	for {
 		fmt.Print("State the ID of the todo you wish to update: ")
 		input, _ = scanner.ReadString('\n')
 		input = strings.TrimSpace(input)
-		id = input
-
-		// Check the todo exists
-		_, err = t.todoClient.GetTodo(id)
+		id, err = ResolveHandle(todos, input)
 		if err != nil {
 			fmt.Println(err.Error())
 			continue
 		}
 
+		// Check the todo exists, offline clients return cached todos with an error
+		todo, err := t.todoClient.GetTodo(id)
+		if todo == nil {
+			fmt.Println(err.Error())
+			continue
+		}
+
 		break
 	}
 
@@ -150,14 +286,19 @@ func (t *CLI) updateTodo() {
 		fmt.Println("What status do you want to updated your todo to? (Started or Completed): ")
 		input, _ = scanner.ReadString('\n')
 		input = strings.TrimSpace(input)
-		input = strings.ToUpper(string(input[0])) + input[1:]
+		if input != "" {
+			input = strings.ToUpper(string(input[0])) + input[1:]
+		}
 		if input != "Started" && input != "Completed" {
 			fmt.Println("Status should be Started or Completed")
 			continue
 		}
 
 		err = t.todoClient.UpdateTodoStatus(id, types.Status(input))
-		if err != nil {
+		if errors.Is(err, ErrOffline) {
+			fmt.Println("The server is unavailable, your update will be sent when it's back.")
+			break
+		} else if err != nil {
 			fmt.Println(err.Error())
 			continue
 		} else {
@@ -167,11 +308,79 @@ func (t *CLI) updateTodo() {
 	}
 }
 
-func (t *CLI) showTodos(todos map[string]types.Todo) {
-	slog.Debug("Method called", "method", "showTodos(todos map[int]types.Todo)")
+// ShowTodos prints todos in the profile's output format.
+func (t *CLI) ShowTodos(todos map[string]types.Todo) {
+	slog.Debug("Method called", "method", "ShowTodos(todos map[int]types.Todo)")
+
+	if t.profile.Output == OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(todos)
+		return
+	}
 
-	for key, t := range todos {
+	loc := t.profile.Location()
+	for key, todo := range todos {
+		// Due dates are calendar dates so only the updated timestamp is converted
+		todo.Updated = todo.Updated.In(loc)
 		fmt.Printf("%s: ", key)
-		fmt.Println(t.String())
+		fmt.Println(todo.String())
+	}
+}
+
+// ShowTodoItems prints todos in order as a table of the given columns, or
+// as JSON.
+func (t *CLI) ShowTodoItems(todos []types.TodoItem, columns []string) {
+	if t.profile.Output == OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(todos)
+		return
+	}
+
+	loc := t.profile.Location()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, item := range todos {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = todoColumn(item, c, loc)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+func todoColumn(item types.TodoItem, column string, loc *time.Location) string {
+	switch column {
+	case "id":
+		return ShortHandle(item.Id)
+	case "status":
+		return string(item.Status)
+	case "description":
+		return item.Description
+	case "due":
+		if item.Due == nil {
+			return "-"
+		}
+		return item.Due.Format(time.DateOnly)
+	case "tags":
+		return strings.Join(item.Tags, ",")
+	case "list":
+		return item.List
+	case "priority":
+		if item.Priority == 0 {
+			return "-"
+		}
+		return strconv.Itoa(item.Priority)
+	case "created":
+		return item.Created.In(loc).Format("2006-01-02 15:04")
+	case "updated":
+		return item.Updated.In(loc).Format("2006-01-02 15:04")
 	}
+	return ""
 }
@@ -1,33 +1,119 @@
 package todoapp
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"grantjames.github.io/todo-app/types"
 )
 
-func NewTodoAPIClient(apiBaseUrl string) *TodoAPIClient {
-	return &TodoAPIClient{
-		apiBaseUrl: apiBaseUrl,
-		client:     &http.Client{},
+const (
+	DefaultClientRetries       = 3
+	DefaultClientRetryDelay    = 250 * time.Millisecond
+	DefaultClientMaxRetryAfter = 30 * time.Second
+)
+
+// NewTodoAPIClient returns a client for the API at apiBaseUrl, such as
+// http://localhost:5000/api. It uses v1 and JSON unless given WithAPIVersion
+// and WithCodec.
+func NewTodoAPIClient(apiBaseUrl string, opts ...ClientOption) *TodoAPIClient {
+	c := &TodoAPIClient{
+		Retries:       DefaultClientRetries,
+		RetryDelay:    DefaultClientRetryDelay,
+		MaxRetryAfter: DefaultClientMaxRetryAfter,
+		version:       APIv1,
+		codec:         JSONCodec,
+		client:        &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	next := c.client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c.client.Transport = retryAfterTransport{client: c, next: next}
+	c.apiBaseUrl = strings.TrimSuffix(apiBaseUrl, "/") + "/" + string(c.version)
+	return c
+}
+
+type ClientOption func(*TodoAPIClient)
+
+// WithAPIVersion has the client use version v of the API.
+func WithAPIVersion(v APIVersion) ClientOption {
+	return func(c *TodoAPIClient) {
+		c.version = v
+	}
+}
+
+// WithCodec has the client send and receive todos in codec's format, such
+// as XMLCodec. The other endpoints always use JSON.
+func WithCodec(codec Codec) ClientOption {
+	return func(c *TodoAPIClient) {
+		c.codec = codec
 	}
 }
 
 type TodoAPIClient struct {
+	// Retries is how many more times a POST that failed to get a response
+	// is sent. Each retry waits twice as long as the last, from RetryDelay.
+	Retries    int
+	RetryDelay time.Duration
+	// MaxRetryAfter is the longest the client waits to send a request again
+	// when the server says it's making too many. Any request can be retried
+	// then, up to Retries times, as the server didn't handle it.
+	MaxRetryAfter time.Duration
+
+	// apiBaseUrl includes the version
 	apiBaseUrl string
-	client     *http.Client
+	version    APIVersion
+	// codec reads and writes todos
+	codec  Codec
+	client *http.Client
+}
+
+// postIdempotent POSTs body in codec's format with a new Idempotency-Key,
+// retrying with the same key if there's no response. The server only
+// applies the change once however many of the attempts reach it.
+func (c *TodoAPIClient) postIdempotent(url string, codec Codec, body []byte) (*http.Response, error) {
+	key := uuid.NewString()
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", codec.MediaType())
+		req.Header.Set("Accept", codec.MediaType())
+		req.Header.Set(IdempotencyKeyHeader, key)
+
+		resp, err := c.client.Do(req)
+		// A refused connection never reached the server, so there's no
+		// point waiting to retry, e.g. the offline client can queue it
+		if err == nil || attempt >= c.Retries || errors.Is(err, syscall.ECONNREFUSED) {
+			return resp, err
+		}
+		slog.Warn("Retrying request", "url", url, "attempt", attempt+1, "error", err.Error())
+		time.Sleep(delay)
+		delay *= 2
+	}
 }
 
 func (c *TodoAPIClient) GetTodo(id string) (*types.Todo, error) {
-	url := fmt.Sprintf("%s/todos/%s", c.apiBaseUrl, id)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	url := RouteGetTodo.URL(c.apiBaseUrl, nil, id)
+	req, err := http.NewRequest(RouteGetTodo.Method, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Accept", c.codec.MediaType())
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -35,12 +121,12 @@ func (c *TodoAPIClient) GetTodo(id string) (*types.Todo, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get todo: status code %d", resp.StatusCode)
+	if resp.StatusCode != RouteGetTodo.Status {
+		return nil, responseError(resp, "get todo")
 	}
 
 	var todo types.Todo
-	if err := json.NewDecoder(resp.Body).Decode(&todo); err != nil {
+	if err := c.codec.Decode(resp.Body, &todo); err != nil {
 		return nil, err
 	}
 
@@ -48,59 +134,46 @@ func (c *TodoAPIClient) GetTodo(id string) (*types.Todo, error) {
 }
 
 func (c *TodoAPIClient) AddTodo(todo types.Todo) (string, error) {
-	url := fmt.Sprintf("%s/todos/", c.apiBaseUrl)
-	todoData, err := json.Marshal(todo)
-	if err != nil {
+	url := RouteAddTodo.URL(c.apiBaseUrl, nil)
+	// The server sets the rest, so they're left out rather than rejected
+	var todoData bytes.Buffer
+	if err := c.codec.Encode(&todoData, todo.Writable()); err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Body = io.NopCloser(strings.NewReader(string(todoData)))
-
-	resp, err := c.client.Do(req)
+	resp, err := c.postIdempotent(url, c.codec, todoData.Bytes())
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("failed to add todo: status code %d", resp.StatusCode)
+	if resp.StatusCode != RouteAddTodo.Status {
+		return "", responseError(resp, "add todo")
 	}
 
-	var result struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var result types.TodoItem
+	if err := c.codec.Decode(resp.Body, &result); err != nil {
 		return "", err
 	}
 
-	return result.ID, nil
+	return result.Id, nil
 }
 
 func (c *TodoAPIClient) UpdateTodoStatus(id string, status types.Status) error {
-	url := fmt.Sprintf("%s/todos/%s", c.apiBaseUrl, id)
-	updateData := struct {
-		Status types.Status `json:"status"`
-	}{
-		Status: status,
-	}
-	data, err := json.Marshal(updateData)
-	if err != nil {
+	route := c.version.Route(RouteUpdateTodoStatus)
+	url := route.URL(c.apiBaseUrl, nil, id)
+	var data bytes.Buffer
+	if err := c.codec.Encode(&data, statusUpdate{Status: status}); err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPut, url, nil)
+	req, err := http.NewRequest(route.Method, url, &data)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Body = io.NopCloser(strings.NewReader(string(data)))
+	req.Header.Set("Content-Type", c.codec.MediaType())
+	req.Header.Set("Accept", c.codec.MediaType())
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -108,29 +181,34 @@ func (c *TodoAPIClient) UpdateTodoStatus(id string, status types.Status) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("failed to update todo status: status code %d", resp.StatusCode)
+	if resp.StatusCode != route.Status {
+		return responseError(resp, "update todo status")
 	}
 
 	return nil
 }
 
 func (c *TodoAPIClient) GetTodosByStatus(status types.Status) (map[string]types.Todo, error) {
-	url := fmt.Sprintf("%s/todos/?status=%s", c.apiBaseUrl, status)
+	if !c.hasMapFormat() {
+		return c.todoMap(types.TodoQuery{Status: status})
+	}
+	url := RouteListTodos.URL(c.apiBaseUrl, url.Values{"status": {string(status)}})
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequest(RouteListTodos.Method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Header.Set("Accept", TodoMapMediaType)
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get todos by status: status code %d", resp.StatusCode)
+	if resp.StatusCode != RouteListTodos.Status {
+		return nil, responseError(resp, "get todos by status")
 	}
 
 	var todos map[string]types.Todo
@@ -142,19 +220,24 @@ func (c *TodoAPIClient) GetTodosByStatus(status types.Status) (map[string]types.
 }
 
 func (c *TodoAPIClient) GetOverdueTodos() (map[string]types.Todo, error) {
-	url := fmt.Sprintf("%s/todos/?overdue", c.apiBaseUrl)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if !c.hasMapFormat() {
+		return c.todoMap(types.TodoQuery{Overdue: true})
+	}
+	url := RouteListTodos.URL(c.apiBaseUrl, url.Values{"overdue": {""}})
+	req, err := http.NewRequest(RouteListTodos.Method, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Accept", TodoMapMediaType)
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get overdue todos: status code %d", resp.StatusCode)
+	if resp.StatusCode != RouteListTodos.Status {
+		return nil, responseError(resp, "get overdue todos")
 	}
 
 	var todos map[string]types.Todo
@@ -166,20 +249,25 @@ func (c *TodoAPIClient) GetOverdueTodos() (map[string]types.Todo, error) {
 }
 
 func (c *TodoAPIClient) GetAllTodos() (map[string]types.Todo, error) {
-	url := fmt.Sprintf("%s/todos/", c.apiBaseUrl)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if !c.hasMapFormat() {
+		return c.todoMap(types.TodoQuery{})
+	}
+	url := RouteListTodos.URL(c.apiBaseUrl, nil)
+	req, err := http.NewRequest(RouteListTodos.Method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Header.Set("Accept", TodoMapMediaType)
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get all todos: status code %d", resp.StatusCode)
+	if resp.StatusCode != RouteListTodos.Status {
+		return nil, responseError(resp, "get all todos")
 	}
 
 	var todos map[string]types.Todo
@@ -189,3 +277,56 @@ func (c *TodoAPIClient) GetAllTodos() (map[string]types.Todo, error) {
 
 	return todos, nil
 }
+
+// versionBaseUrl is the client's base URL for version v of the API.
+func (c *TodoAPIClient) versionBaseUrl(v APIVersion) string {
+	return strings.TrimSuffix(c.apiBaseUrl, string(c.version)) + string(v)
+}
+
+// hasMapFormat is whether the client can ask for TodoMapMediaType, which
+// is only in v1 and is JSON.
+func (c *TodoAPIClient) hasMapFormat() bool {
+	return c.version == APIv1 && c.codec == JSONCodec
+}
+
+// todoMap fetches every todo matching q, for when the client can't ask for
+// the map format.
+func (c *TodoAPIClient) todoMap(q types.TodoQuery) (map[string]types.Todo, error) {
+	q.Limit = MaxPageSize
+	todos := map[string]types.Todo{}
+	for item, err := range c.Todos(q) {
+		if err != nil {
+			return nil, err
+		}
+		todos[item.Id] = item.Todo
+	}
+	return todos, nil
+}
+
+func (c *TodoAPIClient) GetStats(since, until time.Time) (*types.TodoStats, error) {
+	query := url.Values{}
+	query.Set("since", since.Format(time.RFC3339))
+	query.Set("until", until.Format(time.RFC3339))
+	url := RouteGetStats.URL(c.apiBaseUrl, query)
+	req, err := http.NewRequest(RouteGetStats.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteGetStats.Status {
+		return nil, responseError(resp, "get stats")
+	}
+
+	var stats types.TodoStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
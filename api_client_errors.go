@@ -0,0 +1,90 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// ErrUnauthorized matches an APIError for a missing, invalid or revoked
+// token, ErrForbidden one for something the user isn't allowed to do, and
+// ErrRateLimited one for a client making too many requests, once it has
+// given up waiting to retry. ErrForbidden is types.ErrForbidden, which
+// stores return for todos the user doesn't have the role to change.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = types.ErrForbidden
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// APIError is an error response from the server. Depending on the problem
+// it matches types.ErrNotFound, types.ErrConflict, types.ErrInvalid,
+// types.ErrBulkFailed, ErrUnauthorized, ErrForbidden or ErrRateLimited with
+// errors.Is, and errors.As gives the details or the invalid fields as a
+// *types.ValidationError.
+type APIError struct {
+	// Op is what the client was doing, e.g. "get todo"
+	Op string
+	Problem
+}
+
+func (e *APIError) Error() string {
+	detail := e.Detail
+	if detail == "" {
+		detail = e.Title
+	}
+	if detail == "" {
+		detail = http.StatusText(e.Status)
+	}
+	return fmt.Sprintf("failed to %s: %s (status code %d)", e.Op, detail, e.Status)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case types.ErrNotFound:
+		return e.Type == ProblemNotFound || e.Type == "" && e.Status == http.StatusNotFound
+	case types.ErrConflict:
+		return e.Type == ProblemConflict || e.Type == "" && e.Status == http.StatusConflict
+	case types.ErrInvalid:
+		return e.Type == ProblemValidation || e.Type == ProblemBadRequest || e.Type == "" && e.Status == http.StatusBadRequest
+	case types.ErrBulkFailed:
+		return e.Type == ProblemBulkFailed
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func (e *APIError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return &types.ValidationError{Fields: e.Errors}
+}
+
+// responseError reads the problem from an error response. Responses that
+// aren't problems, e.g. from a proxy, use their body as the detail.
+func responseError(resp *http.Response, op string) error {
+	apiErr := &APIError{Op: op}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if !isProblem(resp) || json.Unmarshal(body, &apiErr.Problem) != nil {
+		apiErr.Detail = strings.TrimSpace(string(body))
+	}
+	apiErr.Status = resp.StatusCode
+	return apiErr
+}
+
+func isProblem(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == ProblemMediaType
+}
@@ -0,0 +1,162 @@
+package todoapp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// Subscribe streams every todo change from the server until ctx is done,
+// when the returned channel is closed. Dropped connections are resumed from
+// the last event received.
+func (c *TodoAPIClient) Subscribe(ctx context.Context) (<-chan types.TodoEvent, error) {
+	return c.SubscribeFiltered(ctx, types.EventFilter{})
+}
+
+// SubscribeFiltered is Subscribe with the filtering done by the server.
+func (c *TodoAPIClient) SubscribeFiltered(ctx context.Context, filter types.EventFilter) (<-chan types.TodoEvent, error) {
+	query := url.Values{}
+	if filter.List != "" {
+		query.Set("list", filter.List)
+	}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+	streamUrl := RouteStreamEvents.URL(c.apiBaseUrl, query)
+
+	stream := &eventStream{client: c.client, url: streamUrl, retry: eventsRetry * time.Millisecond}
+
+	// Connect once up front so a bad URL or unreachable server is reported
+	resp, err := stream.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan types.TodoEvent)
+	go stream.run(ctx, resp, events)
+	return events, nil
+}
+
+type eventStream struct {
+	client *http.Client
+	url    string
+	lastId string
+	retry  time.Duration
+}
+
+func (s *eventStream) connect(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastId != "" {
+		req.Header.Set("Last-Event-ID", s.lastId)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != RouteStreamEvents.Status {
+		err := responseError(resp, "subscribe to events")
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *eventStream) run(ctx context.Context, resp *http.Response, events chan<- types.TodoEvent) {
+	defer close(events)
+
+	for {
+		if resp != nil {
+			err := s.read(ctx, resp, events)
+			resp.Body.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("Event stream disconnected, reconnecting", "error", fmt.Sprint(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retry):
+		}
+
+		var err error
+		resp, err = s.connect(ctx)
+		if err != nil {
+			slog.Warn("Failed to reconnect to event stream", "error", err.Error())
+			resp = nil
+		}
+	}
+}
+
+// read parses the text/event-stream format until the connection ends.
+func (s *eventStream) read(ctx context.Context, resp *http.Response, events chan<- types.TodoEvent) error {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var eventType, id string
+	var data strings.Builder
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if data.Len() > 0 || eventType != "" {
+				if id != "" {
+					s.lastId = id
+				}
+				e := types.TodoEvent{Type: types.EventType(eventType)}
+				if eventType != string(types.EventReset) {
+					if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+						return fmt.Errorf("problem parsing event %s, %v", id, err)
+					}
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			eventType, id = "", ""
+			data.Reset()
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// A comment, such as a heartbeat
+		case "event":
+			eventType = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		case "id":
+			id = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,153 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// QueryTodos fetches one page of todos in the query's order. Pass the
+// page's NextCursor in q.Cursor to fetch the next one. Pages are only in
+// v2, so they're fetched from it whichever version the client uses.
+func (c *TodoAPIClient) QueryTodos(q types.TodoQuery) (*types.TodoPage, error) {
+	query := url.Values{}
+	if q.Status != "" {
+		query.Set("status", string(q.Status))
+	}
+	if q.Overdue {
+		query.Set("overdue", "")
+	}
+	if q.All {
+		query.Set("all", "")
+	}
+	if q.Assignee != "" {
+		query.Set("assignee", q.Assignee)
+	}
+	if q.Filter != nil {
+		query.Set("q", q.Filter.String())
+	}
+	if len(q.Sort) > 0 {
+		query.Set("sort", types.FormatSort(q.Sort))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		query.Set("cursor", q.Cursor)
+	}
+
+	url := RouteListTodos.URL(c.versionBaseUrl(APIv2), query)
+	req, err := http.NewRequest(RouteListTodos.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", c.codec.MediaType())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteListTodos.Status {
+		return nil, responseError(resp, "query todos")
+	}
+
+	page := types.TodoPage{NextCursor: resp.Header.Get(NextCursorHeader)}
+	if err := c.codec.Decode(resp.Body, &page.Todos); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Todos iterates over every todo matching q, fetching pages of q.Limit as
+// they're needed. Iteration stops after yielding an error.
+func (c *TodoAPIClient) Todos(q types.TodoQuery) iter.Seq2[types.TodoItem, error] {
+	return func(yield func(types.TodoItem, error) bool) {
+		for {
+			page, err := c.QueryTodos(q)
+			if err != nil {
+				yield(types.TodoItem{}, err)
+				return
+			}
+			for _, item := range page.Todos {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+}
+
+// Search returns up to limit todos matching the words in query, best first.
+func (c *TodoAPIClient) Search(query string, limit int) ([]types.SearchResult, error) {
+	values := url.Values{}
+	values.Set("q", query)
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+
+	url := RouteSearchTodos.URL(c.apiBaseUrl, values)
+	req, err := http.NewRequest(RouteSearchTodos.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteSearchTodos.Status {
+		return nil, responseError(resp, "search todos")
+	}
+
+	var results []types.SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Bulk applies ops on the server as one change. If any op fails nothing is
+// applied, and the error matches types.ErrBulkFailed with the reasons in
+// the results.
+func (c *TodoAPIClient) Bulk(ops []types.BulkOp) ([]types.BulkResult, error) {
+	data, err := json.Marshal(bulkRequestBody{Ops: ops})
+	if err != nil {
+		return nil, err
+	}
+
+	url := RouteBulkTodos.URL(c.apiBaseUrl, nil)
+	resp, err := c.postIdempotent(url, JSONCodec, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnprocessableEntity && isProblem(resp) {
+		var problem bulkProblem
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+			return nil, err
+		}
+		return problem.Results, &APIError{Op: "apply bulk changes", Problem: problem.Problem}
+	}
+	if resp.StatusCode != RouteBulkTodos.Status {
+		return nil, responseError(resp, "apply bulk changes")
+	}
+
+	var body bulkResponseBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Results, nil
+}
@@ -0,0 +1,67 @@
+package todoapp
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryAfterTransport sends a request again when the server responds 429
+// Too Many Requests, after waiting as long as its Retry-After header says.
+// If that's longer than the client's MaxRetryAfter, or it has already tried
+// Retries times, the 429 is returned, which is an APIError matching
+// ErrRateLimited.
+type retryAfterTransport struct {
+	client *TodoAPIClient
+	next   http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= t.client.Retries {
+			return resp, err
+		}
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || wait > t.client.MaxRetryAfter {
+			return resp, nil
+		}
+		// The body has been sent, so it has to be read again from the start
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		slog.Warn("Rate limited, retrying", "url", req.URL.String(), "attempt", attempt+1, "wait", wait.String())
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryAfter reads a Retry-After header, which is a number of seconds or an
+// HTTP date.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
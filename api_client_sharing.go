@@ -0,0 +1,121 @@
+package todoapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// TodoMembers returns the todo's owner and the users it's shared with.
+func (c *TodoAPIClient) TodoMembers(id string) ([]types.Member, error) {
+	return c.getMembers(RouteGetTodoMembers, "list todo members", nil, id)
+}
+
+// ShareTodo shares the todo with the user with the name, or changes their
+// role on it.
+func (c *TodoAPIClient) ShareTodo(id, user string, role types.Role) (types.Member, error) {
+	return c.putMember(RoutePutTodoMember, "share todo", nil, role, id, user)
+}
+
+// UnshareTodo stops sharing the todo with the user with the name.
+func (c *TodoAPIClient) UnshareTodo(id, user string) error {
+	return c.deleteMember(RouteDeleteTodoMember, "unshare todo", nil, id, user)
+}
+
+// ListMembers returns the list's owner and the users it's shared with. The
+// list is owner's, or the user's own if owner is empty.
+func (c *TodoAPIClient) ListMembers(owner, list string) ([]types.Member, error) {
+	return c.getMembers(RouteGetListMembers, "list list members", ownerQuery(owner), list)
+}
+
+// ShareList shares every todo in the list with the user with the name, or
+// changes their role on it. The list is owner's, or the user's own if owner
+// is empty.
+func (c *TodoAPIClient) ShareList(owner, list, user string, role types.Role) (types.Member, error) {
+	return c.putMember(RoutePutListMember, "share list", ownerQuery(owner), role, list, user)
+}
+
+// UnshareList stops sharing the list with the user with the name.
+func (c *TodoAPIClient) UnshareList(owner, list, user string) error {
+	return c.deleteMember(RouteDeleteListMember, "unshare list", ownerQuery(owner), list, user)
+}
+
+func ownerQuery(owner string) url.Values {
+	if owner == "" {
+		return nil
+	}
+	return url.Values{"owner": {owner}}
+}
+
+func (c *TodoAPIClient) getMembers(route Route, op string, query url.Values, params ...string) ([]types.Member, error) {
+	req, err := http.NewRequest(route.Method, route.URL(c.apiBaseUrl, query, params...), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != route.Status {
+		return nil, responseError(resp, op)
+	}
+
+	var members []types.Member
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *TodoAPIClient) putMember(route Route, op string, query url.Values, role types.Role, params ...string) (types.Member, error) {
+	data, err := json.Marshal(memberRequestBody{Role: role})
+	if err != nil {
+		return types.Member{}, err
+	}
+
+	req, err := http.NewRequest(route.Method, route.URL(c.apiBaseUrl, query, params...), bytes.NewReader(data))
+	if err != nil {
+		return types.Member{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return types.Member{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != route.Status {
+		return types.Member{}, responseError(resp, op)
+	}
+
+	var member types.Member
+	if err := json.NewDecoder(resp.Body).Decode(&member); err != nil {
+		return types.Member{}, err
+	}
+	return member, nil
+}
+
+func (c *TodoAPIClient) deleteMember(route Route, op string, query url.Values, params ...string) error {
+	req, err := http.NewRequest(route.Method, route.URL(c.apiBaseUrl, query, params...), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != route.Status {
+		return responseError(resp, op)
+	}
+	return nil
+}
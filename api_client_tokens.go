@@ -0,0 +1,107 @@
+package todoapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"grantjames.github.io/todo-app/users"
+)
+
+// bearerTransport adds the client's token to every request.
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers mustn't change the request they're given
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// WithToken has the client authenticate with token, for servers that have
+// users.
+func WithToken(token string) ClientOption {
+	return func(c *TodoAPIClient) {
+		c.client.Transport = bearerTransport{token: token, next: http.DefaultTransport}
+	}
+}
+
+// Tokens returns the user's tokens, without the tokens themselves.
+func (c *TodoAPIClient) Tokens() ([]users.Token, error) {
+	url := RouteListTokens.URL(c.apiBaseUrl, nil)
+	req, err := http.NewRequest(RouteListTokens.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteListTokens.Status {
+		return nil, responseError(resp, "list tokens")
+	}
+
+	var tokens []users.Token
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CreateToken creates another token for the user, returning it along with
+// the token itself, which the server won't show again.
+func (c *TodoAPIClient) CreateToken(name string) (users.Token, string, error) {
+	data, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return users.Token{}, "", err
+	}
+
+	url := RouteAddToken.URL(c.apiBaseUrl, nil)
+	req, err := http.NewRequest(RouteAddToken.Method, url, bytes.NewReader(data))
+	if err != nil {
+		return users.Token{}, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return users.Token{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteAddToken.Status {
+		return users.Token{}, "", responseError(resp, "create token")
+	}
+
+	var created newToken
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return users.Token{}, "", err
+	}
+	return created.Token, created.Secret, nil
+}
+
+// RevokeToken revokes one of the user's tokens.
+func (c *TodoAPIClient) RevokeToken(id string) error {
+	url := RouteDeleteToken.URL(c.apiBaseUrl, nil, id)
+	req, err := http.NewRequest(RouteDeleteToken.Method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteDeleteToken.Status {
+		return responseError(resp, "revoke token")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// GetViews returns the saved views on the server, in name order.
+func (c *TodoAPIClient) GetViews() ([]views.View, error) {
+	url := RouteListViews.URL(c.apiBaseUrl, nil)
+	req, err := http.NewRequest(RouteListViews.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != RouteListViews.Status {
+		return nil, responseError(resp, "get views")
+	}
+
+	var result []views.View
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetViewTodos runs the named view on the server, fetching every page.
+func (c *TodoAPIClient) GetViewTodos(name string) ([]types.TodoItem, error) {
+	todos := []types.TodoItem{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprint(MaxPageSize))
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		url := RouteGetViewTodos.URL(c.apiBaseUrl, query, name)
+		req, err := http.NewRequest(RouteGetViewTodos.Method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", c.codec.MediaType())
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != RouteGetViewTodos.Status {
+			err := responseError(resp, "get todos for view "+name)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page []types.TodoItem
+		err = c.codec.Decode(resp.Body, &page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, page...)
+
+		if cursor = resp.Header.Get(NextCursorHeader); cursor == "" {
+			return todos, nil
+		}
+	}
+}
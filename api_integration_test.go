@@ -1,12 +1,15 @@
 package todoapp
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"grantjames.github.io/todo-app/filter"
 	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
 )
 
 func TestAddingTodosAndRetrievingThem(t *testing.T) {
@@ -37,8 +40,149 @@ func FuzzPOSTTodo(f *testing.F) {
 
 		res := w.Result()
 
-		if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusBadRequest {
+		if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusBadRequest {
 			t.Errorf("Unexpected status code: %d", res.StatusCode)
 		}
 	})
 }
+
+func TestStats(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+	server.ServeHTTP(httptest.NewRecorder(), newPostTodoRequest())
+
+	t.Run("Returns stats for the requested range", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		var stats types.TodoStats
+		if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+			t.Fatalf("Unable to parse response from server %q into stats, '%v'", response.Body, err)
+		}
+		if stats.ByStatus[types.NotStarted] != 1 {
+			t.Errorf("got %d not started todos want 1", stats.ByStatus[types.NotStarted])
+		}
+	})
+
+	t.Run("Rejects an invalid range", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/stats?since=2026-10-10&until=2026-10-01", nil))
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func TestQueryingTodos(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for range 5 {
+		server.ServeHTTP(httptest.NewRecorder(), newPostTodoRequest())
+	}
+
+	t.Run("Lists are ordered arrays with a link to the next page", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v2/todos/?limit=2&sort=-created", nil))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		var todos []types.TodoItem
+		if err := json.NewDecoder(response.Body).Decode(&todos); err != nil {
+			t.Fatalf("Unable to parse response from server %q into todos, '%v'", response.Body, err)
+		}
+		if len(todos) != 2 || todos[0].Created.Before(todos[1].Created) {
+			t.Errorf("got %v want the two newest todos, newest first", todos)
+		}
+		if !strings.Contains(response.Header().Get("Link"), `rel="next"`) || response.Header().Get(NextCursorHeader) == "" {
+			t.Errorf("got headers %v want a next page", response.Header())
+		}
+	})
+
+	t.Run("v1 lists every todo as a map by default", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/todos/?limit=2", nil))
+		var todos map[string]types.Todo
+		if err := json.NewDecoder(response.Body).Decode(&todos); err != nil || len(todos) != 5 {
+			t.Errorf("got %d todos and error %v want all 5 keyed by id", len(todos), err)
+		}
+
+		todos, err := NewTodoAPIClient(ts.URL + "/api").GetAllTodos()
+		if err != nil || len(todos) != 5 {
+			t.Errorf("got %d todos and error %v want 5", len(todos), err)
+		}
+	})
+
+	t.Run("The client iterates over every page", func(t *testing.T) {
+		client := NewTodoAPIClient(ts.URL + "/api")
+		seen := map[string]bool{}
+		for item, err := range client.Todos(types.TodoQuery{Limit: 2}) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			seen[item.Id] = true
+		}
+		if len(seen) != 5 {
+			t.Errorf("got %d todos want 5", len(seen))
+		}
+	})
+
+	t.Run("Filters with a query expression", func(t *testing.T) {
+		client := NewTodoAPIClient(ts.URL + "/api")
+		expr, _ := filter.Parse(`text~"new" AND NOT status:completed`)
+		page, err := client.QueryTodos(types.TodoQuery{Filter: expr})
+		if err != nil || len(page.Todos) != 5 {
+			t.Errorf("got %v and error %v want all 5 todos", page, err)
+		}
+
+		expr, _ = filter.Parse(`status:completed`)
+		page, _ = client.QueryTodos(types.TodoQuery{Filter: expr})
+		if len(page.Todos) != 0 {
+			t.Errorf("got %d todos want 0", len(page.Todos))
+		}
+	})
+
+	t.Run("Bad queries are rejected", func(t *testing.T) {
+		for _, query := range []string{"sort=colour", "limit=0", "cursor=nonsense", "status=done", "q=colour:red"} {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v2/todos/?"+query, nil))
+			assertStatus(t, response.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestSearchingTodos(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	store.AddTodo(t.Context(), types.NewTodo("Renew the VPN certificate", nil))
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewTodoAPIClient(ts.URL + "/api")
+
+	t.Run("Finds todos that were in the store at startup", func(t *testing.T) {
+		results, err := client.Search("vpn", 0)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("got %v and error %v want one result", results, err)
+		}
+		if h := results[0].Highlights; len(h) != 1 || results[0].Snippet[h[0].Start:h[0].End] != "VPN" {
+			t.Errorf("got highlights %v want VPN highlighted", h)
+		}
+	})
+
+	t.Run("Finds todos as they're added", func(t *testing.T) {
+		if _, err := client.AddTodo(types.NewTodo("Connecting the printer", nil)); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		results, err := client.Search("connected printers", 0)
+		if err != nil || len(results) != 1 || results[0].Todo.Description != "Connecting the printer" {
+			t.Errorf("got %v and error %v want the printer todo", results, err)
+		}
+	})
+
+	t.Run("Bad searches are rejected", func(t *testing.T) {
+		for _, query := range []string{"", "q=", "q=vpn&limit=0", "q=vpn&limit=many"} {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/search?"+query, nil))
+			assertStatus(t, response.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultProfileName = "default"
+	DefaultServerURL   = "http://localhost:5000/api"
+
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// CLIProfile holds the settings the CLI uses to talk to a server.
+// Empty fields fall back to the defaults from DefaultCLIProfile.
+type CLIProfile struct {
+	ServerURL   string `json:"server_url,omitempty"`
+	Token       string `json:"token,omitempty"`
+	DefaultList string `json:"default_list,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
+	Output      string `json:"output,omitempty"`
+	Format      string `json:"format,omitempty"`
+	LogFile     string `json:"log_file,omitempty"`
+	LogLevel    string `json:"log_level,omitempty"`
+}
+
+// CLIConfig is the contents of the CLI config file.
+type CLIConfig struct {
+	CurrentProfile string                `json:"current_profile,omitempty"`
+	Profiles       map[string]CLIProfile `json:"profiles"`
+}
+
+// The keys accepted by `todo config get/set`, in display order, along with
+// the environment variable that overrides each of them.
+var profileKeys = []struct {
+	key string
+	env string
+}{
+	{"server_url", "TODO_SERVER_URL"},
+	{"token", "TODO_TOKEN"},
+	{"default_list", "TODO_DEFAULT_LIST"},
+	{"time_zone", "TODO_TIME_ZONE"},
+	{"output", "TODO_OUTPUT"},
+	{"format", "TODO_FORMAT"},
+	{"log_file", "TODO_LOG_FILE"},
+	{"log_level", "TODO_LOG_LEVEL"},
+}
+
+func ProfileKeys() []string {
+	keys := make([]string, len(profileKeys))
+	for i, k := range profileKeys {
+		keys[i] = k.key
+	}
+	return keys
+}
+
+func DefaultCLIProfile() CLIProfile {
+	logFile := "app.log"
+	if dir, err := os.UserCacheDir(); err == nil {
+		logFile = filepath.Join(dir, "todo-app", "cli.log")
+	}
+
+	return CLIProfile{
+		ServerURL: DefaultServerURL,
+		TimeZone:  "Local",
+		Output:    OutputText,
+		Format:    "json",
+		LogFile:   logFile,
+		LogLevel:  "info",
+	}
+}
+
+// DefaultCLIConfigPath returns the config file location under the user's
+// config directory ($XDG_CONFIG_HOME/todo-app/config.json on Linux).
+func DefaultCLIConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("problem finding user config directory, %v", err)
+	}
+	return filepath.Join(dir, "todo-app", "config.json"), nil
+}
+
+// LoadCLIConfig reads the config file at path. A missing file is not an
+// error, an empty config is returned instead.
+func LoadCLIConfig(path string) (*CLIConfig, error) {
+	cfg := &CLIConfig{Profiles: map[string]CLIProfile{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem reading config file %s, %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("problem parsing config file %s, %v", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]CLIProfile{}
+	}
+	return cfg, nil
+}
+
+func (c *CLIConfig) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("problem creating config directory, %v", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The file can hold API tokens so keep it private to the user
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// SelectProfile picks the profile name to use. The --profile flag wins, then
+// the TODO_PROFILE environment variable, then the config's current profile.
+func (c *CLIConfig) SelectProfile(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv("TODO_PROFILE"); env != "" {
+		return env
+	}
+	if c.CurrentProfile != "" {
+		return c.CurrentProfile
+	}
+	return DefaultProfileName
+}
+
+func (c *CLIConfig) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the named profile merged over the defaults, with any
+// TODO_* environment variables applied on top.
+func (c *CLIConfig) Resolve(name string) (CLIProfile, error) {
+	p, ok := c.Profiles[name]
+	if !ok && name != DefaultProfileName {
+		return CLIProfile{}, fmt.Errorf("no profile named %q in config", name)
+	}
+
+	resolved := DefaultCLIProfile()
+	for _, k := range profileKeys {
+		if v := p.Get(k.key); v != "" {
+			resolved.Set(k.key, v)
+		}
+		if v := os.Getenv(k.env); v != "" {
+			resolved.Set(k.key, v)
+		}
+	}
+
+	if err := resolved.Validate(); err != nil {
+		return CLIProfile{}, fmt.Errorf("profile %q is invalid, %v", name, err)
+	}
+	return resolved, nil
+}
+
+func (p *CLIProfile) field(key string) (*string, error) {
+	switch key {
+	case "server_url":
+		return &p.ServerURL, nil
+	case "token":
+		return &p.Token, nil
+	case "default_list":
+		return &p.DefaultList, nil
+	case "time_zone":
+		return &p.TimeZone, nil
+	case "output":
+		return &p.Output, nil
+	case "format":
+		return &p.Format, nil
+	case "log_file":
+		return &p.LogFile, nil
+	case "log_level":
+		return &p.LogLevel, nil
+	}
+	return nil, fmt.Errorf("unknown config key %q, valid keys are %s", key, strings.Join(ProfileKeys(), ", "))
+}
+
+func (p *CLIProfile) Get(key string) string {
+	f, err := p.field(key)
+	if err != nil {
+		return ""
+	}
+	return *f
+}
+
+func (p *CLIProfile) Set(key, value string) error {
+	f, err := p.field(key)
+	if err != nil {
+		return err
+	}
+	*f = value
+	return nil
+}
+
+func (p *CLIProfile) Validate() error {
+	if p.Output != "" && p.Output != OutputText && p.Output != OutputJSON {
+		return fmt.Errorf("output must be %q or %q, got %q", OutputText, OutputJSON, p.Output)
+	}
+	if _, ok := formatCodecs[p.Format]; p.Format != "" && !ok {
+		return fmt.Errorf("format must be one of %s, got %q", strings.Join(slices.Sorted(maps.Keys(formatCodecs)), ", "), p.Format)
+	}
+	if p.TimeZone != "" {
+		if _, err := time.LoadLocation(p.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", p.TimeZone)
+		}
+	}
+	if p.LogLevel != "" {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(p.LogLevel)); err != nil {
+			return fmt.Errorf("unknown log level %q", p.LogLevel)
+		}
+	}
+	return nil
+}
+
+// Location returns the profile's time zone, defaulting to the local one.
+func (p CLIProfile) Location() *time.Location {
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil || p.TimeZone == "" {
+		return time.Local
+	}
+	return loc
+}
+
+// formatCodecs are the codecs the CLI can talk to the server with, by the
+// name used for the format key.
+var formatCodecs = map[string]Codec{
+	"json": JSONCodec,
+	"xml":  XMLCodec,
+	"csv":  CSVCodec,
+	"yaml": YAMLCodec,
+}
+
+// Codec returns the codec for the profile's format, defaulting to JSON.
+func (p CLIProfile) Codec() Codec {
+	if c, ok := formatCodecs[p.Format]; ok {
+		return c
+	}
+	return JSONCodec
+}
+
+func (p CLIProfile) Level() slog.Level {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(p.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return lvl
+}
@@ -0,0 +1,84 @@
+package todoapp
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCLIConfig(t *testing.T) {
+	t.Run("Missing config file resolves to the defaults", func(t *testing.T) {
+		cfg, err := LoadCLIConfig(filepath.Join(t.TempDir(), "config.json"))
+		if err != nil {
+			t.Fatalf("unexpected error loading config: %v", err)
+		}
+
+		got, err := cfg.Resolve(cfg.SelectProfile(""))
+		if err != nil {
+			t.Fatalf("unexpected error resolving profile: %v", err)
+		}
+		if got.ServerURL != DefaultServerURL {
+			t.Errorf("got server url %q want %q", got.ServerURL, DefaultServerURL)
+		}
+	})
+
+	t.Run("Profiles are saved and selected by flag or environment", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		cfg, _ := LoadCLIConfig(path)
+		cfg.Profiles["work"] = CLIProfile{ServerURL: "http://work:5000/api", DefaultList: "ops"}
+		cfg.CurrentProfile = "work"
+		if err := cfg.Save(path); err != nil {
+			t.Fatalf("unexpected error saving config: %v", err)
+		}
+
+		cfg, _ = LoadCLIConfig(path)
+		if got := cfg.SelectProfile(""); got != "work" {
+			t.Errorf("got profile %q want %q", got, "work")
+		}
+
+		t.Setenv("TODO_PROFILE", "home")
+		if got := cfg.SelectProfile(""); got != "home" {
+			t.Errorf("got profile %q want %q", got, "home")
+		}
+		if got := cfg.SelectProfile("work"); got != "work" {
+			t.Errorf("got profile %q want %q", got, "work")
+		}
+
+		if _, err := cfg.Resolve("home"); err == nil {
+			t.Errorf("expected an error resolving an unknown profile")
+		}
+	})
+
+	t.Run("Environment variables override profile values", func(t *testing.T) {
+		cfg := &CLIConfig{Profiles: map[string]CLIProfile{
+			"work": {ServerURL: "http://work:5000/api", Output: OutputJSON},
+		}}
+		t.Setenv("TODO_SERVER_URL", "http://override:5000/api")
+
+		got, err := cfg.Resolve("work")
+		if err != nil {
+			t.Fatalf("unexpected error resolving profile: %v", err)
+		}
+		if got.ServerURL != "http://override:5000/api" {
+			t.Errorf("got server url %q want the env override", got.ServerURL)
+		}
+		if got.Output != OutputJSON {
+			t.Errorf("got output %q want %q", got.Output, OutputJSON)
+		}
+	})
+
+	t.Run("Invalid values are rejected", func(t *testing.T) {
+		p := CLIProfile{TimeZone: "Not/AZone"}
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected an error for an unknown time zone")
+		}
+
+		p = CLIProfile{Format: "toml"}
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected an error for an unknown format")
+		}
+
+		if err := p.Set("colour", "blue"); err == nil {
+			t.Errorf("expected an error for an unknown key")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
+)
+
+// runDoneCommand completes several todos at once, all or none of them.
+func runDoneCommand(env *cliEnv, args []string) error {
+	if len(args) == 0 {
+		return usageError("todo done <id>...")
+	}
+
+	_, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+	todos, err := allTodos(client)
+	if err != nil && !errors.Is(err, todoapp.ErrOffline) {
+		return err
+	}
+
+	var ops []types.BulkOp
+	for _, handle := range args {
+		id, err := todoapp.ResolveHandle(todos, handle)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, types.BulkOp{Op: types.BulkUpdate, Id: id, Status: types.Completed})
+	}
+
+	return applyBulk(env, client, ops, fmt.Sprintf("%d todo(s) completed", len(ops)))
+}
+
+func completeDoneCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	return todoapp.TodoCompletions(completionTodos(env), current)
+}
+
+// runBulkCommand reads one JSON op per line from stdin, e.g.
+// {"op": "update", "id": "3e6ee309", "status": "completed"}, and applies
+// them as one change. Ids can be short handles.
+func runBulkCommand(env *cliEnv, args []string) error {
+	if len(args) != 0 {
+		return usageError("todo bulk < ops.jsonl")
+	}
+
+	ops, err := readBulkOps(os.Stdin)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return errors.New("no operations given on stdin")
+	}
+
+	_, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+	todos, err := allTodos(client)
+	if err != nil && !errors.Is(err, todoapp.ErrOffline) {
+		return err
+	}
+	for i := range ops {
+		if ops[i].Id == "" {
+			continue
+		}
+		if ops[i].Id, err = todoapp.ResolveHandle(todos, ops[i].Id); err != nil {
+			return fmt.Errorf("op %d: %v", i+1, err)
+		}
+	}
+
+	return applyBulk(env, client, ops, fmt.Sprintf("%d operation(s) applied", len(ops)))
+}
+
+func readBulkOps(r io.Reader) ([]types.BulkOp, error) {
+	var ops []types.BulkOp
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var op types.BulkOp
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&op); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := op.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// applyBulk sends ops and prints done, or the results in JSON. If they
+// weren't applied it explains which ops failed.
+func applyBulk(env *cliEnv, client todoapp.TodoClient, ops []types.BulkOp, done string) error {
+	results, err := client.Bulk(ops)
+	if errors.Is(err, types.ErrBulkFailed) {
+		for i, r := range results {
+			if r.Error != types.BulkNotApplied {
+				fmt.Fprintf(os.Stderr, "%d: %s %s: %s\n", i+1, r.Op, todoapp.ShortHandle(r.Id), r.Error)
+			}
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if profile, _ := env.Profile(); profile.Output == todoapp.OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	fmt.Println(done)
+	return nil
+}
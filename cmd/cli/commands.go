@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/views"
+)
+
+// command is a CLI subcommand. complete returns the candidates for the
+// word being typed, current, given the arguments before it, and may be nil.
+type command struct {
+	summary  string
+	hidden   bool
+	run      func(env *cliEnv, args []string) error
+	complete func(env *cliEnv, args []string, current string) []todoapp.Completion
+}
+
+var commands map[string]command
+
+func init() {
+	// Assigned in init as the completion commands refer back to the table
+	commands = map[string]command{
+		"config":     {summary: "View and edit the config file", run: runConfigCommand, complete: completeConfigCommand},
+		"completion": {summary: "Print a shell completion script (bash, zsh or fish)", run: runCompletionCommand, complete: completeCompletionCommand},
+		"__complete": {hidden: true, run: runCompleteCommand},
+		"bulk":       {summary: "Apply JSON lines of create, update and delete ops from stdin, all or none", run: runBulkCommand},
+		"done":       {summary: "Complete one or more todos", run: runDoneCommand, complete: completeDoneCommand},
+		"list":       {summary: "List todos", run: runListCommand, complete: completeListCommand},
+		"search":     {summary: "Search todos for words", run: runSearchCommand, complete: completeSearchCommand},
+		"share":      {summary: "Share a todo or list with other users", run: runShareCommand, complete: completeShareCommand},
+		"show":       {summary: "Show a todo", run: runShowCommand, complete: completeShowCommand},
+		"status":     {summary: "Change a todo's status", run: runStatusCommand, complete: completeStatusCommand},
+		"stats":      {summary: "Show throughput, lead time and overdue trends", run: runStatsCommand, complete: completeStatsCommand},
+		"token":      {summary: "List, create and revoke your API tokens", run: runTokenCommand, complete: completeTokenCommand},
+	}
+}
+
+// cliEnv carries the global flags and config, and lazily creates the
+// profile, logger and client for commands that need them.
+type cliEnv struct {
+	cfg         *todoapp.CLIConfig
+	configPath  string
+	profileName string
+	localPath   string
+	logLevel    *int
+
+	profile *todoapp.CLIProfile
+	client  todoapp.TodoClient
+	closers []func() error
+}
+
+func newCLIEnv(configPath, profileFlag, localPath string) (*cliEnv, error) {
+	if configPath == "" {
+		var err error
+		configPath, err = todoapp.DefaultCLIConfigPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := todoapp.LoadCLIConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cliEnv{
+		cfg:         cfg,
+		configPath:  configPath,
+		profileName: cfg.SelectProfile(profileFlag),
+		localPath:   localPath,
+	}, nil
+}
+
+// Profile resolves the selected profile and sets up logging to its log file.
+func (e *cliEnv) Profile() (todoapp.CLIProfile, error) {
+	if e.profile != nil {
+		return *e.profile, nil
+	}
+
+	profile, err := e.cfg.Resolve(e.profileName)
+	if err != nil {
+		return todoapp.CLIProfile{}, err
+	}
+
+	level := profile.Level()
+	if e.logLevel != nil {
+		level = slog.Level(*e.logLevel)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(profile.LogFile), 0755); err != nil {
+		return todoapp.CLIProfile{}, err
+	}
+	f, err := os.OpenFile(profile.LogFile,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return todoapp.CLIProfile{}, err
+	}
+	e.closers = append(e.closers, f.Close)
+
+	opts := &slog.HandlerOptions{
+		Level: level,
+	}
+
+	logger := slog.New(slog.NewTextHandler(f, opts))
+	slog.SetDefault(logger)
+
+	e.profile = &profile
+	return profile, nil
+}
+
+func (e *cliEnv) Client() (todoapp.TodoClient, error) {
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	profile, err := e.Profile()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.localPath != "" {
+		slog.Info("Using local todo store", "path", e.localPath)
+		store, err := stores.OpenJSONFileTodoStore(e.localPath)
+		if err != nil {
+			return nil, err
+		}
+		e.closers = append(e.closers, store.Close)
+		// Views are kept next to the store, as the server does
+		savedViews, err := views.NewStore(filepath.Join(filepath.Dir(e.localPath), "views.json"))
+		if err != nil {
+			return nil, err
+		}
+		e.client = todoapp.NewLocalTodoClient(store, savedViews)
+		return e.client, nil
+	}
+
+	offlineDir, err := e.cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	e.client, err = todoapp.NewOfflineTodoClient(apiClient(profile), offlineDir)
+	if err != nil {
+		return nil, err
+	}
+	return e.client, nil
+}
+
+func apiClient(profile todoapp.CLIProfile) *todoapp.TodoAPIClient {
+	opts := []todoapp.ClientOption{todoapp.WithAPIVersion(todoapp.APIv2), todoapp.WithCodec(profile.Codec())}
+	if profile.Token != "" {
+		opts = append(opts, todoapp.WithToken(profile.Token))
+	}
+	return todoapp.NewTodoAPIClient(profile.ServerURL, opts...)
+}
+
+func (e *cliEnv) cacheDir() (string, error) {
+	return todoapp.DefaultOfflineDir(e.profileName)
+}
+
+func (e *cliEnv) close() {
+	for _, c := range e.closers {
+		c()
+	}
+}
+
+func runREPL(env *cliEnv) error {
+	profile, err := env.Profile()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	app := todoapp.NewCLI(client, profile)
+
+	app.Start()
+	return nil
+}
+
+func usageError(usage string) error {
+	return fmt.Errorf("usage: %s", usage)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
+)
+
+// The scripts call back into `todo __complete <words>` for every tab press,
+// so new commands and flags complete without regenerating the script.
+
+const bashCompletion = `# bash completion for {{name}}
+_{{fn}}_completions() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local out
+    out=$({{name}} __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    if [[ "$out" == ":files" ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(printf '%s\n' "$out" | cut -f1))
+}
+complete -F _{{fn}}_completions {{name}}
+`
+
+const zshCompletion = `#compdef {{name}}
+_{{fn}}() {
+    local -a lines completions
+    local line
+    lines=("${(@f)$({{name}} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ "${lines[1]}" == ":files" ]]; then
+        _files
+        return
+    fi
+    for line in $lines; do
+        [[ -z "$line" ]] && continue
+        completions+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+    _describe '{{name}}' completions
+}
+compdef _{{fn}} {{name}}
+`
+
+const fishCompletion = `# fish completion for {{name}}
+function __{{fn}}_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -e tokens[1]
+    set -l out ({{name}} __complete $tokens 2>/dev/null)
+    if test "$out[1]" = ":files"
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $out
+end
+complete -c {{name}} -f -a '(__{{fn}}_complete)'
+`
+
+// filesDirective tells the completion scripts to fall back to file names.
+const filesDirective = ":files"
+
+// How long todos fetched for completion are reused before asking again.
+const completionCacheTTL = 30 * time.Second
+
+var globalFlags = []todoapp.Completion{
+	{Value: "--profile", Description: "Config profile to use"},
+	{Value: "--config", Description: "Path to the config file"},
+	{Value: "--local", Description: "Use a JSON todo database directly"},
+	{Value: "-l", Description: "Logging level"},
+}
+
+func runCompletionCommand(env *cliEnv, args []string) error {
+	if len(args) != 1 {
+		return usageError("todo completion bash|zsh|fish")
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", args[0])
+	}
+
+	name := filepath.Base(os.Args[0])
+	fn := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	script = strings.ReplaceAll(script, "{{name}}", name)
+	script = strings.ReplaceAll(script, "{{fn}}", fn)
+	fmt.Print(script)
+	return nil
+}
+
+func completeCompletionCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	if len(args) == 0 {
+		return todoapp.Completions("bash", "zsh", "fish")
+	}
+	return nil
+}
+
+// runCompleteCommand prints the candidates for the last of args, which is
+// the word being completed and may be empty, one per line as value<TAB>description.
+func runCompleteCommand(env *cliEnv, args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, current := args[:len(args)-1], args[len(args)-1]
+
+	// Global flags typed before the command change which profile or
+	// database the candidates come from
+	i := 0
+	var profileFlag, configFlag, localFlag string
+	for ; i < len(words) && strings.HasPrefix(words[i], "-"); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(words[i], "-"), "=")
+		if !hasValue && i+1 < len(words) {
+			i++
+			value = words[i]
+		}
+		switch name {
+		case "profile":
+			profileFlag = value
+		case "config":
+			configFlag = value
+		case "local":
+			localFlag = value
+		}
+	}
+	if profileFlag != "" || configFlag != "" || localFlag != "" {
+		if configFlag == "" {
+			configFlag = env.configPath
+		}
+		if localFlag == "" {
+			localFlag = env.localPath
+		}
+		if e, err := newCLIEnv(configFlag, profileFlag, localFlag); err == nil {
+			env = e
+		}
+	}
+	defer env.close()
+
+	var candidates []todoapp.Completion
+	if i == len(words) {
+		// Still completing global flags or the command name
+		previous := ""
+		if len(words) > 0 {
+			previous = strings.TrimLeft(words[len(words)-1], "-")
+		}
+		switch {
+		case previous == "profile" && !strings.Contains(words[len(words)-1], "="):
+			candidates = todoapp.Completions(env.cfg.ProfileNames()...)
+		case (previous == "config" || previous == "local") && !strings.Contains(words[len(words)-1], "="):
+			fmt.Println(filesDirective)
+			return nil
+		case previous == "l":
+			candidates = todoapp.Completions("-4", "0", "4", "8")
+		case strings.HasPrefix(current, "-"):
+			candidates = globalFlags
+		default:
+			for name, cmd := range commands {
+				if !cmd.hidden {
+					candidates = append(candidates, todoapp.Completion{Value: name, Description: cmd.summary})
+				}
+			}
+			sort.Slice(candidates, func(a, b int) bool { return candidates[a].Value < candidates[b].Value })
+		}
+	} else if cmd, ok := commands[words[i]]; ok && cmd.complete != nil {
+		candidates = cmd.complete(env, words[i+1:], current)
+		if len(candidates) == 1 && candidates[0].Value == filesDirective {
+			fmt.Println(filesDirective)
+			return nil
+		}
+	}
+
+	for _, c := range todoapp.FilterCompletions(candidates, current) {
+		fmt.Printf("%s\t%s\n", c.Value, c.Description)
+	}
+	return nil
+}
+
+// completionTodos returns the todos to complete IDs, tags and lists from,
+// cached briefly so completion stays fast.
+func completionTodos(env *cliEnv) map[string]types.Todo {
+	client, err := env.Client()
+	if err != nil {
+		return nil
+	}
+	dir, err := env.cacheDir()
+	if err != nil {
+		return nil
+	}
+
+	todos, err := todoapp.NewCompletionCache(dir, completionCacheTTL).Todos(client)
+	if err != nil {
+		return nil
+	}
+	return todos
+}
+
+// viewCompletions returns the saved views, described by their filters.
+func viewCompletions(env *cliEnv) []todoapp.Completion {
+	client, err := env.Client()
+	if err != nil {
+		return nil
+	}
+	savedViews, _ := client.GetViews()
+	var completions []todoapp.Completion
+	for _, v := range savedViews {
+		completions = append(completions, todoapp.Completion{Value: v.Name, Description: v.Filter})
+	}
+	return completions
+}
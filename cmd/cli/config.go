@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	todoapp "grantjames.github.io/todo-app"
+)
+
+const configUsage = `usage: todo config [command]
+
+Commands:
+  show                 Show the selected profile with defaults and env overrides applied (default)
+  path                 Print the config file location
+  profiles             List the profiles in the config file
+  get <key>            Print a value from the selected profile
+  set <key> <value>    Set a value in the selected profile
+  unset <key>          Remove a value from the selected profile
+  use <profile>        Make <profile> the current profile
+  delete <profile>     Remove a profile
+
+Keys: ` + "server_url, token, default_list, time_zone, output, format, log_file, log_level"
+
+func runConfigCommand(env *cliEnv, args []string) error {
+	cfg, path, profileName := env.cfg, env.configPath, env.profileName
+	cmd := "show"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "show":
+		resolved, err := cfg.Resolve(profileName)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# profile %q from %s\n", profileName, path)
+		for _, key := range todoapp.ProfileKeys() {
+			value := resolved.Get(key)
+			if key == "token" && value != "" {
+				value = maskToken(value)
+			}
+			fmt.Printf("%s = %s\n", key, value)
+		}
+	case "path":
+		fmt.Println(path)
+	case "profiles":
+		for _, name := range cfg.ProfileNames() {
+			marker := " "
+			if name == profileName {
+				marker = "*"
+			}
+			fmt.Println(marker, name)
+		}
+	case "get":
+		if len(args) != 1 {
+			return errors.New(configUsage)
+		}
+		resolved, err := cfg.Resolve(profileName)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(todoapp.ProfileKeys(), args[0]) {
+			return fmt.Errorf("unknown config key %q", args[0])
+		}
+		fmt.Println(resolved.Get(args[0]))
+	case "set", "unset":
+		value := ""
+		if cmd == "set" && len(args) == 2 {
+			value = args[1]
+		} else if !(cmd == "unset" && len(args) == 1) {
+			return errors.New(configUsage)
+		}
+		p := cfg.Profiles[profileName]
+		if err := p.Set(args[0], value); err != nil {
+			return err
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		cfg.Profiles[profileName] = p
+		if cfg.CurrentProfile == "" {
+			cfg.CurrentProfile = profileName
+		}
+		return cfg.Save(path)
+	case "use":
+		if len(args) != 1 {
+			return errors.New(configUsage)
+		}
+		if _, ok := cfg.Profiles[args[0]]; !ok {
+			cfg.Profiles[args[0]] = todoapp.CLIProfile{}
+		}
+		cfg.CurrentProfile = args[0]
+		return cfg.Save(path)
+	case "delete":
+		if len(args) != 1 {
+			return errors.New(configUsage)
+		}
+		if _, ok := cfg.Profiles[args[0]]; !ok {
+			return fmt.Errorf("no profile named %q in config", args[0])
+		}
+		delete(cfg.Profiles, args[0])
+		if cfg.CurrentProfile == args[0] {
+			cfg.CurrentProfile = ""
+		}
+		return cfg.Save(path)
+	default:
+		return errors.New(configUsage)
+	}
+	return nil
+}
+
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", len(token)-4) + token[len(token)-4:]
+}
+
+func completeConfigCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	if len(args) == 0 {
+		return todoapp.Completions("show", "path", "profiles", "get", "set", "unset", "use", "delete")
+	}
+	if len(args) > 1 {
+		return nil
+	}
+
+	switch args[0] {
+	case "get", "set", "unset":
+		return todoapp.Completions(todoapp.ProfileKeys()...)
+	case "use", "delete":
+		return todoapp.Completions(env.cfg.ProfileNames()...)
+	}
+	return nil
+}
@@ -1,32 +1,81 @@
 package main
 
 import (
+	"errors"
 	"flag"
-	"log/slog"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
-	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
 )
 
 func main() {
-	var lFlag = flag.Int("l", 0, "Specify the logging level. DEBUG, INFO, WARN, ERROR")
+	var lFlag = flag.Int("l", 0, "Specify the logging level. DEBUG, INFO, WARN, ERROR. Overrides the profile's log_level")
+	var profileFlag = flag.String("profile", "", "Config profile to use. Defaults to $TODO_PROFILE or the current profile")
+	var configFlag = flag.String("config", "", "Path to the config file. Defaults to the user config directory")
+	var localFlag = flag.String("local", "", "Path to a JSON todo database to use directly instead of the server")
+	flag.Usage = usage
 	flag.Parse()
 
-	f, err := os.OpenFile("app.log",
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	env, err := newCLIEnv(*configFlag, *profileFlag, *localFlag)
 	if err != nil {
-		panic(err)
+		fatal(err)
 	}
-	defer f.Close()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "l" {
+			env.logLevel = lFlag
+		}
+	})
 
-	opts := &slog.HandlerOptions{
-		Level: slog.Level(*lFlag),
+	args := flag.Args()
+	if len(args) == 0 {
+		err = runREPL(env)
+	} else if cmd, ok := commands[args[0]]; ok {
+		err = cmd.run(env, args[1:])
+	} else {
+		err = fmt.Errorf("unknown command %q, run with -h for a list of commands", args[0])
 	}
+	// Close the store or client before exiting, even after an error
+	env.close()
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: todo [flags] [command] [args]")
+	fmt.Fprintln(out, "\nWith no command the interactive menu is started.\n\nCommands:")
 
-	logger := slog.New(slog.NewTextHandler(f, opts))
-	slog.SetDefault(logger)
+	names := make([]string, 0, len(commands))
+	for name, cmd := range commands {
+		if !cmd.hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].summary)
+	}
 
-	app := todoapp.NewCLI(*todoapp.NewTodoAPIClient("http://localhost:5000/api"))
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
 
-	app.Start()
+// fatal prints err and exits with a status saying what kind of error it
+// was: 2 for invalid input, 3 if something wasn't found, 4 for a conflict
+// and otherwise 1.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "todo:", strings.TrimSpace(err.Error()))
+	switch {
+	case errors.Is(err, types.ErrInvalid):
+		os.Exit(2)
+	case errors.Is(err, types.ErrNotFound):
+		os.Exit(3)
+	case errors.Is(err, types.ErrConflict):
+		os.Exit(4)
+	}
+	os.Exit(1)
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
+)
+
+func runSearchCommand(env *cliEnv, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "Most results to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("todo search [--limit n] <words...>")
+	}
+
+	profile, err := env.Profile()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	results, err := client.Search(strings.Join(fs.Args(), " "), *limit)
+	if err = reportOffline(err); err != nil {
+		return err
+	}
+
+	if profile.Output == todoapp.OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No todos found")
+		return nil
+	}
+	for _, r := range results {
+		fmt.Printf("%s  %-12s %s\n", todoapp.ShortHandle(r.Id), r.Todo.Status, highlight(r.Snippet, r.Highlights))
+	}
+	return nil
+}
+
+func completeSearchCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	return []todoapp.Completion{
+		{Value: "--limit", Description: "Most results to show"},
+	}
+}
+
+// highlight wraps the matched words in a snippet in asterisks.
+func highlight(snippet string, ranges []types.TextRange) string {
+	var b strings.Builder
+	last := 0
+	for _, r := range ranges {
+		if r.Start < last || r.End > len(snippet) {
+			continue
+		}
+		b.WriteString(snippet[last:r.Start])
+		b.WriteString("*" + snippet[r.Start:r.End] + "*")
+		last = r.End
+	}
+	b.WriteString(snippet[last:])
+	return b.String()
+}
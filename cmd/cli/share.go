@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
+)
+
+const shareUsage = `usage: todo share <todo|list> ...
+
+Commands:
+  todo <id>                             List who a todo is shared with
+  todo <id> <user> <role>               Share a todo as a viewer, editor or owner
+  todo <id> <user> none                 Stop sharing a todo
+  list [--owner <user>] <list>          List who a list is shared with
+  list [--owner <user>] <list> <user> <role|none>
+                                        Share every todo in a list, or stop
+
+--owner is for a list another user shared with you, by their name.`
+
+func runShareCommand(env *cliEnv, args []string) error {
+	if len(args) == 0 {
+		return errors.New(shareUsage)
+	}
+	kind, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("share "+kind, flag.ContinueOnError)
+	owner := fs.String("owner", "", "The name of the user the list belongs to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if (kind != "todo" && kind != "list") || (len(args) != 1 && len(args) != 3) || (kind == "todo" && *owner != "") {
+		return errors.New(shareUsage)
+	}
+
+	profile, err := env.Profile()
+	if err != nil {
+		return err
+	}
+	// Sharing can only be done online, so skip the offline client
+	client := apiClient(profile)
+
+	if len(args) == 1 {
+		var members []types.Member
+		if kind == "todo" {
+			members, err = client.TodoMembers(args[0])
+		} else {
+			members, err = client.ListMembers(*owner, args[0])
+		}
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tROLE")
+		for _, m := range members {
+			fmt.Fprintf(w, "%s\t%s\n", m.Name, m.Role)
+		}
+		return w.Flush()
+	}
+
+	target, user := args[0], args[1]
+	if args[2] == "none" {
+		if kind == "todo" {
+			err = client.UnshareTodo(target, user)
+		} else {
+			err = client.UnshareList(*owner, target, user)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stopped sharing the %s with %s\n", kind, user)
+		return nil
+	}
+
+	role, err := types.ParseRole(args[2])
+	if err != nil {
+		return err
+	}
+	if kind == "todo" {
+		_, err = client.ShareTodo(target, user, role)
+	} else {
+		_, err = client.ShareList(*owner, target, user, role)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Shared the %s with %s as %s\n", kind, user, role)
+	return nil
+}
+
+func completeShareCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	switch len(args) {
+	case 0:
+		return todoapp.Completions("todo", "list")
+	case 3:
+		return todoapp.Completions("viewer", "editor", "owner", "none")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/types"
+)
+
+func runStatsCommand(env *cliEnv, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	since := fs.String("since", "", "Start of the report, yyyy-mm-dd. Defaults to 30 days before --until")
+	until := fs.String("until", "", "End of the report, yyyy-mm-dd (inclusive). Defaults to now")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	profile, err := env.Profile()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	loc := profile.Location()
+	start, end, err := types.ParseStatsRange(*since, *until, time.Now(), loc)
+	if err != nil {
+		return err
+	}
+
+	stats, err := client.GetStats(start, end)
+	if err = reportOffline(err); err != nil {
+		return err
+	}
+
+	if profile.Output == todoapp.OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	printStats(stats, loc)
+	return nil
+}
+
+func completeStatsCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	return []todoapp.Completion{
+		{Value: "--since", Description: "Start of the report, yyyy-mm-dd"},
+		{Value: "--until", Description: "End of the report, yyyy-mm-dd"},
+	}
+}
+
+func printStats(stats *types.TodoStats, loc *time.Location) {
+	fmt.Printf("*** Todo stats from %s to %s ***\n\n", stats.Since.In(loc).Format(time.DateOnly), stats.Until.In(loc).Format(time.DateOnly))
+
+	fmt.Printf("%d todos\n", stats.Total)
+	for _, s := range types.Statuses {
+		fmt.Printf("  %-12s %d\n", s, stats.ByStatus[s])
+	}
+
+	fmt.Println("\nCompleted per week")
+	for _, w := range stats.CompletedPerWeek {
+		fmt.Printf("  %s  %3d %s\n", w.Start.Format(time.DateOnly), w.Count, strings.Repeat("#", w.Count))
+	}
+
+	fmt.Println("\nCompleted per day")
+	for _, d := range stats.CompletedPerDay {
+		if d.Count > 0 {
+			fmt.Printf("  %s  %3d %s\n", d.Start.Format(time.DateOnly), d.Count, strings.Repeat("#", d.Count))
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Average lead time (created to completed): %s (%d todos)\n", formatHours(stats.AverageLeadTimeHours), stats.LeadTimeSamples)
+	fmt.Printf("Average cycle time (started to completed): %s (%d todos)\n", formatHours(stats.AverageCycleTimeHours), stats.CycleTimeSamples)
+
+	fmt.Println("\nOverdue at the end of each week")
+	for i, d := range stats.OverduePerDay {
+		if d.Start.Weekday() == time.Sunday || i == len(stats.OverduePerDay)-1 {
+			fmt.Printf("  %s  %3d %s\n", d.Start.Format(time.DateOnly), d.Count, strings.Repeat("!", d.Count))
+		}
+	}
+
+	if len(stats.OldestOpen) > 0 {
+		fmt.Println("\nOldest open todos")
+		for _, t := range stats.OldestOpen {
+			fmt.Printf("  %s  %4d days  %-12s %s\n", todoapp.ShortHandle(t.Id), t.AgeDays, t.Status, t.Description)
+		}
+	}
+}
+
+func formatHours(hours float64) string {
+	if hours >= 48 {
+		return fmt.Sprintf("%.1f days", hours/24)
+	}
+	return fmt.Sprintf("%.1f hours", hours)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/filter"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+func runListCommand(env *cliEnv, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	status := fs.String("status", "", "Only show todos with this status")
+	overdue := fs.Bool("overdue", false, "Only show overdue todos")
+	all := fs.Bool("all", false, "Include completed todos")
+	tag := fs.String("tag", "", "Only show todos with this tag")
+	list := fs.String("list", "", "Only show todos in this list")
+	where := fs.String("where", "", `Only show todos matching a filter, e.g. 'status:started AND due<2026-11-01'`)
+	view := fs.String("view", "", "Show the todos in a saved view")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *view != "" {
+		return runListView(env, *view)
+	}
+
+	var expr filter.Expr
+	if *where != "" {
+		var err error
+		expr, err = filter.Parse(*where)
+		if syntaxErr, ok := err.(*filter.SyntaxError); ok {
+			return fmt.Errorf("%v\n%s", err, syntaxErr.Caret(*where))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	app, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+
+	var todos map[string]types.Todo
+	var s types.Status
+	if *status != "" {
+		if s, err = types.ParseStatus(*status); err != nil {
+			return err
+		}
+	}
+	switch {
+	case expr != nil:
+		// The server filters, and a filter decides for itself whether
+		// completed todos are included
+		todos, err = queryTodos(client, types.TodoQuery{Status: s, Overdue: *overdue, All: *all, Filter: expr})
+	case s != "":
+		todos, err = client.GetTodosByStatus(s)
+	case *overdue:
+		todos, err = client.GetOverdueTodos()
+	case *all:
+		todos, err = allTodos(client)
+	default:
+		todos, err = client.GetAllTodos()
+	}
+	if err = reportOffline(err); err != nil {
+		return err
+	}
+
+	for id, t := range todos {
+		if *tag != "" && !slices.Contains(t.Tags, *tag) || *list != "" && t.List != *list {
+			delete(todos, id)
+		}
+	}
+
+	app.ShowTodos(todos)
+	return nil
+}
+
+// runListView shows a saved view's todos in its order and columns.
+func runListView(env *cliEnv, name string) error {
+	app, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+
+	savedViews, err := client.GetViews()
+	if err = reportOffline(err); err != nil {
+		return err
+	}
+	i := slices.IndexFunc(savedViews, func(v views.View) bool { return v.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w %q", views.ErrUnknownView, name)
+	}
+
+	todos, err := client.GetViewTodos(name)
+	if err = reportOffline(err); err != nil {
+		return err
+	}
+	app.ShowTodoItems(todos, savedViews[i].ShownColumns())
+	return nil
+}
+
+func completeListCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	previous := ""
+	if len(args) > 0 {
+		previous = args[len(args)-1]
+	}
+
+	switch previous {
+	case "--status", "-status":
+		return todoapp.StatusCompletions()
+	case "--tag", "-tag":
+		return todoapp.TagCompletions(completionTodos(env))
+	case "--list", "-list":
+		return todoapp.ListCompletions(completionTodos(env))
+	case "--view", "-view":
+		return viewCompletions(env)
+	}
+	return []todoapp.Completion{
+		{Value: "--status", Description: "Only show todos with this status"},
+		{Value: "--overdue", Description: "Only show overdue todos"},
+		{Value: "--all", Description: "Include completed todos"},
+		{Value: "--tag", Description: "Only show todos with this tag"},
+		{Value: "--list", Description: "Only show todos in this list"},
+		{Value: "--where", Description: "Only show todos matching a filter"},
+		{Value: "--view", Description: "Show the todos in a saved view"},
+	}
+}
+
+func runShowCommand(env *cliEnv, args []string) error {
+	if len(args) != 1 {
+		return usageError("todo show <id>")
+	}
+
+	app, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+
+	id, err := resolveTodoId(client, args[0])
+	if err != nil {
+		return err
+	}
+	todo, err := client.GetTodo(id)
+	if todo == nil {
+		return err
+	}
+	reportOffline(err)
+
+	app.ShowTodos(map[string]types.Todo{id: *todo})
+	return nil
+}
+
+func completeShowCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	if len(args) == 0 {
+		return todoapp.TodoCompletions(completionTodos(env), current)
+	}
+	return nil
+}
+
+func runStatusCommand(env *cliEnv, args []string) error {
+	if len(args) != 2 {
+		return usageError("todo status <id> <status>")
+	}
+
+	status, err := types.ParseStatus(args[1])
+	if err != nil {
+		return err
+	}
+
+	_, client, err := newCLIApp(env)
+	if err != nil {
+		return err
+	}
+
+	id, err := resolveTodoId(client, args[0])
+	if err != nil {
+		return err
+	}
+	if err := reportOffline(client.UpdateTodoStatus(id, status)); err != nil {
+		return err
+	}
+
+	fmt.Println("Todo status updated")
+	return nil
+}
+
+func completeStatusCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	switch len(args) {
+	case 0:
+		return todoapp.TodoCompletions(completionTodos(env), current)
+	case 1:
+		return todoapp.StatusCompletions()
+	}
+	return nil
+}
+
+func newCLIApp(env *cliEnv) (*todoapp.CLI, todoapp.TodoClient, error) {
+	profile, err := env.Profile()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	return todoapp.NewCLI(client, profile), client, nil
+}
+
+func allTodos(client todoapp.TodoClient) (map[string]types.Todo, error) {
+	active, err := client.GetAllTodos()
+	if err != nil && !errors.Is(err, todoapp.ErrOffline) {
+		return nil, err
+	}
+	completed, cerr := client.GetTodosByStatus(types.Completed)
+	if cerr != nil && !errors.Is(cerr, todoapp.ErrOffline) {
+		return nil, cerr
+	}
+	// Either can be nil offline, and neither is ours to change
+	todos := map[string]types.Todo{}
+	maps.Copy(todos, active)
+	maps.Copy(todos, completed)
+	return todos, errors.Join(err, cerr)
+}
+
+// queryTodos fetches every todo matching q, a page at a time.
+func queryTodos(client todoapp.TodoClient, q types.TodoQuery) (map[string]types.Todo, error) {
+	todos := map[string]types.Todo{}
+	for {
+		page, err := client.QueryTodos(q)
+		if page != nil {
+			for _, item := range page.Todos {
+				todos[item.Id] = item.Todo
+			}
+		}
+		if err != nil || page.NextCursor == "" {
+			return todos, err
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// resolveTodoId expands a short handle typed on the command line.
+func resolveTodoId(client todoapp.TodoClient, handle string) (string, error) {
+	todos, err := allTodos(client)
+	if err != nil && !errors.Is(err, todoapp.ErrOffline) {
+		return "", err
+	}
+	return todoapp.ResolveHandle(todos, handle)
+}
+
+// reportOffline turns the offline client's ErrOffline into a notice, since
+// the cached result or queued change is still usable.
+func reportOffline(err error) error {
+	if errors.Is(err, todoapp.ErrOffline) {
+		fmt.Println("(The server is unavailable, using cached todos and queueing changes)")
+		return nil
+	}
+	return err
+}
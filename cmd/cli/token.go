@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	todoapp "grantjames.github.io/todo-app"
+)
+
+const tokenUsage = `usage: todo token [command]
+
+Commands:
+  list                         List your tokens on the server (default)
+  create [--save] [name]       Create a token, and with --save use it in the selected profile
+  revoke <id>                  Revoke a token
+
+The first token comes from the server's admin, and is set with:
+  todo config set token <token>`
+
+func runTokenCommand(env *cliEnv, args []string) error {
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	profile, err := env.Profile()
+	if err != nil {
+		return err
+	}
+	// Tokens can only be managed online, so skip the offline client
+	client := apiClient(profile)
+
+	switch cmd {
+	case "list":
+		tokens, err := client.Tokens()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCREATED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Id, t.Name, t.Created.In(profile.Location()).Format(time.DateTime))
+		}
+		return w.Flush()
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		save := fs.Bool("save", false, "Save the token in the selected profile")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() > 1 {
+			return errors.New(tokenUsage)
+		}
+
+		_, secret, err := client.CreateToken(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if !*save {
+			fmt.Println(secret)
+			return nil
+		}
+		p := env.cfg.Profiles[env.profileName]
+		p.Token = secret
+		env.cfg.Profiles[env.profileName] = p
+		if err := env.cfg.Save(env.configPath); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the new token in profile %q\n", env.profileName)
+	case "revoke":
+		if len(args) != 1 {
+			return errors.New(tokenUsage)
+		}
+		if err := client.RevokeToken(args[0]); err != nil {
+			return err
+		}
+		fmt.Println("Revoked", args[0])
+	default:
+		return errors.New(tokenUsage)
+	}
+	return nil
+}
+
+func completeTokenCommand(env *cliEnv, args []string, current string) []todoapp.Completion {
+	if len(args) == 0 {
+		return todoapp.Completions("list", "create", "revoke")
+	}
+	if args[0] == "create" {
+		return []todoapp.Completion{{Value: "--save", Description: "Save the token in the selected profile"}}
+	}
+	return nil
+}
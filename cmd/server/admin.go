@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"grantjames.github.io/todo-app/users"
+)
+
+const adminUsage = `usage: server <command>
+
+Commands, which change the users file the server reads:
+  users add [-admin] <name>     Add a user and create their first token
+  users list                    List the users
+  users passwd <name>           Set the password a user signs in to the web pages
+                                with, read from stdin
+  tokens create <user> [name]   Create a token for a user
+  tokens list [user]            List everyone's tokens, or a user's
+  tokens revoke <id>            Revoke a token
+
+Tokens are only shown when they're created. Put one in the CLI's config
+with: todo config set token <token>`
+
+// adminCommands are run instead of the server when one is given as an
+// argument.
+var adminCommands = map[string]func(store *users.Store, args []string) error{
+	"users":  runUsersCommand,
+	"tokens": runTokensCommand,
+}
+
+func runAdminCommand(path string, args []string) error {
+	run, ok := adminCommands[args[0]]
+	if !ok {
+		return errors.New(adminUsage)
+	}
+	store, err := users.NewStore(path)
+	if err != nil {
+		return err
+	}
+	return run(store, args[1:])
+}
+
+func runUsersCommand(store *users.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("users add", flag.ContinueOnError)
+		admin := fs.Bool("admin", false, "Let the user manage webhooks")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(adminUsage)
+		}
+		user, err := store.AddUser(fs.Arg(0), *admin)
+		if err != nil {
+			return err
+		}
+		_, secret, err := store.CreateToken(user.Id, "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %s (%s) with the token:\n%s\n", user.Name, user.Id, secret)
+	case "list":
+		all, err := store.Users()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tID\tADMIN\tCREATED")
+		for _, u := range all {
+			fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", u.Name, u.Id, u.Admin, u.Created.Format(time.DateTime))
+		}
+		return w.Flush()
+	case "passwd":
+		if len(args) != 2 {
+			return errors.New(adminUsage)
+		}
+		user, err := store.UserNamed(args[1])
+		if err != nil {
+			return fmt.Errorf("%v %q", err, args[1])
+		}
+		fmt.Fprintf(os.Stderr, "Password for %s: ", user.Name)
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			return fmt.Errorf("problem reading the password, %v", err)
+		}
+		if err := store.SetPassword(user.Id, strings.TrimRight(password, "\r\n")); err != nil {
+			return err
+		}
+		fmt.Println("Set the password for", user.Name)
+	default:
+		return errors.New(adminUsage)
+	}
+	return nil
+}
+
+func runTokensCommand(store *users.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	switch {
+	case args[0] == "create" && (len(args) == 2 || len(args) == 3):
+		user, err := store.UserNamed(args[1])
+		if err != nil {
+			return fmt.Errorf("%v %q", err, args[1])
+		}
+		name := ""
+		if len(args) == 3 {
+			name = args[2]
+		}
+		_, secret, err := store.CreateToken(user.Id, name)
+		if err != nil {
+			return err
+		}
+		fmt.Println(secret)
+	case args[0] == "list" && len(args) <= 2:
+		userId := ""
+		if len(args) == 2 {
+			user, err := store.UserNamed(args[1])
+			if err != nil {
+				return fmt.Errorf("%v %q", err, args[1])
+			}
+			userId = user.Id
+		}
+		tokens, err := store.Tokens(userId)
+		if err != nil {
+			return err
+		}
+		all, err := store.Users()
+		if err != nil {
+			return err
+		}
+		names := map[string]string{}
+		for _, u := range all {
+			names[u.Id] = u.Name
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER\tNAME\tCREATED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Id, names[t.UserId], t.Name, t.Created.Format(time.DateTime))
+		}
+		return w.Flush()
+	case args[0] == "revoke" && len(args) == 2:
+		if err := store.RevokeToken("", args[1]); err != nil {
+			return err
+		}
+		fmt.Println("Revoked", args[1])
+	default:
+		return errors.New(adminUsage)
+	}
+	return nil
+}
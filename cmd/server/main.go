@@ -2,46 +2,140 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/ratelimit"
+	"grantjames.github.io/todo-app/reminders"
+	"grantjames.github.io/todo-app/sharing"
 	"grantjames.github.io/todo-app/stores"
 	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+	"grantjames.github.io/todo-app/views"
+	"grantjames.github.io/todo-app/webhooks"
 )
 
-const dbFileName = "db.json"
+const (
+	dbFileName        = "db.json"
+	webhooksFileName  = "webhooks.json"
+	remindersFileName = "reminders.json"
+	viewsFileName     = "views.json"
+	usersFileName     = "users.json"
+	sharesFileName    = "shares.json"
+)
 
 func main() {
 	var storageFlag = flag.Int("f", 0, "Specify which data store to use. 0 = File, 1 = Memory. Default = 0")
+	var remindFlag = flag.String("reminders", reminders.DefaultRules, "Comma separated reminders to send: before:<duration>, due, overdue:<interval>. Empty to disable")
+	var mailDirFlag = flag.String("remind-mail-dir", "", "Also write reminders as mail files into this directory")
+	var mailToFlag = flag.String("remind-mail-to", "todo@localhost", "Recipient address for reminder mail files")
+	var commandFlag = flag.String("remind-command", "", "Also run this command for each reminder, with the subject and body as arguments, e.g. notify-send")
+	var authFlag = flag.Bool("auth", true, "Require a token from one of the users in "+usersFileName+". Without it anyone can use the API")
+	var readLimitFlag = flag.String("read-limit", "600/m", "How many GET requests each token, or address without one, can make per s, m or h. 0 for no limit")
+	var writeLimitFlag = flag.String("write-limit", "120/m", "How many other requests each token, or address without one, can make per s, m or h. 0 for no limit")
+	var secureCookiesFlag = flag.Bool("secure-cookies", false, "Only send the web pages' session cookie over HTTPS, when behind a proxy that serves it")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), adminUsage)
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags, when running the server:")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	if flag.NArg() > 0 {
+		if err := runAdminCommand(usersFileName, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	base := slog.NewTextHandler(os.Stdout, nil)
 	logger := slog.New(&traceIdContextHandler{h: base})
 	slog.SetDefault(logger)
 
 	var store types.TodoStore
+	webhooksPath, remindersPath, viewsPath, sharesPath := "", "", "", ""
 	if *storageFlag == 0 {
 		slog.Info("Using File Todo Store")
 
-		db, err := os.OpenFile(dbFileName, os.O_RDWR|os.O_CREATE, 0666)
+		fileStore, err := stores.OpenJSONFileTodoStore(dbFileName)
+		if err != nil {
+			log.Fatalf("problem creating file todo store, %v", err)
+		}
+		defer fileStore.Close()
+		store = fileStore
+		webhooksPath, remindersPath, viewsPath, sharesPath = webhooksFileName, remindersFileName, viewsFileName, sharesFileName
+	} else {
+		slog.Info("Using Memory Todo Store")
+		store = stores.NewInMemoryTodoStore()
+	}
+
+	dispatcher, err := webhooks.NewDispatcher(webhooksPath)
+	if err != nil {
+		log.Fatalf("problem loading webhooks, %v", err)
+	}
+	savedViews, err := views.NewStore(viewsPath)
+	if err != nil {
+		log.Fatalf("problem loading views, %v", err)
+	}
+	readLimit, err := ratelimit.ParseLimit(*readLimitFlag)
+	if err != nil {
+		log.Fatalf("problem parsing -read-limit, %v", err)
+	}
+	writeLimit, err := ratelimit.ParseLimit(*writeLimitFlag)
+	if err != nil {
+		log.Fatalf("problem parsing -write-limit, %v", err)
+	}
+	opts := []todoapp.ServerOption{todoapp.WithWebhooks(dispatcher), todoapp.WithViews(savedViews), todoapp.WithRateLimits(readLimit, writeLimit)}
+	if *secureCookiesFlag {
+		opts = append(opts, todoapp.WithSecureCookies())
+	}
 
+	if *authFlag {
+		// Users are kept in the file even with the memory store, so the
+		// admin commands can add them
+		userStore, err := users.NewStore(usersFileName)
 		if err != nil {
-			log.Fatalf("problem opening %s %v", dbFileName, err)
+			log.Fatalf("problem loading users, %v", err)
+		}
+		if all, _ := userStore.Users(); len(all) == 0 {
+			slog.Warn("There are no users, so nobody can use the API. Add one with: server users add -admin <name>, and give them a password for the web pages with: server users passwd <name>")
+		}
+		shares, err := sharing.NewStore(sharesPath)
+		if err != nil {
+			log.Fatalf("problem loading shared lists, %v", err)
+		}
+		opts = append(opts, todoapp.WithUsers(userStore), todoapp.WithSharing(shares))
+	}
+
+	rules, err := reminders.ParseRules(*remindFlag)
+	if err != nil {
+		log.Fatalf("problem parsing reminders, %v", err)
+	}
+	if len(rules) > 0 {
+		notifiers := []reminders.Notifier{reminders.LogNotifier{}, reminders.WebhookNotifier{Dispatcher: dispatcher}}
+		if *mailDirFlag != "" {
+			notifiers = append(notifiers, reminders.MailDropNotifier{Dir: *mailDirFlag, From: "todo-app@localhost", To: *mailToFlag})
+		}
+		if *commandFlag != "" {
+			fields := strings.Fields(*commandFlag)
+			notifiers = append(notifiers, reminders.CommandNotifier{Command: fields[0], Args: fields[1:], Timeout: 10 * time.Second})
 		}
 
-		store, err = stores.NewJSONFileTodoStore(db)
+		scheduler, err := reminders.NewScheduler(rules, notifiers, remindersPath)
 		if err != nil {
-			slog.Error("problem creating file todo store, %v", "error", err.Error())
+			log.Fatalf("problem loading reminders, %v", err)
 		}
-	} else {
-		slog.Info("Using Memory Todo Store")
-		store = stores.NewInMemoryTodoStore()
+		opts = append(opts, todoapp.WithReminders(scheduler))
 	}
 
 	a := stores.NewTodoStoreActor(store)
-	server := todoapp.NewTodoServer(a)
+	server := todoapp.NewTodoServer(a, opts...)
 	log.Fatal(http.ListenAndServe(":5000", todoapp.LoggingMiddleware(server)))
 }
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	todoapp "grantjames.github.io/todo-app"
+	"grantjames.github.io/todo-app/users"
 )
 
 type traceIdContextHandler struct{ h slog.Handler }
@@ -25,6 +26,9 @@ func (a *traceIdContextHandler) Enabled(ctx context.Context, lvl slog.Level) boo
 func (a *traceIdContextHandler) Handle(ctx context.Context, r slog.Record) error {
 	rr := r.Clone()
 	rr.AddAttrs(slog.String("trace_id", traceFrom(ctx)))
+	if user, ok := ctx.Value(todoapp.UserKey{}).(users.User); ok {
+		rr.AddAttrs(slog.String("user", user.Name))
+	}
 	return a.h.Handle(ctx, rr)
 }
 
@@ -0,0 +1,226 @@
+package todoapp
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// Codec reads and writes the bodies of the todo endpoints in one media type.
+// The server picks one from a request's Accept and Content-Type headers,
+// and TodoAPIClient uses the one it's given WithCodec.
+//
+// Codecs are given todos as types.Todo, types.TodoItem or slices of them,
+// v2's todos, and the body of a status update.
+type Codec interface {
+	MediaType() string
+	Encode(w io.Writer, v any) error
+	// Decode reads one value into v, ignoring fields v doesn't have
+	Decode(r io.Reader, v any) error
+}
+
+// StrictDecoder is implemented by codecs that can reject fields v doesn't
+// have, returning a *types.ValidationError. The server uses it for request
+// bodies if the codec has it.
+type StrictDecoder interface {
+	DecodeStrict(r io.Reader, v any) error
+}
+
+var (
+	JSONCodec Codec = jsonCodec{}
+	XMLCodec  Codec = xmlCodec{}
+	CSVCodec  Codec = csvCodec{}
+	YAMLCodec Codec = yamlCodec{}
+)
+
+// codecs are the registered codecs by media type, in the order they were
+// registered.
+var codecs = struct {
+	sync.RWMutex
+	all []Codec
+}{all: []Codec{JSONCodec, XMLCodec, CSVCodec, YAMLCodec}}
+
+// RegisterCodec adds c to the codecs the server can use, replacing any
+// registered for the same media type.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.all = slices.DeleteFunc(codecs.all, func(r Codec) bool { return r.MediaType() == c.MediaType() })
+	codecs.all = append(codecs.all, c)
+}
+
+// Codecs returns the registered codecs, JSON first.
+func Codecs() []Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return slices.Clone(codecs.all)
+}
+
+func codecFor(mediaType string) (Codec, bool) {
+	for _, c := range Codecs() {
+		if c.MediaType() == mediaType {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// responseCodec picks the codec for the request's Accept header, preferring
+// higher q values and then the order they're listed. JSON is used when
+// there's no Accept header or anything is accepted. It returns false if
+// none of the accepted media types have a codec.
+func responseCodec(r *http.Request) (Codec, bool) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return JSONCodec, true
+	}
+
+	type ranged struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []ranged
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, ranged{mediaType, q})
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b ranged) int { return cmp.Compare(b.q, a.q) })
+
+	for _, rng := range ranges {
+		if c, ok := codecFor(rng.mediaType); ok {
+			return c, true
+		}
+		// Such as TodoMapMediaType, which is JSON
+		if _, suffix, ok := strings.Cut(rng.mediaType, "+"); ok {
+			if c, ok := codecFor("application/" + suffix); ok {
+				return c, true
+			}
+		}
+		// Of the codecs in the range, the first registered, which is JSON
+		// for */* and application/*
+		for _, c := range Codecs() {
+			if matchesRange(c.MediaType(), rng.mediaType) {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func matchesRange(mediaType, rng string) bool {
+	if rng == "*/*" {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(rng, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// requestCodec picks the codec for the request's Content-Type, JSON if it
+// doesn't have one. It returns false if there's no codec for it.
+func requestCodec(r *http.Request) (Codec, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return JSONCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	return codecFor(mediaType)
+}
+
+// writeTodos responds with v encoded by the codec the client accepts, or a
+// 406 problem if there isn't one.
+func writeTodos(w http.ResponseWriter, r *http.Request, status int, v any) {
+	c, ok := responseCodec(r)
+	if !ok {
+		problem(w, r, http.StatusNotAcceptable, ProblemNotAcceptable, "todos can be sent as "+mediaTypes())
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	if c == JSONCodec {
+		writeJSON(w, status, v)
+		return
+	}
+	w.Header().Set("Content-Type", c.MediaType())
+	w.WriteHeader(status)
+	if err := c.Encode(w, v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode response", slog.String("media_type", c.MediaType()), slog.String("error", err.Error()))
+	}
+}
+
+// decodeTodos reads a body of at most limit bytes into v with the codec for
+// its Content-Type. If it can't, it responds with a problem and returns
+// false.
+func decodeTodos(w http.ResponseWriter, r *http.Request, v any, limit int64) bool {
+	c, ok := requestCodec(r)
+	switch {
+	case !ok:
+		problem(w, r, http.StatusUnsupportedMediaType, ProblemUnsupportedMediaType, "todos can be sent as "+mediaTypes())
+		return false
+	case c == JSONCodec:
+		return decodeJSON(w, r, v, limit)
+	}
+
+	body := http.MaxBytesReader(w, r.Body, limit)
+	var err error
+	if strict, ok := c.(StrictDecoder); ok {
+		err = strict.DecodeStrict(body, v)
+	} else {
+		err = c.Decode(body, v)
+	}
+	var tooLarge *http.MaxBytesError
+	var validation *types.ValidationError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		problem(w, r, http.StatusRequestEntityTooLarge, ProblemTooLarge, "the body must be at most "+strconv.FormatInt(limit, 10)+" bytes")
+	case errors.As(err, &validation):
+		writeError(w, r, err)
+	default:
+		badRequest(w, r, err)
+	}
+	return false
+}
+
+func mediaTypes() string {
+	var names []string
+	for _, c := range Codecs() {
+		names = append(names, c.MediaType())
+	}
+	return strings.Join(names, ", ")
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string { return "application/json" }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,111 @@
+package todoapp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// csvCodec writes todos as rows under a header naming their fields, leaving
+// out fields that none of the todos have. Tags are separated by semicolons,
+// as are the status=time pairs of status_changed and the user=role pairs of
+// members. A single todo is a header and one row.
+type csvCodec struct{}
+
+func (csvCodec) MediaType() string { return "text/csv" }
+
+func (csvCodec) Encode(w io.Writer, v any) error {
+	records, _, err := encodeRecords(v)
+	if err != nil {
+		return err
+	}
+
+	var header []string
+	for _, name := range recordFieldNames {
+		if slices.ContainsFunc(records, func(rec []recordField) bool { return hasField(rec, name) }) {
+			header = append(header, name)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, rec := range records {
+		row := make([]string, len(header))
+		for _, f := range rec {
+			row[slices.Index(header, f.name)] = csvCell(f.value)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func hasField(rec []recordField, name string) bool {
+	return slices.ContainsFunc(rec, func(f recordField) bool { return f.name == name })
+}
+
+func csvCell(value any) string {
+	switch value := value.(type) {
+	case []string:
+		return strings.Join(value, ";")
+	case []keyValue:
+		pairs := make([]string, len(value))
+		for i, kv := range value {
+			pairs[i] = kv.key + "=" + kv.value
+		}
+		return strings.Join(pairs, ";")
+	}
+	return value.(string)
+}
+
+func (c csvCodec) Decode(r io.Reader, v any) error {
+	return c.decode(r, v, false)
+}
+
+func (c csvCodec) DecodeStrict(r io.Reader, v any) error {
+	return c.decode(r, v, true)
+}
+
+// decode treats an empty cell as a field the todo doesn't have, and an empty
+// body as no todos.
+func (csvCodec) decode(r io.Reader, v any, strict bool) error {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	// No todos have no fields, so their header is empty too
+	if len(rows) == 0 {
+		return decodeRecords(nil, false, v, strict)
+	}
+
+	header := rows[0]
+	var records []map[string]any
+	for _, row := range rows[1:] {
+		rec := map[string]any{}
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+			switch name := header[i]; name {
+			case "tags":
+				rec[name] = strings.Split(cell, ";")
+			case "status_changed", "members":
+				var pairs []keyValue
+				for pair := range strings.SplitSeq(cell, ";") {
+					key, value, ok := strings.Cut(pair, "=")
+					if !ok {
+						return fmt.Errorf("%s must be key=value pairs, got %q", name, pair)
+					}
+					pairs = append(pairs, keyValue{key, value})
+				}
+				rec[name] = pairs
+			default:
+				rec[name] = cell
+			}
+		}
+		records = append(records, rec)
+	}
+	return decodeRecords(records, false, v, strict)
+}
@@ -0,0 +1,294 @@
+package todoapp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// statusUpdate is the body of a request to change a todo's status.
+type statusUpdate struct {
+	Status types.Status `json:"status"`
+}
+
+// recordField is one field of a todo as the XML, CSV and YAML codecs see it.
+// The value is a string, a []string for tags or a []keyValue for the maps
+// status_changed and members. Times are RFC 3339.
+type recordField struct {
+	name  string
+	value any
+}
+
+type keyValue struct {
+	key   string
+	value string
+}
+
+// recordFieldNames are the fields of a todo record in the order the codecs
+// write them, the same as JSON's.
+var recordFieldNames = []string{
+	"id", "description", "status", "due", "list", "tags", "priority", "created", "updated", "status_changed", "version", "owner", "assignee", "members", "overdue", "url",
+}
+
+var todoFieldNames = recordFieldNames[1:14]
+
+// encodeRecords turns a value a Codec is given into todo records, and
+// whether it's a list of them.
+func encodeRecords(v any) ([][]recordField, bool, error) {
+	switch v := v.(type) {
+	case []types.TodoItem:
+		records := make([][]recordField, len(v))
+		for i, item := range v {
+			records[i] = todoRecord(item.Id, item.Todo)
+		}
+		return records, true, nil
+	case []todoV2:
+		records := make([][]recordField, len(v))
+		for i, todo := range v {
+			records[i] = todoV2Record(todo)
+		}
+		return records, true, nil
+	case types.Todo:
+		return [][]recordField{todoRecord("", v)}, false, nil
+	case types.TodoItem:
+		return [][]recordField{todoRecord(v.Id, v.Todo)}, false, nil
+	case todoV2:
+		return [][]recordField{todoV2Record(v)}, false, nil
+	case statusUpdate:
+		return [][]recordField{{{"status", string(v.Status)}}}, false, nil
+	}
+	return nil, false, fmt.Errorf("can't encode %T as a todo", v)
+}
+
+// todoRecord leaves out the same empty fields as JSON, and a missing due
+// date, which JSON writes as null.
+func todoRecord(id string, t types.Todo) []recordField {
+	var fields []recordField
+	add := func(name string, value any) {
+		fields = append(fields, recordField{name, value})
+	}
+	addTime := func(name string, at time.Time) {
+		if !at.IsZero() {
+			add(name, at.Format(time.RFC3339Nano))
+		}
+	}
+
+	if id != "" {
+		add("id", id)
+	}
+	add("description", t.Description)
+	add("status", string(t.Status))
+	if t.Due != nil {
+		addTime("due", *t.Due)
+	}
+	if t.List != "" {
+		add("list", t.List)
+	}
+	if len(t.Tags) > 0 {
+		add("tags", t.Tags)
+	}
+	if t.Priority != 0 {
+		add("priority", strconv.Itoa(t.Priority))
+	}
+	addTime("created", t.Created)
+	addTime("updated", t.Updated)
+	if len(t.StatusChanged) > 0 {
+		var changed []keyValue
+		// In the order a todo moves through them, rather than the map's
+		for _, status := range types.Statuses {
+			if at, ok := t.StatusChanged[status]; ok {
+				changed = append(changed, keyValue{string(status), at.Format(time.RFC3339Nano)})
+			}
+		}
+		add("status_changed", changed)
+	}
+	if t.Version != 0 {
+		add("version", strconv.Itoa(t.Version))
+	}
+	if t.Owner != "" {
+		add("owner", t.Owner)
+	}
+	if t.Assignee != "" {
+		add("assignee", t.Assignee)
+	}
+	if len(t.Members) > 0 {
+		var members []keyValue
+		for _, user := range slices.Sorted(maps.Keys(t.Members)) {
+			members = append(members, keyValue{user, string(t.Members[user])})
+		}
+		add("members", members)
+	}
+	return fields
+}
+
+func todoV2Record(t todoV2) []recordField {
+	return append(todoRecord(t.Id, t.Todo),
+		recordField{"overdue", strconv.FormatBool(t.Overdue)},
+		recordField{"url", t.URL})
+}
+
+// decodeRecords fills v, which is a pointer to a value a Codec is given,
+// from records read by a codec. If strict, fields v doesn't have are
+// invalid, otherwise they're ignored.
+func decodeRecords(records []map[string]any, list bool, v any, strict bool) error {
+	switch v := v.(type) {
+	case *[]types.TodoItem:
+		items := make([]types.TodoItem, len(records))
+		for i, rec := range records {
+			if err := decodeRecord(rec, &items[i], strict); err != nil {
+				return err
+			}
+		}
+		*v = items
+		return nil
+	case *[]todoV2:
+		todos := make([]todoV2, len(records))
+		for i, rec := range records {
+			if err := decodeRecord(rec, &todos[i], strict); err != nil {
+				return err
+			}
+		}
+		*v = todos
+		return nil
+	}
+
+	if list || len(records) != 1 {
+		return fmt.Errorf("expected one todo, got %d", len(records))
+	}
+	return decodeRecord(records[0], v, strict)
+}
+
+func decodeRecord(rec map[string]any, v any, strict bool) error {
+	var allowed []string
+	switch v.(type) {
+	case *statusUpdate:
+		allowed = []string{"status"}
+	case *types.Todo:
+		allowed = todoFieldNames
+	case *types.TodoItem:
+		allowed = recordFieldNames[:14]
+	case *todoV2:
+		allowed = recordFieldNames
+	default:
+		return fmt.Errorf("can't decode a todo into %T", v)
+	}
+
+	var invalid []types.FieldError
+	fail := func(name, detail string) {
+		invalid = append(invalid, types.FieldError{Field: name, Detail: detail})
+	}
+	str := func(name string) string {
+		s, ok := rec[name].(string)
+		if !ok && rec[name] != nil {
+			fail(name, "must be a single value")
+		}
+		return s
+	}
+	parseTime := func(name string) time.Time {
+		s := str(name)
+		if s == "" {
+			return time.Time{}
+		}
+		at, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			fail(name, "must be an RFC 3339 time")
+		}
+		return at
+	}
+
+	var t todoV2
+	for _, name := range slices.Sorted(maps.Keys(rec)) {
+		if !slices.Contains(allowed, name) {
+			if strict {
+				fail(name, "unknown field")
+			}
+			continue
+		}
+
+		var err error
+		switch name {
+		case "id":
+			t.Id = str(name)
+		case "description":
+			t.Description = str(name)
+		case "status":
+			t.Status = types.Status(str(name))
+		case "due":
+			if due := parseTime(name); !due.IsZero() {
+				t.Due = &due
+			}
+		case "list":
+			t.List = str(name)
+		case "tags":
+			switch tags := rec[name].(type) {
+			case []string:
+				t.Tags = tags
+			case string:
+				t.Tags = []string{tags}
+			}
+		case "priority":
+			if s := str(name); s != "" {
+				if t.Priority, err = strconv.Atoi(s); err != nil {
+					fail(name, "must be a whole number")
+				}
+			}
+		case "created":
+			t.Created = parseTime(name)
+		case "updated":
+			t.Updated = parseTime(name)
+		case "status_changed":
+			changed, _ := rec[name].([]keyValue)
+			t.StatusChanged = map[types.Status]time.Time{}
+			for _, c := range changed {
+				at, err := time.Parse(time.RFC3339Nano, c.value)
+				if err != nil {
+					fail(name, "must be RFC 3339 times")
+				}
+				t.StatusChanged[types.Status(c.key)] = at
+			}
+		case "version":
+			if s := str(name); s != "" {
+				if t.Version, err = strconv.Atoi(s); err != nil {
+					fail(name, "must be a whole number")
+				}
+			}
+		case "owner":
+			t.Owner = str(name)
+		case "assignee":
+			t.Assignee = str(name)
+		case "members":
+			members, _ := rec[name].([]keyValue)
+			t.Members = map[string]types.Role{}
+			for _, m := range members {
+				if t.Members[m.key], err = types.ParseRole(m.value); err != nil {
+					fail(name, err.Error())
+				}
+			}
+		case "overdue":
+			if t.Overdue, err = strconv.ParseBool(str(name)); err != nil {
+				fail(name, "must be true or false")
+			}
+		case "url":
+			t.URL = str(name)
+		}
+	}
+	if len(invalid) > 0 {
+		return &types.ValidationError{Fields: invalid}
+	}
+
+	switch v := v.(type) {
+	case *statusUpdate:
+		v.Status = t.Status
+	case *types.Todo:
+		*v = t.Todo
+	case *types.TodoItem:
+		*v = t.TodoItem
+	case *todoV2:
+		*v = t
+	}
+	return nil
+}
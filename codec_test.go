@@ -0,0 +1,152 @@
+package todoapp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestCodecsRoundTripTodos(t *testing.T) {
+	due := time.Date(2026, time.November, 1, 9, 0, 0, 0, time.UTC)
+	todo := types.NewTodo(`Buy "milk", eggs; bread # and jam`, &due)
+	todo.List = "shopping"
+	todo.Tags = []string{"errands", "food"}
+	todo.Created = time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	todo.Updated = todo.Created
+	todo.Version = 3
+	todo.Assignee = "user-2"
+	todo.Members = map[string]types.Role{"user-2": types.RoleEditor, "user-3": types.RoleViewer}
+	items := []types.TodoItem{{Id: "1", Todo: todo}, {Id: "2", Todo: types.NewTodo("Walk the dog", nil)}}
+
+	for _, c := range Codecs() {
+		t.Run(c.MediaType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Encode(&buf, items); err != nil {
+				t.Fatalf("unexpected error encoding %v", err)
+			}
+			var got []types.TodoItem
+			if err := c.Decode(&buf, &got); err != nil {
+				t.Fatalf("unexpected error decoding %v", err)
+			}
+			if len(got) != 2 || got[0].Id != "1" || got[0].Description != todo.Description || !reflect.DeepEqual(got[0].Tags, todo.Tags) {
+				t.Errorf("got %+v want %+v", got, items)
+			}
+			if got[0].Due == nil || !got[0].Due.Equal(due) || got[0].Version != 3 || got[1].Due != nil {
+				t.Errorf("got %+v want the due dates and version kept", got)
+			}
+			if got[0].Assignee != todo.Assignee || !reflect.DeepEqual(got[0].Members, todo.Members) {
+				t.Errorf("got %+v want the assignee and members kept", got[0])
+			}
+
+			buf.Reset()
+			if err := c.Encode(&buf, []types.TodoItem{}); err != nil {
+				t.Fatalf("unexpected error encoding no todos %v", err)
+			}
+			got = nil
+			if err := c.Decode(&buf, &got); err != nil || len(got) != 0 {
+				t.Errorf("got %+v and error %v want no todos", got, err)
+			}
+		})
+	}
+}
+
+func TestNegotiatingFormats(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	id, _ := store.AddTodo(t.Context(), types.NewTodo("Walk the dog", nil))
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	serve := func(method, url, contentType, accept, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		request.Header.Set("Accept", accept)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/*", "text/csv"},
+		{"application/json;q=0.5, application/yaml", "application/yaml"},
+		{"text/html, application/xml;q=0.9", "application/xml"},
+	}
+	for _, c := range cases {
+		t.Run("Accept "+c.accept+" is answered with "+c.want, func(t *testing.T) {
+			response := serve(http.MethodGet, "/api/v2/todos/"+id, "", c.accept, "")
+			assertStatus(t, response.Code, http.StatusOK)
+			if got := response.Header().Get("Content-Type"); !strings.HasPrefix(got, c.want) {
+				t.Errorf("got Content-Type %q want %q", got, c.want)
+			}
+			if got := response.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("got Vary %q want Accept", got)
+			}
+		})
+	}
+
+	t.Run("An XML todo can be added and is returned as XML", func(t *testing.T) {
+		body := `<todo><description>Feed the cat</description><tags><tag>pets</tag></tags></todo>`
+		response := serve(http.MethodPost, "/api/v2/todos/", "application/xml", "application/xml", body)
+		assertStatus(t, response.Code, http.StatusCreated)
+		var got todoV2
+		if err := XMLCodec.Decode(response.Body, &got); err != nil {
+			t.Fatalf("unexpected error decoding %v", err)
+		}
+		if got.Description != "Feed the cat" || !reflect.DeepEqual(got.Tags, []string{"pets"}) || got.Id == "" {
+			t.Errorf("got %+v want the added todo", got)
+		}
+	})
+
+	t.Run("A status can be updated with YAML", func(t *testing.T) {
+		response := serve(http.MethodPut, "/api/v2/todos/"+id, "application/yaml", "application/yaml", "status: Started\n")
+		assertStatus(t, response.Code, http.StatusOK)
+		if !strings.Contains(response.Body.String(), `status: "Started"`) {
+			t.Errorf("got body %q want the started todo", response.Body.String())
+		}
+	})
+
+	t.Run("Unknown fields are rejected in any format", func(t *testing.T) {
+		bodies := map[string]string{
+			"application/xml":  `<todo><description>Walk the dog</description><colour>red</colour></todo>`,
+			"text/csv":         "description,colour\nWalk the dog,red\n",
+			"application/yaml": "description: Walk the dog\ncolour: red\n",
+		}
+		for contentType, body := range bodies {
+			got := assertProblem(t, serve(http.MethodPost, "/api/todos/", contentType, "", body), http.StatusBadRequest, ProblemValidation)
+			if len(got.Errors) != 1 || got.Errors[0].Field != "colour" {
+				t.Errorf("%s got errors %+v want one for colour", contentType, got.Errors)
+			}
+		}
+	})
+
+	t.Run("Malformed bodies are bad requests", func(t *testing.T) {
+		assertProblem(t, serve(http.MethodPost, "/api/todos/", "application/xml", "", `<todo><description>`), http.StatusBadRequest, ProblemBadRequest)
+	})
+
+	t.Run("YAML without values is refused rather than panicking", func(t *testing.T) {
+		for _, body := range []string{"-\n", "- \n", "key:\n", "- -\n", "description:\n"} {
+			response := serve(http.MethodPost, "/api/todos/", "application/yaml", "", body)
+			if response.Code != http.StatusBadRequest {
+				t.Errorf("%q: got status %d want 400", body, response.Code)
+			}
+		}
+	})
+
+	t.Run("Formats without a codec are refused", func(t *testing.T) {
+		assertProblem(t, serve(http.MethodGet, "/api/todos/"+id, "", "text/html", ""), http.StatusNotAcceptable, ProblemNotAcceptable)
+		assertProblem(t, serve(http.MethodPost, "/api/todos/", "application/toml", "", `description = "Walk the dog"`), http.StatusUnsupportedMediaType, ProblemUnsupportedMediaType)
+	})
+}
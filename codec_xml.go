@@ -0,0 +1,241 @@
+package todoapp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlCodec writes a todo as a <todo> element with an element for each
+// field, and lists of them in a <todos> element:
+//
+//	<todos>
+//	  <todo>
+//	    <id>1</id>
+//	    <description>Walk the dog</description>
+//	    <status>Started</status>
+//	    <tags><tag>pets</tag></tags>
+//	    <status_changed><change status="Started">2026-10-01T09:00:00Z</change></status_changed>
+//	    <members><member user="3f1c...">editor</member></members>
+//	  </todo>
+//	</todos>
+type xmlCodec struct{}
+
+// xmlPairs names the element and attribute each pair of the maps
+// status_changed and members is written as.
+var xmlPairs = map[string]struct{ element, attr string }{
+	"status_changed": {"change", "status"},
+	"members":        {"member", "user"},
+}
+
+func (xmlCodec) MediaType() string { return "application/xml" }
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	records, list, err := encodeRecords(v)
+	if err != nil {
+		return err
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if list {
+		if err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "todos"}}); err != nil {
+			return err
+		}
+	}
+	for _, rec := range records {
+		if err := encodeXMLRecord(enc, rec); err != nil {
+			return err
+		}
+	}
+	if list {
+		if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "todos"}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func encodeXMLRecord(enc *xml.Encoder, rec []recordField) error {
+	todo := xml.StartElement{Name: xml.Name{Local: "todo"}}
+	enc.EncodeToken(todo)
+	for _, f := range rec {
+		start := xml.StartElement{Name: xml.Name{Local: f.name}}
+		var err error
+		switch value := f.value.(type) {
+		case string:
+			err = enc.EncodeElement(value, start)
+		case []string:
+			err = enc.EncodeElement(struct {
+				Tags []string `xml:"tag"`
+			}{value}, start)
+		case []keyValue:
+			err = encodeXMLPairs(enc, start, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(todo.End())
+}
+
+func encodeXMLPairs(enc *xml.Encoder, start xml.StartElement, pairs []keyValue) error {
+	names := xmlPairs[start.Name.Local]
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, kv := range pairs {
+		pair := xml.StartElement{
+			Name: xml.Name{Local: names.element},
+			Attr: []xml.Attr{{Name: xml.Name{Local: names.attr}, Value: kv.key}},
+		}
+		if err := enc.EncodeElement(kv.value, pair); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func (c xmlCodec) Decode(r io.Reader, v any) error {
+	return c.decode(r, v, false)
+}
+
+func (c xmlCodec) DecodeStrict(r io.Reader, v any) error {
+	return c.decode(r, v, true)
+}
+
+func (xmlCodec) decode(r io.Reader, v any, strict bool) error {
+	dec := xml.NewDecoder(r)
+	root, err := nextStart(dec)
+	if err != nil {
+		return err
+	}
+
+	var records []map[string]any
+	switch root.Name.Local {
+	case "todo":
+		rec, err := decodeXMLRecord(dec)
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+	case "todos":
+		for {
+			start, err := nextStart(dec)
+			if errors.Is(err, errEndElement) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if start.Name.Local != "todo" {
+				return fmt.Errorf("expected a <todo> element, got <%s>", start.Name.Local)
+			}
+			rec, err := decodeXMLRecord(dec)
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+	default:
+		return fmt.Errorf("expected a <todo> or <todos> element, got <%s>", root.Name.Local)
+	}
+
+	if _, err := nextStart(dec); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after the XML element")
+	}
+	return decodeRecords(records, root.Name.Local == "todos", v, strict)
+}
+
+var errEndElement = errors.New("end of element")
+
+// nextStart skips to the next start element, returning errEndElement if the
+// current element ends first.
+func nextStart(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errEndElement
+		case xml.CharData:
+			if strings.TrimSpace(string(tok)) != "" {
+				return xml.StartElement{}, fmt.Errorf("unexpected text %q", strings.TrimSpace(string(tok)))
+			}
+		}
+	}
+}
+
+// decodeXMLRecord reads the fields of a <todo> element, after its start.
+func decodeXMLRecord(dec *xml.Decoder) (map[string]any, error) {
+	rec := map[string]any{}
+	for {
+		start, err := nextStart(dec)
+		if errors.Is(err, errEndElement) {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch start.Name.Local {
+		case "tags":
+			var tags struct {
+				Tags []string `xml:"tag"`
+			}
+			err = dec.DecodeElement(&tags, &start)
+			rec["tags"] = tags.Tags
+		case "status_changed", "members":
+			rec[start.Name.Local], err = decodeXMLPairs(dec, start.Name.Local)
+		default:
+			var s string
+			err = dec.DecodeElement(&s, &start)
+			rec[start.Name.Local] = s
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeXMLPairs reads the pairs of a status_changed or members element,
+// after its start.
+func decodeXMLPairs(dec *xml.Decoder, name string) ([]keyValue, error) {
+	names := xmlPairs[name]
+	var pairs []keyValue
+	for {
+		start, err := nextStart(dec)
+		if errors.Is(err, errEndElement) {
+			return pairs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start.Name.Local != names.element {
+			return nil, fmt.Errorf("expected <%s> elements in <%s>, got <%s>", names.element, name, start.Name.Local)
+		}
+
+		kv := keyValue{}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == names.attr {
+				kv.key = attr.Value
+			}
+		}
+		if err := dec.DecodeElement(&kv.value, &start); err != nil {
+			return nil, err
+		}
+		kv.value = strings.TrimSpace(kv.value)
+		pairs = append(pairs, kv)
+	}
+}
@@ -0,0 +1,395 @@
+package todoapp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// yamlCodec writes a todo as a mapping of its fields, and lists of them as
+// a sequence of mappings. It reads the block mappings and sequences, flow
+// sequences and plain or quoted scalars that todos need, rather than all of
+// YAML, so anchors, tags, multi-line strings and multiple documents aren't
+// supported.
+type yamlCodec struct{}
+
+func (yamlCodec) MediaType() string { return "application/yaml" }
+
+func (yamlCodec) Encode(w io.Writer, v any) error {
+	records, list, err := encodeRecords(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if list && len(records) == 0 {
+		bw.WriteString("[]\n")
+	}
+	for _, rec := range records {
+		indent := ""
+		for i, f := range rec {
+			switch {
+			case list && i == 0:
+				bw.WriteString("- ")
+				indent = "  "
+			default:
+				bw.WriteString(indent)
+			}
+			writeYAMLField(bw, indent, f)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeYAMLField(w *bufio.Writer, indent string, f recordField) {
+	switch value := f.value.(type) {
+	case []string:
+		fmt.Fprintf(w, "%s:\n", f.name)
+		for _, s := range value {
+			fmt.Fprintf(w, "%s  - %s\n", indent, strconv.Quote(s))
+		}
+	case []keyValue:
+		fmt.Fprintf(w, "%s:\n", f.name)
+		for _, kv := range value {
+			fmt.Fprintf(w, "%s  %s: %s\n", indent, strconv.Quote(kv.key), strconv.Quote(kv.value))
+		}
+	case string:
+		// Numbers and booleans are plain so they keep their type
+		if f.name == "version" || f.name == "overdue" {
+			fmt.Fprintf(w, "%s: %s\n", f.name, value)
+			return
+		}
+		fmt.Fprintf(w, "%s: %s\n", f.name, strconv.Quote(value))
+	}
+}
+
+func (c yamlCodec) Decode(r io.Reader, v any) error {
+	return c.decode(r, v, false)
+}
+
+func (c yamlCodec) DecodeStrict(r io.Reader, v any) error {
+	return c.decode(r, v, true)
+}
+
+func (yamlCodec) decode(r io.Reader, v any, strict bool) error {
+	p, err := newYAMLParser(r)
+	if err != nil {
+		return err
+	}
+	if len(p.lines) == 0 {
+		return errors.New("expected a todo, got an empty document")
+	}
+	root, err := p.node(p.lines[0].indent)
+	if err != nil {
+		return err
+	}
+	if p.pos < len(p.lines) {
+		return p.errorf("unexpected indentation")
+	}
+
+	var records []map[string]any
+	var list bool
+	switch root := root.(type) {
+	case map[string]any:
+		rec, err := yamlRecord(root)
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+	case []any:
+		list = true
+		for _, item := range root {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return errors.New("expected a sequence of todos")
+			}
+			rec, err := yamlRecord(m)
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+	default:
+		return errors.New("expected a todo or a sequence of todos")
+	}
+	return decodeRecords(records, list, v, strict)
+}
+
+// yamlRecord converts a parsed mapping's values to those of a record.
+func yamlRecord(m map[string]any) (map[string]any, error) {
+	rec := map[string]any{}
+	for name, value := range m {
+		switch value := value.(type) {
+		case nil:
+		case string:
+			rec[name] = value
+		case []any:
+			var list []string
+			for _, item := range value {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must be a list of strings", name)
+				}
+				list = append(list, s)
+			}
+			rec[name] = list
+		case map[string]any:
+			var pairs []keyValue
+			for _, key := range slices.Sorted(maps.Keys(value)) {
+				s, ok := value[key].(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must map strings to strings", name)
+				}
+				pairs = append(pairs, keyValue{key, s})
+			}
+			rec[name] = pairs
+		}
+	}
+	return rec, nil
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlParser parses the block structure of a document by indentation. node
+// returns a map[string]any, []any, string or nil.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func newYAMLParser(r io.Reader) (*yamlParser, error) {
+	p := &yamlParser{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(stripYAMLComment(scanner.Text()), " \r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || (len(p.lines) == 0 && text == "---") {
+			continue
+		}
+		if text == "..." {
+			break
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't be used for indentation", number)
+		}
+		p.lines = append(p.lines, yamlLine{number, len(line) - len(text), text})
+	}
+	return p, scanner.Err()
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	line := p.lines[min(p.pos, len(p.lines)-1)]
+	return fmt.Errorf("line %d: %s", line.number, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) node(indent int) (any, error) {
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return yamlScalar(line.text)
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var item any
+		var err error
+		switch _, _, isKey := splitYAMLKey(rest); {
+		case rest == "":
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err = p.node(p.lines[p.pos].indent)
+			}
+		case isKey:
+			// The item is a mapping starting on this line, so treat its
+			// first key as a line of its own
+			column := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{line.number, column, rest}
+			item, err = p.mapping(column)
+		default:
+			p.pos++
+			item, err = yamlScalar(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isYAMLSequenceItem(p.lines[p.pos].text) {
+		key, rest, ok := splitYAMLKey(p.lines[p.pos].text)
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("%s is repeated", key)
+		}
+		p.pos++
+
+		var value any
+		var err error
+		switch {
+		case rest != "":
+			value, err = yamlScalar(rest)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			value, err = p.node(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text):
+			// A sequence can be indented as far as its key
+			value, err = p.sequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" into its key and value, which is empty
+// if the value is on the following lines.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		key, n, err := yamlQuotedPrefix(text)
+		if err != nil {
+			return "", "", false
+		}
+		after := text[n:]
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(after[1:]), true
+	}
+	if i := strings.Index(text, ": "); i > 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+	}
+	if key, ok := strings.CutSuffix(text, ":"); ok && key != "" {
+		return strings.TrimSpace(key), "", true
+	}
+	return "", "", false
+}
+
+// yamlScalar parses a value written on one line, including flow sequences
+// like [a, "b"].
+func yamlScalar(text string) (any, error) {
+	switch {
+	case text == "" || text == "~" || text == "null":
+		return nil, nil
+	case text == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(text, "["):
+		inner, ok := strings.CutSuffix(text[1:], "]")
+		if !ok {
+			return nil, fmt.Errorf("unterminated sequence %s", text)
+		}
+		items := []any{}
+		for inner = strings.TrimSpace(inner); inner != ""; {
+			var item string
+			if inner[0] == '"' || inner[0] == '\'' {
+				s, n, err := yamlQuotedPrefix(inner)
+				if err != nil {
+					return nil, err
+				}
+				item, inner = s, strings.TrimSpace(inner[n:])
+			} else {
+				end := strings.IndexByte(inner, ',')
+				if end < 0 {
+					end = len(inner)
+				}
+				item, inner = strings.TrimSpace(inner[:end]), inner[end:]
+			}
+			items = append(items, item)
+			if inner != "" && inner[0] != ',' {
+				return nil, fmt.Errorf("expected a comma in %s", text)
+			}
+			inner = strings.TrimSpace(strings.TrimPrefix(inner, ","))
+		}
+		return items, nil
+	case text[0] == '"' || text[0] == '\'':
+		s, n, err := yamlQuotedPrefix(text)
+		if err != nil {
+			return nil, err
+		}
+		if n != len(text) {
+			return nil, fmt.Errorf("unexpected text after %s", text[:n])
+		}
+		return s, nil
+	}
+	return text, nil
+}
+
+// yamlQuotedPrefix unquotes the quoted string text starts with, returning
+// its length. Single quoted strings escape a quote by doubling it, double
+// quoted strings use backslash escapes.
+func yamlQuotedPrefix(text string) (string, int, error) {
+	if text[0] == '"' {
+		quoted, err := strconv.QuotedPrefix(text)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid quoted string %s", text)
+		}
+		s, err := strconv.Unquote(quoted)
+		return s, len(quoted), err
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(text); i++ {
+		if text[i] != '\'' {
+			b.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", text)
+}
+
+// stripYAMLComment removes a # comment, which starts a line or follows a
+// space, unless it's inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [,", line[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,128 @@
+package todoapp
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/types"
+)
+
+// Completion is a shell completion candidate with an optional description.
+type Completion struct {
+	Value       string
+	Description string
+}
+
+func Completions(values ...string) []Completion {
+	completions := make([]Completion, len(values))
+	for i, v := range values {
+		completions[i] = Completion{Value: v}
+	}
+	return completions
+}
+
+// FilterCompletions keeps the candidates starting with prefix.
+func FilterCompletions(completions []Completion, prefix string) []Completion {
+	var results []Completion
+	for _, c := range completions {
+		if strings.HasPrefix(c.Value, prefix) {
+			results = append(results, c)
+		}
+	}
+	return results
+}
+
+// CompletionCache keeps the todos fetched for tab completion on disk for a
+// short time, so pressing tab repeatedly doesn't hit the server each time.
+type CompletionCache struct {
+	path string
+	ttl  time.Duration
+}
+
+func NewCompletionCache(dir string, ttl time.Duration) *CompletionCache {
+	return &CompletionCache{
+		path: filepath.Join(dir, "completion.json"),
+		ttl:  ttl,
+	}
+}
+
+// Todos returns every todo, open and completed.
+func (c *CompletionCache) Todos(client TodoClient) (map[string]types.Todo, error) {
+	var cached offlineCache
+	if err := readJSONFile(c.path, &cached); err == nil && time.Since(cached.Fetched) < c.ttl && cached.Todos != nil {
+		return cached.Todos, nil
+	}
+
+	active, err := client.GetAllTodos()
+	if err != nil && !errors.Is(err, ErrOffline) {
+		return nil, err
+	}
+	completed, err := client.GetTodosByStatus(types.Completed)
+	if err != nil && !errors.Is(err, ErrOffline) {
+		return nil, err
+	}
+	// Either can be nil offline, and neither is ours to change
+	todos := map[string]types.Todo{}
+	maps.Copy(todos, active)
+	maps.Copy(todos, completed)
+
+	atomicfile.WriteJSON(c.path, offlineCache{Fetched: time.Now(), Todos: todos})
+	return todos, nil
+}
+
+// TodoCompletions offers the short handle of every todo, described by the
+// todo's description. Full IDs are offered once the handle is typed out.
+func TodoCompletions(todos map[string]types.Todo, prefix string) []Completion {
+	var results []Completion
+	for id, t := range todos {
+		value := ShortHandle(id)
+		if len(prefix) >= ShortHandleLength {
+			value = id
+		}
+		results = append(results, Completion{Value: value, Description: t.Description})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Value < results[j].Value })
+	return FilterCompletions(results, prefix)
+}
+
+func StatusCompletions() []Completion {
+	var results []Completion
+	for _, s := range types.Statuses {
+		results = append(results, Completion{Value: s.Slug(), Description: string(s)})
+	}
+	return results
+}
+
+func TagCompletions(todos map[string]types.Todo) []Completion {
+	var tags []string
+	for _, t := range todos {
+		tags = append(tags, t.Tags...)
+	}
+	return Completions(uniqueSorted(tags)...)
+}
+
+func ListCompletions(todos map[string]types.Todo) []Completion {
+	var lists []string
+	for _, t := range todos {
+		if t.List != "" {
+			lists = append(lists, t.List)
+		}
+	}
+	return Completions(uniqueSorted(lists)...)
+}
+
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	var results []string
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			results = append(results, v)
+		}
+	}
+	return results
+}
@@ -0,0 +1,81 @@
+package todoapp
+
+import (
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestShortHandles(t *testing.T) {
+	todos := map[string]types.Todo{
+		"3e6ee309-126b-4112-b7b5-9d770c8a982b": types.NewTodo("First todo", nil),
+		"3e6ff000-126b-4112-b7b5-9d770c8a982b": types.NewTodo("Second todo", nil),
+	}
+
+	t.Run("Unambiguous prefixes resolve to the full ID", func(t *testing.T) {
+		got, err := ResolveHandle(todos, "3e6ee309")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "3e6ee309-126b-4112-b7b5-9d770c8a982b" {
+			t.Errorf("got %q want the first todo's ID", got)
+		}
+	})
+
+	t.Run("Ambiguous prefixes are an error", func(t *testing.T) {
+		if _, err := ResolveHandle(todos, "3e6"); err == nil {
+			t.Errorf("expected an error for an ambiguous handle")
+		}
+	})
+
+	t.Run("Completions offer handles matching the prefix", func(t *testing.T) {
+		got := TodoCompletions(todos, "3e6f")
+		if len(got) != 1 || got[0].Value != "3e6ff000" || got[0].Description != "Second todo" {
+			t.Errorf("got %v want the second todo's handle", got)
+		}
+	})
+}
+
+func TestCompletionCache(t *testing.T) {
+	remote := newStubRemote()
+	remote.todos["1"] = types.NewTodo("Cached todo", nil)
+	cache := NewCompletionCache(t.TempDir(), time.Minute)
+
+	if todos, _ := cache.Todos(remote); len(todos) != 1 {
+		t.Fatalf("got %d todos want 1", len(todos))
+	}
+
+	// Served from the cache so the server isn't asked again
+	remote.offline = true
+	todos, err := cache.Todos(remote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(todos) != 1 {
+		t.Errorf("got %d todos want 1", len(todos))
+	}
+}
+
+// offlineActiveTodos is a client that can't list active todos, as the
+// offline client can't before it has a cache.
+type offlineActiveTodos struct {
+	*stubRemote
+}
+
+func (offlineActiveTodos) GetAllTodos() (map[string]types.Todo, error) {
+	return nil, ErrOffline
+}
+
+func TestCompletionCacheWithoutActiveTodos(t *testing.T) {
+	remote := newStubRemote()
+	done := types.NewTodo("Completed todo", nil)
+	done.SetStatus(types.Completed)
+	remote.todos["1"] = done
+	cache := NewCompletionCache(t.TempDir(), time.Minute)
+
+	todos, err := cache.Todos(offlineActiveTodos{remote})
+	if err != nil || len(todos) != 1 {
+		t.Errorf("got %v and error %v want the completed todo", todos, err)
+	}
+}
@@ -0,0 +1,196 @@
+package todoapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// TestClientServerContract runs every TodoAPIClient operation against a real
+// TodoServer, in every version and format, so a change to either side that
+// breaks the other fails here.
+func TestClientServerContract(t *testing.T) {
+	for _, version := range APIVersions {
+		for _, codec := range Codecs() {
+			t.Run(string(version)+" "+codec.MediaType(), func(t *testing.T) {
+				testClientServerContract(t, version, codec)
+			})
+		}
+	}
+}
+
+func testClientServerContract(t *testing.T, version APIVersion, codec Codec) {
+	viewStore, _ := views.NewStore("")
+	viewStore.Put("", views.View{Name: "work", Filter: "list = work"})
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithViews(viewStore))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewTodoAPIClient(ts.URL+APIPrefix, WithAPIVersion(version), WithCodec(codec))
+
+	past := time.Now().AddDate(0, 0, -2)
+	overdue := types.NewTodo("File the tax return", &past)
+	overdue.List = "work"
+
+	var id string
+	t.Run("AddTodo returns the id of the created todo", func(t *testing.T) {
+		var err error
+		if id, err = client.AddTodo(overdue); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if id == "" {
+			t.Fatal("got an empty id")
+		}
+	})
+
+	t.Run("GetTodo fetches it", func(t *testing.T) {
+		todo, err := client.GetTodo(id)
+		if err != nil || todo.Description != overdue.Description {
+			t.Errorf("got %v and error %v want %q", todo, err, overdue.Description)
+		}
+	})
+
+	t.Run("UpdateTodoStatus changes its status", func(t *testing.T) {
+		if err := client.UpdateTodoStatus(id, types.Started); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		todo, _ := client.GetTodo(id)
+		if todo.Status != types.Started {
+			t.Errorf("got status %q want %q", todo.Status, types.Started)
+		}
+	})
+
+	t.Run("The list operations find it", func(t *testing.T) {
+		byStatus, err := client.GetTodosByStatus(types.Started)
+		if _, ok := byStatus[id]; err != nil || !ok {
+			t.Errorf("GetTodosByStatus got %v and error %v", byStatus, err)
+		}
+		overdue, err := client.GetOverdueTodos()
+		if _, ok := overdue[id]; err != nil || !ok {
+			t.Errorf("GetOverdueTodos got %v and error %v", overdue, err)
+		}
+		all, err := client.GetAllTodos()
+		if _, ok := all[id]; err != nil || !ok {
+			t.Errorf("GetAllTodos got %v and error %v", all, err)
+		}
+		page, err := client.QueryTodos(types.TodoQuery{Status: types.Started})
+		if err != nil || len(page.Todos) != 1 || page.Todos[0].Id != id {
+			t.Errorf("QueryTodos got %v and error %v", page, err)
+		}
+	})
+
+	t.Run("GetStats counts it", func(t *testing.T) {
+		stats, err := client.GetStats(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1))
+		if err != nil || stats.ByStatus[types.Started] != 1 {
+			t.Errorf("got %v and error %v want one started todo", stats, err)
+		}
+	})
+
+	t.Run("Search finds it", func(t *testing.T) {
+		results, err := client.Search("tax", 0)
+		if err != nil || len(results) != 1 || results[0].Id != id {
+			t.Errorf("got %v and error %v want the tax todo", results, err)
+		}
+	})
+
+	t.Run("Views can be listed and run", func(t *testing.T) {
+		list, err := client.GetViews()
+		if err != nil || len(list) != 1 || list[0].Name != "work" {
+			t.Fatalf("got %v and error %v want the work view", list, err)
+		}
+		todos, err := client.GetViewTodos("work")
+		if err != nil || len(todos) != 1 || todos[0].Id != id {
+			t.Errorf("got %v and error %v want the tax todo", todos, err)
+		}
+	})
+
+	t.Run("Bulk applies a batch", func(t *testing.T) {
+		results, err := client.Bulk([]types.BulkOp{
+			{Op: types.BulkCreate, Todo: &types.Todo{Description: "Book the dentist"}},
+			{Op: types.BulkUpdate, Id: id, Status: types.Completed},
+		})
+		if err != nil || len(results) != 2 || !results[0].Ok || !results[1].Ok {
+			t.Errorf("got %v and error %v want both ops applied", results, err)
+		}
+	})
+
+	t.Run("Bulk reports a failed batch", func(t *testing.T) {
+		results, err := client.Bulk([]types.BulkOp{{Op: types.BulkDelete, Id: "missing"}})
+		if !errors.Is(err, types.ErrBulkFailed) || len(results) != 1 || results[0].Error == "" {
+			t.Errorf("got %v and error %v want ErrBulkFailed", results, err)
+		}
+	})
+
+	t.Run("Subscribe receives changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		events, err := client.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		client.UpdateTodoStatus(id, types.NotStarted)
+
+		select {
+		case event := <-events:
+			if event.TodoId != id || event.Type != types.EventUpdated {
+				t.Errorf("got %+v want an update to %s", event, id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("timed out waiting for the event")
+		}
+	})
+
+	t.Run("GetTodo returns an error for a missing todo", func(t *testing.T) {
+		if _, err := client.GetTodo("missing"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+// TestRoutesAreRegistered checks the server has a handler for every route in
+// the table the client builds its requests from, in every version, and for
+// v1 at APIPrefix too.
+func TestRoutesAreRegistered(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+
+	prefixes := map[string][]Route{APIPrefix: Routes}
+	for _, version := range APIVersions {
+		prefixes[version.Prefix()] = version.Routes()
+	}
+	for prefix, routes := range prefixes {
+		for _, route := range routes {
+			t.Run(prefix+" "+route.Name, func(t *testing.T) {
+				url := route.URL(prefix, nil, "some-id", "some-id")
+				if route == RouteStreamEvents {
+					// Streams don't return until the request is cancelled
+					ctx, cancel := context.WithCancel(t.Context())
+					cancel()
+					request := httptest.NewRequestWithContext(ctx, route.Method, url, nil)
+					response := httptest.NewRecorder()
+					server.ServeHTTP(response, request)
+					assertRegistered(t, route, response.Code, response.Body.String())
+					return
+				}
+
+				response := httptest.NewRecorder()
+				server.ServeHTTP(response, httptest.NewRequest(route.Method, url, strings.NewReader("{}")))
+				assertRegistered(t, route, response.Code, response.Body.String())
+			})
+		}
+	}
+}
+
+// assertRegistered fails if the mux, rather than a handler, answered.
+func assertRegistered(t testing.TB, route Route, status int, body string) {
+	t.Helper()
+	if status == http.StatusMethodNotAllowed || (status == http.StatusNotFound && strings.HasPrefix(body, "404 page not found")) {
+		t.Errorf("%s %s isn't registered, got status %d", route.Method, route.Path, status)
+	}
+}
@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// Expr is a parsed filter. It implements types.TodoFilter, so it can be
+// given to a TodoQuery and evaluated by the store. String gives back a
+// filter that parses to the same expression.
+type Expr interface {
+	Match(t types.Todo) bool
+	String() string
+}
+
+type And struct{ Left, Right Expr }
+
+type Or struct{ Left, Right Expr }
+
+type Not struct{ Expr Expr }
+
+// Comparison is a single field, operator and value, such as tag:ops.
+type Comparison struct {
+	Field string
+	Op    string
+	Value string
+
+	status  types.Status
+	overdue bool
+	none    bool
+	// Times match the half-open range [from, to), a whole day for dates
+	from, to time.Time
+}
+
+func (e And) Match(t types.Todo) bool { return e.Left.Match(t) && e.Right.Match(t) }
+func (e Or) Match(t types.Todo) bool  { return e.Left.Match(t) || e.Right.Match(t) }
+func (e Not) Match(t types.Todo) bool { return !e.Expr.Match(t) }
+
+func (c Comparison) Match(t types.Todo) bool {
+	negate := c.Op == "!="
+	switch c.Field {
+	case "status":
+		return (t.Status == c.status) != negate
+	case "overdue":
+		return (t.IsOverdue() == c.overdue) != negate
+	case "tag":
+		has := slices.ContainsFunc(t.Tags, func(tag string) bool { return strings.EqualFold(tag, c.Value) })
+		return has != negate
+	case "list":
+		return strings.EqualFold(t.List, c.Value) != negate
+	case "text":
+		if c.Op == "~" || c.Op == ":" {
+			return strings.Contains(strings.ToLower(t.Description), strings.ToLower(c.Value))
+		}
+		return strings.EqualFold(t.Description, c.Value) != negate
+	case "due":
+		if c.none {
+			return (t.Due == nil) != negate
+		}
+		if t.Due == nil {
+			return negate
+		}
+		return c.matchTime(*t.Due)
+	case "created":
+		return c.matchTime(t.Created)
+	case "updated":
+		return c.matchTime(t.Updated)
+	}
+	return false
+}
+
+func (c Comparison) matchTime(x time.Time) bool {
+	switch c.Op {
+	case "<":
+		return x.Before(c.from)
+	case "<=":
+		return x.Before(c.to)
+	case ">":
+		return !x.Before(c.to)
+	case ">=":
+		return !x.Before(c.from)
+	case "!=":
+		return x.Before(c.from) || !x.Before(c.to)
+	default:
+		return !x.Before(c.from) && x.Before(c.to)
+	}
+}
+
+func (e And) String() string { return group(e.Left, false) + " AND " + group(e.Right, false) }
+func (e Or) String() string  { return e.Left.String() + " OR " + e.Right.String() }
+func (e Not) String() string { return "NOT " + group(e.Expr, true) }
+
+func (c Comparison) String() string {
+	return c.Field + c.Op + quote(c.Value)
+}
+
+// group adds the parentheses needed to keep e's meaning inside an AND, or
+// inside a NOT when not is true.
+func group(e Expr, not bool) string {
+	switch e.(type) {
+	case Or:
+		return "(" + e.String() + ")"
+	case And:
+		if not {
+			return "(" + e.String() + ")"
+		}
+	}
+	return e.String()
+}
+
+func quote(s string) string {
+	switch strings.ToUpper(s) {
+	case "AND", "OR", "NOT":
+		return strconv.Quote(s)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\r"+specialChars) {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	return s
+}
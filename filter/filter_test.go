@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestParse(t *testing.T) {
+	due := time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC)
+	ops := types.NewTodo("Rotate the ops keys", &due)
+	ops.Tags = []string{"ops"}
+	ops.SetStatus(types.Started)
+	invoice := types.NewTodo("Send the invoice", nil)
+	invoice.List = "finance"
+	done := types.NewTodo("Done and dusted", &due)
+	done.SetStatus(types.Completed)
+
+	cases := []struct {
+		filter string
+		want   []bool // matches ops, invoice, done
+	}{
+		{`status:Started AND due<2026-11-01 AND tag:ops OR text~"invoice"`, []bool{true, true, false}},
+		{`status:started due<=2026-10-30`, []bool{true, false, false}},
+		{`due>2026-10-30`, []bool{false, false, false}},
+		{`due:2026-10-30`, []bool{true, false, true}},
+		{`due:none`, []bool{false, true, false}},
+		{`NOT (tag:ops OR list:finance)`, []bool{false, false, true}},
+		{`status!=completed AND NOT tag:ops`, []bool{false, true, false}},
+		{`description="send the INVOICE"`, []bool{false, true, false}},
+		{`list:finance or status:"Not Started"`, []bool{false, true, false}},
+	}
+	for _, c := range cases {
+		expr, err := Parse(c.filter)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", c.filter, err)
+			continue
+		}
+		for i, todo := range []types.Todo{ops, invoice, done} {
+			if got := expr.Match(todo); got != c.want[i] {
+				t.Errorf("%q matching %q: got %v want %v", c.filter, todo.Description, got, c.want[i])
+			}
+		}
+
+		// The string form must parse back to an equivalent filter
+		again, err := Parse(expr.String())
+		if err != nil || again.String() != expr.String() {
+			t.Errorf("%q printed as %q which parsed to %v, %v", c.filter, expr.String(), again, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		filter string
+		pos    int
+	}{
+		{``, 0},
+		{`status`, 6},
+		{`status:`, 7},
+		{`status:later`, 7},
+		{`colour:red`, 0},
+		{`tag:ops AND`, 11},
+		{`(tag:ops OR list:home`, 21},
+		{`due~2026-11-01`, 3},
+		{`due<tomorrow`, 4},
+		{`text:"unterminated`, 5},
+		{`tag!ops`, 3},
+		{`tag:ops)`, 7},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.filter)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: got %v want a syntax error", c.filter, err)
+			continue
+		}
+		if syntaxErr.Pos != c.pos {
+			t.Errorf("%q: got error %q at %d want position %d", c.filter, syntaxErr.Msg, syntaxErr.Pos, c.pos)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of filter"
+	case tokenWord, tokenString:
+		return "value"
+	case tokenOp:
+		return "operator"
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenLParen:
+		return `"("`
+	default:
+		return `")"`
+	}
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// SyntaxError reports a problem with a filter and the byte offset it was
+// found at.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
+
+// Caret shows the filter with a marker under where the error is.
+func (e *SyntaxError) Caret(filter string) string {
+	return filter + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+const specialChars = `():=<>~!"`
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '"':
+			s, n, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, s, i})
+			i += n
+		case strings.IndexByte(specialChars, c) >= 0:
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && (c == '!' || c == '<' || c == '>') {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &SyntaxError{i, `expected "!=", use NOT to negate`}
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r"+specialChars, rune(input[i])) {
+				i++
+			}
+			word := input[start:i]
+			kind := tokenWord
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind, word, start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+// lexString reads a double quoted string starting at input[start], where
+// \" and \\ are escapes, returning it and how many bytes it took up.
+func lexString(input string, start int) (string, int, error) {
+	var s strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if i+1 < len(input) {
+				i++
+				s.WriteByte(input[i])
+			}
+		case '"':
+			return s.String(), i + 1 - start, nil
+		default:
+			s.WriteByte(input[i])
+		}
+	}
+	return "", 0, &SyntaxError{start, "unterminated string"}
+}
@@ -0,0 +1,211 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// Parse parses a filter such as
+//
+//	status:Started AND due<2026-11-01 AND tag:ops OR text~"invoice"
+//
+// Comparisons are a field, an operator and a value, which is quoted if it
+// contains spaces or any of ():=<>~!". They combine with AND, OR, NOT and
+// parentheses, AND binding tighter than OR. Comparisons next to each other
+// without an operator between them are ANDed.
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{0, "empty filter"}
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %s", t.kind)}
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenWord, tokenNot, tokenLParen:
+			// Implicit AND
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch t := p.peek(); t.kind {
+	case tokenNot:
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{expr}, nil
+	case tokenLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{closing.pos, fmt.Sprintf(`expected ")" to close the "(" at position %d`, t.pos+1)}
+		}
+		return expr, nil
+	case tokenWord:
+		return p.parseComparison()
+	default:
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("expected a field name, got %s", t.kind)}
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldToken := p.next()
+	field, ok := fields[strings.ToLower(fieldToken.text)]
+	if !ok {
+		return nil, &SyntaxError{fieldToken.pos, fmt.Sprintf("unknown field %q, expected one of %s", fieldToken.text, strings.Join(FieldNames(), ", "))}
+	}
+
+	opToken := p.next()
+	if opToken.kind != tokenOp {
+		return nil, &SyntaxError{opToken.pos, fmt.Sprintf("expected an operator after %q", fieldToken.text)}
+	}
+	if !slices.Contains(field.ops, opToken.text) {
+		return nil, &SyntaxError{opToken.pos, fmt.Sprintf("%q can't be used with %s, expected one of %s", opToken.text, field.name, strings.Join(field.ops, " "))}
+	}
+
+	valueToken := p.next()
+	if valueToken.kind != tokenWord && valueToken.kind != tokenString {
+		return nil, &SyntaxError{valueToken.pos, fmt.Sprintf("expected a value after %q", fieldToken.text+opToken.text)}
+	}
+
+	c := Comparison{Field: field.name, Op: opToken.text, Value: valueToken.text}
+	if err := c.parseValue(); err != nil {
+		return nil, &SyntaxError{valueToken.pos, err.Error()}
+	}
+	return c, nil
+}
+
+type field struct {
+	name string
+	ops  []string
+}
+
+var (
+	equalityOps = []string{":", "=", "!="}
+	textOps     = []string{":", "=", "!=", "~"}
+	timeOps     = []string{":", "=", "!=", "<", "<=", ">", ">="}
+)
+
+var fields = map[string]field{
+	"status":      {"status", equalityOps},
+	"tag":         {"tag", equalityOps},
+	"list":        {"list", equalityOps},
+	"text":        {"text", textOps},
+	"description": {"text", textOps},
+	"due":         {"due", timeOps},
+	"created":     {"created", timeOps},
+	"updated":     {"updated", timeOps},
+	"overdue":     {"overdue", equalityOps},
+}
+
+// FieldNames lists the fields filters can use.
+func FieldNames() []string {
+	return []string{"status", "tag", "list", "text", "due", "created", "updated", "overdue"}
+}
+
+// parseValue checks the value makes sense for the field and keeps the
+// parsed form for matching.
+func (c *Comparison) parseValue() error {
+	switch c.Field {
+	case "status":
+		status, err := types.ParseStatus(c.Value)
+		if err != nil {
+			return err
+		}
+		c.status = status
+	case "overdue":
+		switch strings.ToLower(c.Value) {
+		case "true", "yes":
+			c.overdue = true
+		case "false", "no":
+		default:
+			return fmt.Errorf("expected true or false, got %q", c.Value)
+		}
+	case "due", "created", "updated":
+		if c.Field == "due" && strings.EqualFold(c.Value, "none") {
+			if c.Op != ":" && c.Op != "=" && c.Op != "!=" {
+				return fmt.Errorf("%q can't be compared with none", c.Op)
+			}
+			c.none = true
+			return nil
+		}
+		if d, err := time.Parse(time.DateOnly, c.Value); err == nil {
+			c.from, c.to = d, d.AddDate(0, 0, 1)
+		} else if t, err := time.Parse(time.RFC3339, c.Value); err == nil {
+			c.from, c.to = t, t.Add(time.Nanosecond)
+		} else {
+			return fmt.Errorf("expected a yyyy-mm-dd date or RFC3339 time, got %q", c.Value)
+		}
+	}
+	return nil
+}
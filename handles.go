@@ -0,0 +1,43 @@
+package todoapp
+
+import (
+	"fmt"
+	"strings"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// ShortHandleLength is how many characters of a todo ID are shown as its
+// short handle. Any unambiguous prefix is accepted in place of an ID.
+const ShortHandleLength = 8
+
+func ShortHandle(id string) string {
+	if len(id) <= ShortHandleLength {
+		return id
+	}
+	return id[:ShortHandleLength]
+}
+
+// ResolveHandle expands a short handle to the full ID of one of todos.
+// Handles that match no todo are returned unchanged so the caller gets the
+// usual not found error when using them.
+func ResolveHandle(todos map[string]types.Todo, handle string) (string, error) {
+	if _, ok := todos[handle]; ok || handle == "" {
+		return handle, nil
+	}
+
+	var matches []string
+	for id := range todos {
+		if strings.HasPrefix(id, handle) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return handle, nil
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches %d todos, use more characters of the ID", handle, len(matches))
+}
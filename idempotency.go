@@ -0,0 +1,196 @@
+package todoapp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader is set on responses replayed for a retry.
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	DefaultIdempotencyRetention = 24 * time.Hour
+	MaxIdempotencyKeyLength     = 255
+)
+
+// idempotentResponse is what a request with an Idempotency-Key got back.
+type idempotentResponse struct {
+	fingerprint [32]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyCache keeps responses by key for the retention window so a
+// retried POST gets the original response rather than repeating the change.
+type idempotencyCache struct {
+	lock      sync.Mutex
+	retention time.Duration
+	responses map[string]*idempotentResponse
+	lastSweep time.Time
+}
+
+func newIdempotencyCache(retention time.Duration) *idempotencyCache {
+	return &idempotencyCache{retention: retention, responses: map[string]*idempotentResponse{}}
+}
+
+// begin claims key for a request with the given fingerprint, unless it's
+// already claimed, in which case it returns the claim. The claim has no
+// response until the first request with the key finishes.
+func (c *idempotencyCache) begin(key string, fingerprint [32]byte, now time.Time) *idempotentResponse {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, r := range c.responses {
+			if r.done && now.After(r.expires) {
+				delete(c.responses, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if r, exists := c.responses[key]; exists && !(r.done && now.After(r.expires)) {
+		return r
+	}
+	c.responses[key] = &idempotentResponse{fingerprint: fingerprint}
+	return nil
+}
+
+// finish saves the response to key, or forgets the key so the request can
+// be tried again if it shouldn't be saved.
+func (c *idempotencyCache) finish(key string, rec *recordingWriter, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Server errors and cancellations didn't necessarily happen, retrying
+	// them should try again
+	if rec.status >= 500 || rec.status == http.StatusRequestTimeout {
+		delete(c.responses, key)
+		return
+	}
+	r := c.responses[key]
+	r.done = true
+	r.status = rec.status
+	r.header = rec.header
+	r.body = rec.body.Bytes()
+	r.expires = now.Add(c.retention)
+}
+
+// abandon forgets key if its request didn't finish, so it can be retried.
+func (c *idempotencyCache) abandon(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if r, exists := c.responses[key]; exists && !r.done {
+		delete(c.responses, key)
+	}
+}
+
+// recordingWriter copies a response as it's written.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.header == nil {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.header == nil {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// idempotent lets clients safely retry next by sending an Idempotency-Key.
+// The first response for a key is saved and replayed for retries with the
+// same method, path and body. Reusing a key for a different request is a
+// 422 problem, and retrying while the first attempt is still running is a 409.
+func (s *TodoServer) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > MaxIdempotencyKeyLength {
+			invalid(w, r, IdempotencyKeyHeader, "must be at most %d characters", MaxIdempotencyKeyLength)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBulkBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem(w, r, http.StatusRequestEntityTooLarge, ProblemTooLarge, fmt.Sprintf("the body must be at most %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			badRequest(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		// Keys are only unique to the user that chose them. The endpoint
+		// isn't part of the key, so reusing one on another endpoint is
+		// caught by the fingerprint
+		if user, ok := userFrom(r); ok {
+			key = user.Id + " " + key
+		}
+
+		saved := s.idempotency.begin(key, fingerprint, time.Now())
+		switch {
+		case saved == nil:
+		case saved.fingerprint != fingerprint:
+			problem(w, r, http.StatusUnprocessableEntity, ProblemKeyReused, "")
+			return
+		case !saved.done:
+			writeError(w, r, types.Conflictf("a request with this Idempotency-Key is still in progress"))
+			return
+		default:
+			slog.InfoContext(r.Context(), "Replaying response for idempotency key", slog.Int("status", saved.status))
+			maps.Copy(w.Header(), saved.header)
+			w.Header().Set(IdempotentReplayHeader, "true")
+			w.WriteHeader(saved.status)
+			w.Write(saved.body)
+			return
+		}
+
+		// If next panics the key would stay in progress until it expired
+		finished := false
+		defer func() {
+			if !finished {
+				s.idempotency.abandon(key)
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: w}
+		next(rec, r)
+		if rec.header == nil {
+			rec.WriteHeader(http.StatusOK)
+		}
+		s.idempotency.finish(key, rec, time.Now())
+		finished = true
+	}
+}
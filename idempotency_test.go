@@ -0,0 +1,125 @@
+package todoapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	post := func(path, key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set(IdempotencyKeyHeader, key)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("Retries get the original response without repeating the change", func(t *testing.T) {
+		first := post("/api/todos/", "add-1", `{"description": "Only once"}`)
+		again := post("/api/todos/", "add-1", `{"description": "Only once"}`)
+		assertStatus(t, again.Code, first.Code)
+		if again.Header().Get(IdempotentReplayHeader) != "true" || first.Header().Get(IdempotentReplayHeader) != "" {
+			t.Errorf("got replay headers %q and %q want only the retry marked", first.Header().Get(IdempotentReplayHeader), again.Header().Get(IdempotentReplayHeader))
+		}
+		if n := len(store.GetAllTodos(t.Context())); n != 1 {
+			t.Errorf("got %d todos want 1", n)
+		}
+	})
+
+	t.Run("Bulk retries return the same results", func(t *testing.T) {
+		body := `{"ops": [{"op": "create", "todo": {"description": "Bulk once"}}]}`
+		first := post("/api/todos/bulk", "bulk-1", body)
+		again := post("/api/todos/bulk", "bulk-1", body)
+		assertStatus(t, first.Code, http.StatusOK)
+		if first.Body.String() != again.Body.String() {
+			t.Errorf("got %s then %s want the same created id", first.Body, again.Body)
+		}
+		if n := len(store.GetAllTodos(t.Context())); n != 2 {
+			t.Errorf("got %d todos want 2", n)
+		}
+	})
+
+	t.Run("A key can't be reused for a different request", func(t *testing.T) {
+		response := post("/api/todos/", "add-1", `{"description": "Something else"}`)
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+
+		response = post("/api/todos/bulk", "add-1", `{"ops": [{"op": "create", "todo": {"description": "Only once"}}]}`)
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("A key can be retried after its handler panics", func(t *testing.T) {
+		panics := true
+		handler := server.idempotent(func(w http.ResponseWriter, r *http.Request) {
+			if panics {
+				panic("handler failed")
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		serve := func() (response *httptest.ResponseRecorder) {
+			defer func() { recover() }()
+			request := httptest.NewRequest(http.MethodPost, "/api/todos/", strings.NewReader("{}"))
+			request.Header.Set(IdempotencyKeyHeader, "panics-1")
+			response = httptest.NewRecorder()
+			handler(response, request)
+			return response
+		}
+
+		serve()
+		panics = false
+		assertStatus(t, serve().Code, http.StatusCreated)
+	})
+
+	t.Run("Keys are forgotten after the retention window", func(t *testing.T) {
+		cache := newIdempotencyCache(time.Hour)
+		now := time.Now()
+		cache.begin("key", [32]byte{1}, now)
+		if saved := cache.begin("key", [32]byte{1}, now); saved == nil || saved.done {
+			t.Fatalf("got %+v want the key in progress", saved)
+		}
+		cache.finish("key", &recordingWriter{status: http.StatusAccepted}, now)
+		if saved := cache.begin("key", [32]byte{1}, now.Add(59*time.Minute)); saved == nil || saved.status != http.StatusAccepted {
+			t.Errorf("got %+v want the saved response", saved)
+		}
+		if saved := cache.begin("key", [32]byte{1}, now.Add(61*time.Minute)); saved != nil {
+			t.Errorf("got %+v want the key to have expired", saved)
+		}
+	})
+}
+
+func TestClientRetriesWithTheSameKey(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	// The first attempt is applied but its response is lost
+	dropped := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dropped {
+			dropped = true
+			server.ServeHTTP(httptest.NewRecorder(), r)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	client := NewTodoAPIClient(ts.URL + "/api")
+	client.RetryDelay = time.Millisecond
+	results, err := client.Bulk([]types.BulkOp{{Op: types.BulkCreate, Todo: &types.Todo{Description: "Sent twice"}}})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("got %v and error %v want one result", results, err)
+	}
+	if todos := store.GetAllTodos(t.Context()); len(todos) != 1 {
+		t.Errorf("got %d todos want 1", len(todos))
+	}
+}
@@ -0,0 +1,40 @@
+package atomicfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	t.Run("Replaces the file", func(t *testing.T) {
+		for _, want := range []string{"first", "second"} {
+			if err := WriteJSON(path, map[string]string{"value": want}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			var got map[string]string
+			if err := json.Unmarshal(data, &got); err != nil || got["value"] != want {
+				t.Errorf("got %s and error %v want %q", data, err, want)
+			}
+		}
+	})
+
+	t.Run("Leaves no temporary files behind", func(t *testing.T) {
+		WriteJSON(path, func() {})
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("got %d files want only state.json", len(entries))
+		}
+	})
+
+	t.Run("Fails when the directory doesn't exist", func(t *testing.T) {
+		if err := WriteJSON(filepath.Join(dir, "missing", "state.json"), 1); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
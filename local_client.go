@@ -0,0 +1,101 @@
+package todoapp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"grantjames.github.io/todo-app/search"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// LocalTodoClient talks to a types.TodoStore directly, so the CLI can be used
+// without running the server.
+type LocalTodoClient struct {
+	store types.TodoStore
+	views *views.Store
+}
+
+var errNoLocalViews = errors.New("saved views need a views file in local mode")
+
+// NewLocalTodoClient returns a client for store that runs the views saved
+// in savedViews, such as the views.json next to the store's file. Without
+// them, the client has no views.
+func NewLocalTodoClient(store types.TodoStore, savedViews *views.Store) *LocalTodoClient {
+	return &LocalTodoClient{store: store, views: savedViews}
+}
+
+func (c *LocalTodoClient) GetTodo(id string) (*types.Todo, error) {
+	todo, err := c.store.GetTodo(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// AddTodo makes the same checks as the server, and sets the fields it
+// would.
+func (c *LocalTodoClient) AddTodo(todo types.Todo) (string, error) {
+	if err := todo.Validate(); err != nil {
+		return "", err
+	}
+	todo.Init(time.Now())
+	return c.store.AddTodo(context.Background(), todo)
+}
+
+func (c *LocalTodoClient) UpdateTodoStatus(id string, status types.Status) error {
+	return c.store.UpdateTodoStatus(context.Background(), id, status)
+}
+
+func (c *LocalTodoClient) Bulk(ops []types.BulkOp) ([]types.BulkResult, error) {
+	return c.store.ApplyBulk(context.Background(), ops)
+}
+
+func (c *LocalTodoClient) GetTodosByStatus(status types.Status) (map[string]types.Todo, error) {
+	return c.store.GetTodosByStatus(context.Background(), status), nil
+}
+
+func (c *LocalTodoClient) GetOverdueTodos() (map[string]types.Todo, error) {
+	return c.store.GetOverdueTodos(context.Background()), nil
+}
+
+func (c *LocalTodoClient) GetAllTodos() (map[string]types.Todo, error) {
+	return c.store.GetAllTodos(context.Background()), nil
+}
+
+func (c *LocalTodoClient) QueryTodos(q types.TodoQuery) (*types.TodoPage, error) {
+	page, err := c.store.QueryTodos(context.Background(), q)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *LocalTodoClient) GetStats(since, until time.Time) (*types.TodoStats, error) {
+	todos := types.AllTodos(context.Background(), c.store)
+	stats := types.ComputeStats(todos, since, until, time.Now(), types.StatsOldestOpen)
+	return &stats, nil
+}
+
+func (c *LocalTodoClient) Search(query string, limit int) ([]types.SearchResult, error) {
+	return search.SearchTodos(types.AllTodos(context.Background(), c.store), query, limit), nil
+}
+
+func (c *LocalTodoClient) GetViews() ([]views.View, error) {
+	if c.views == nil {
+		return []views.View{}, nil
+	}
+	return c.views.Views(""), nil
+}
+
+func (c *LocalTodoClient) GetViewTodos(name string) ([]types.TodoItem, error) {
+	if c.views == nil {
+		return nil, errNoLocalViews
+	}
+	view, err := c.views.View("", name)
+	if err != nil {
+		return nil, err
+	}
+	return view.Run(types.AllTodos(context.Background(), c.store))
+}
@@ -0,0 +1,55 @@
+package todoapp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+func TestLocalTodoClient(t *testing.T) {
+	client := NewLocalTodoClient(stores.NewInMemoryTodoStore(), nil)
+
+	t.Run("Added todos get the fields the server would set", func(t *testing.T) {
+		id, err := client.AddTodo(types.Todo{Description: "Walk the dog"})
+		if err != nil {
+			t.Fatalf("unexpected error adding %v", err)
+		}
+		todo, _ := client.GetTodo(id)
+		if todo.Status != types.NotStarted || todo.Created.IsZero() || todo.Version != 1 || todo.StatusChanged[types.NotStarted].IsZero() {
+			t.Errorf("got %+v want it initialised", todo)
+		}
+	})
+
+	t.Run("Invalid todos aren't added", func(t *testing.T) {
+		if _, err := client.AddTodo(types.Todo{}); err == nil {
+			t.Errorf("got no error adding a todo without a description")
+		}
+	})
+
+	t.Run("Without a views file there are no views", func(t *testing.T) {
+		if _, err := client.GetViewTodos("ops"); err == nil {
+			t.Errorf("got no error running a view without a views file")
+		}
+	})
+
+	t.Run("Runs the views saved in the views file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "views.json")
+		saved, _ := views.NewStore(path)
+		saved.Put("", views.View{Name: "dog", Filter: `text~"dog"`})
+		reloaded, err := views.NewStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		withViews := NewLocalTodoClient(stores.NewInMemoryTodoStore(), reloaded)
+		withViews.AddTodo(types.Todo{Description: "Walk the dog"})
+		withViews.AddTodo(types.Todo{Description: "Feed the cat"})
+		todos, err := withViews.GetViewTodos("dog")
+		if err != nil || len(todos) != 1 || todos[0].Description != "Walk the dog" {
+			t.Errorf("got %v and error %v want just the dog todo", todos, err)
+		}
+	})
+}
@@ -0,0 +1,546 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/search"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// ErrOffline is returned alongside cached results, or after queueing a
+// change, when the server couldn't be reached.
+var ErrOffline = errors.New("server unavailable")
+
+const localIdPrefix = "local-"
+
+// OutboxEntry is a change made while offline, waiting to be sent to the server.
+type OutboxEntry struct {
+	Op     string       `json:"op"`
+	Id     string       `json:"id"`
+	Todo   types.Todo   `json:"todo,omitzero"`
+	Status types.Status `json:"status,omitempty"`
+	// The todo's version when the change was made, used like If-Match to
+	// spot changes made on the server in the meantime. It's 0 for todos
+	// that were added offline.
+	BaseVersion int       `json:"base_version,omitempty"`
+	Queued      time.Time `json:"queued"`
+}
+
+const (
+	outboxAdd          = "add"
+	outboxUpdateStatus = "update_status"
+)
+
+// SyncConflict is an outbox entry that couldn't be replayed as-is.
+type SyncConflict struct {
+	Entry  OutboxEntry `json:"entry"`
+	Reason string      `json:"reason"`
+	Server *types.Todo `json:"server,omitempty"`
+}
+
+type offlineCache struct {
+	Fetched time.Time             `json:"fetched"`
+	Todos   map[string]types.Todo `json:"todos"`
+	Views   []views.View          `json:"views,omitempty"`
+}
+
+type offlineOutbox struct {
+	Entries   []OutboxEntry     `json:"entries"`
+	Conflicts []SyncConflict    `json:"conflicts"`
+	LocalIds  map[string]string `json:"local_ids"`
+}
+
+// OfflineTodoClient wraps another TodoClient, keeping a local cache of the
+// todos it has seen and a durable outbox of changes made while the server
+// is unreachable. The outbox is replayed in order once the server is back.
+type OfflineTodoClient struct {
+	remote TodoClient
+	dir    string
+	cache  offlineCache
+	outbox offlineOutbox
+}
+
+func NewOfflineTodoClient(remote TodoClient, dir string) (*OfflineTodoClient, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("problem creating offline cache directory, %v", err)
+	}
+
+	c := &OfflineTodoClient{
+		remote: remote,
+		dir:    dir,
+		cache:  offlineCache{Todos: map[string]types.Todo{}},
+		outbox: offlineOutbox{LocalIds: map[string]string{}},
+	}
+
+	if err := readJSONFile(filepath.Join(dir, "cache.json"), &c.cache); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(filepath.Join(dir, "outbox.json"), &c.outbox); err != nil {
+		return nil, err
+	}
+	if c.cache.Todos == nil {
+		c.cache.Todos = map[string]types.Todo{}
+	}
+	if c.outbox.LocalIds == nil {
+		c.outbox.LocalIds = map[string]string{}
+	}
+	return c, nil
+}
+
+// DefaultOfflineDir returns the per-profile cache directory.
+func DefaultOfflineDir(profile string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("problem finding user cache directory, %v", err)
+	}
+	return filepath.Join(dir, "todo-app", profile), nil
+}
+
+func isOffline(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func (c *OfflineTodoClient) GetTodo(id string) (*types.Todo, error) {
+	id = c.resolveId(id)
+	if !strings.HasPrefix(id, localIdPrefix) && c.trySync() {
+		todo, err := c.remote.GetTodo(id)
+		if err == nil {
+			c.cache.Todos[id] = *todo
+			c.saveCache()
+			return todo, nil
+		}
+		if !isOffline(err) {
+			return nil, err
+		}
+	}
+
+	todo, ok := c.cache.Todos[id]
+	if !ok {
+		return nil, fmt.Errorf("%w, no cached todo with id %s", ErrOffline, id)
+	}
+	return &todo, ErrOffline
+}
+
+func (c *OfflineTodoClient) AddTodo(todo types.Todo) (string, error) {
+	// Check now rather than finding out when the outbox is replayed
+	if err := todo.Validate(); err != nil {
+		return "", err
+	}
+	if c.trySync() {
+		id, err := c.remote.AddTodo(todo)
+		if !isOffline(err) {
+			if err == nil {
+				c.cache.Todos[id] = todo
+				c.saveCache()
+			}
+			return id, err
+		}
+	}
+
+	id := localIdPrefix + uuid.NewString()
+	c.cache.Todos[id] = todo
+	if err := c.enqueue(OutboxEntry{Op: outboxAdd, Id: id, Todo: todo}); err != nil {
+		return "", err
+	}
+	return id, ErrOffline
+}
+
+func (c *OfflineTodoClient) UpdateTodoStatus(id string, status types.Status) error {
+	id = c.resolveId(id)
+	if !strings.HasPrefix(id, localIdPrefix) && c.trySync() {
+		err := c.remote.UpdateTodoStatus(id, status)
+		if !isOffline(err) {
+			if todo, ok := c.cache.Todos[id]; ok && err == nil {
+				todo.SetStatus(status)
+				c.cache.Todos[id] = todo
+				c.saveCache()
+			}
+			return err
+		}
+	}
+
+	todo, ok := c.cache.Todos[id]
+	if !ok {
+		return fmt.Errorf("%w, no cached todo with id %s", ErrOffline, id)
+	}
+	entry := OutboxEntry{Op: outboxUpdateStatus, Id: id, Status: status}
+	if !strings.HasPrefix(id, localIdPrefix) {
+		entry.BaseVersion = todo.Version
+	}
+	todo.SetStatus(status)
+	c.cache.Todos[id] = todo
+	if err := c.enqueue(entry); err != nil {
+		return err
+	}
+	return ErrOffline
+}
+
+// Bulk isn't queued while offline, as the server may no longer agree with
+// the cache by the time the batch could be sent.
+func (c *OfflineTodoClient) Bulk(ops []types.BulkOp) ([]types.BulkResult, error) {
+	if !c.trySync() {
+		return nil, errors.New("bulk changes need the server, which is unavailable")
+	}
+	results, err := c.remote.Bulk(ops)
+	if isOffline(err) {
+		return nil, fmt.Errorf("bulk changes need the server, %v", err)
+	}
+	if err == nil {
+		for _, r := range results {
+			if r.Op == types.BulkDelete {
+				delete(c.cache.Todos, r.Id)
+			} else {
+				c.cache.Todos[r.Id] = *r.Todo
+			}
+		}
+		c.saveCache()
+	}
+	return results, err
+}
+
+func (c *OfflineTodoClient) GetTodosByStatus(status types.Status) (map[string]types.Todo, error) {
+	return c.list(func() (map[string]types.Todo, error) { return c.remote.GetTodosByStatus(status) },
+		func(t types.Todo) bool { return t.Status == status }, false)
+}
+
+func (c *OfflineTodoClient) GetOverdueTodos() (map[string]types.Todo, error) {
+	return c.list(c.remote.GetOverdueTodos, func(t types.Todo) bool { return t.IsOverdue() }, false)
+}
+
+func (c *OfflineTodoClient) GetAllTodos() (map[string]types.Todo, error) {
+	return c.list(c.remote.GetAllTodos, func(t types.Todo) bool { return t.Status != types.Completed }, true)
+}
+
+// QueryTodos fetches a page from the server, caching its todos, or offline
+// queries the cached todos.
+func (c *OfflineTodoClient) QueryTodos(q types.TodoQuery) (*types.TodoPage, error) {
+	if c.trySync() {
+		page, err := c.remote.QueryTodos(q)
+		if err == nil {
+			for _, item := range page.Todos {
+				c.cache.Todos[item.Id] = item.Todo
+			}
+			c.saveCache()
+			return page, nil
+		}
+		if !isOffline(err) {
+			return nil, err
+		}
+	}
+
+	page, err := types.QueryTodos(c.cache.Todos, q)
+	if err != nil {
+		return nil, err
+	}
+	return &page, ErrOffline
+}
+
+func (c *OfflineTodoClient) GetStats(since, until time.Time) (*types.TodoStats, error) {
+	if c.trySync() {
+		stats, err := c.remote.GetStats(since, until)
+		if !isOffline(err) {
+			return stats, err
+		}
+	}
+
+	stats := types.ComputeStats(c.cache.Todos, since, until, time.Now(), types.StatsOldestOpen)
+	return &stats, ErrOffline
+}
+
+func (c *OfflineTodoClient) Search(query string, limit int) ([]types.SearchResult, error) {
+	if c.trySync() {
+		results, err := c.remote.Search(query, limit)
+		if !isOffline(err) {
+			return results, err
+		}
+	}
+
+	return search.SearchTodos(c.cache.Todos, query, limit), ErrOffline
+}
+
+// GetViews returns the server's views, or the ones last fetched from it.
+func (c *OfflineTodoClient) GetViews() ([]views.View, error) {
+	if c.trySync() {
+		result, err := c.remote.GetViews()
+		if err == nil {
+			c.cache.Views = result
+			c.saveCache()
+			return result, nil
+		}
+		if !isOffline(err) {
+			return nil, err
+		}
+	}
+	return c.cache.Views, ErrOffline
+}
+
+// GetViewTodos runs a view on the server, or offline runs the cached view
+// over the cached todos.
+func (c *OfflineTodoClient) GetViewTodos(name string) ([]types.TodoItem, error) {
+	if c.trySync() {
+		todos, err := c.remote.GetViewTodos(name)
+		if err == nil {
+			for _, item := range todos {
+				c.cache.Todos[item.Id] = item.Todo
+			}
+			c.saveCache()
+			return todos, nil
+		}
+		if !isOffline(err) {
+			return nil, err
+		}
+	}
+
+	for _, view := range c.cache.Views {
+		if view.Name == name {
+			todos, err := view.Run(c.cache.Todos)
+			if err != nil {
+				return nil, err
+			}
+			return todos, ErrOffline
+		}
+	}
+	return nil, fmt.Errorf("%w, no cached view named %s", ErrOffline, name)
+}
+
+// list fetches from the server, refreshing the cache, or falls back to the
+// cached todos matching keep. When complete is true the server's result is
+// every todo matching keep, so cached matches missing from it are dropped.
+func (c *OfflineTodoClient) list(fetch func() (map[string]types.Todo, error), keep func(types.Todo) bool, complete bool) (map[string]types.Todo, error) {
+	if c.trySync() {
+		todos, err := fetch()
+		if err == nil {
+			if complete {
+				for id, t := range c.cache.Todos {
+					if _, ok := todos[id]; !ok && keep(t) && !strings.HasPrefix(id, localIdPrefix) {
+						delete(c.cache.Todos, id)
+					}
+				}
+			}
+			for id, t := range todos {
+				c.cache.Todos[id] = t
+			}
+			c.cache.Fetched = time.Now()
+			c.saveCache()
+			return todos, nil
+		}
+		if !isOffline(err) {
+			return nil, err
+		}
+	}
+
+	results := map[string]types.Todo{}
+	for id, t := range c.cache.Todos {
+		if keep(t) {
+			results[id] = t
+		}
+	}
+	return results, ErrOffline
+}
+
+// CachedAt is when the cache was last refreshed from the server.
+func (c *OfflineTodoClient) CachedAt() time.Time {
+	return c.cache.Fetched
+}
+
+func (c *OfflineTodoClient) Pending() []OutboxEntry {
+	return c.outbox.Entries
+}
+
+func (c *OfflineTodoClient) Conflicts() []SyncConflict {
+	return c.outbox.Conflicts
+}
+
+// trySync replays the outbox and reports whether the server is reachable.
+func (c *OfflineTodoClient) trySync() bool {
+	err := c.Sync()
+	return !errors.Is(err, ErrOffline)
+}
+
+// Sync replays queued changes in order. It stops at the first entry that
+// can't be sent because the server is unreachable, leaving it and everything
+// after it queued. Entries the server rejects are moved to the conflicts.
+func (c *OfflineTodoClient) Sync() error {
+	for len(c.outbox.Entries) > 0 {
+		entry := c.outbox.Entries[0]
+		conflict, err := c.replay(entry)
+		if isOffline(err) {
+			return fmt.Errorf("%w, %v", ErrOffline, err)
+		}
+		if conflict != nil {
+			slog.Warn("Offline change conflicts with server", "op", entry.Op, "todo_id", entry.Id, "reason", conflict.Reason)
+			c.outbox.Conflicts = append(c.outbox.Conflicts, *conflict)
+		}
+		c.outbox.Entries = c.outbox.Entries[1:]
+		if err := c.saveOutbox(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *OfflineTodoClient) replay(entry OutboxEntry) (*SyncConflict, error) {
+	switch entry.Op {
+	case outboxAdd:
+		id, err := c.remote.AddTodo(entry.Todo)
+		if isOffline(err) {
+			return nil, err
+		}
+		if err != nil {
+			return &SyncConflict{Entry: entry, Reason: err.Error()}, nil
+		}
+		c.outbox.LocalIds[entry.Id] = id
+		if todo, ok := c.cache.Todos[entry.Id]; ok {
+			delete(c.cache.Todos, entry.Id)
+			c.cache.Todos[id] = todo
+			c.saveCache()
+		}
+		return nil, nil
+
+	case outboxUpdateStatus:
+		id := c.resolveId(entry.Id)
+		if strings.HasPrefix(id, localIdPrefix) {
+			return &SyncConflict{Entry: entry, Reason: "the todo it updates was never created on the server"}, nil
+		}
+
+		server, err := c.remote.GetTodo(id)
+		if isOffline(err) {
+			return nil, err
+		}
+		if errors.Is(err, types.ErrNotFound) {
+			return &SyncConflict{Entry: entry, Reason: "the todo no longer exists on the server"}, nil
+		}
+		if err != nil {
+			return &SyncConflict{Entry: entry, Reason: err.Error()}, nil
+		}
+		if entry.BaseVersion != 0 && server.Version != entry.BaseVersion {
+			return &SyncConflict{Entry: entry, Reason: "the todo was changed on the server after it was edited offline", Server: server}, nil
+		}
+
+		err = c.remote.UpdateTodoStatus(id, entry.Status)
+		if isOffline(err) {
+			return nil, err
+		}
+		if err != nil {
+			return &SyncConflict{Entry: entry, Reason: err.Error(), Server: server}, nil
+		}
+
+		// Later offline edits to the same todo build on this one, so they
+		// shouldn't see it as a change made on the server
+		if updated, err := c.remote.GetTodo(id); err == nil {
+			for i := range c.outbox.Entries {
+				if c.resolveId(c.outbox.Entries[i].Id) == id {
+					c.outbox.Entries[i].BaseVersion = updated.Version
+				}
+			}
+		}
+		return nil, nil
+	}
+
+	return &SyncConflict{Entry: entry, Reason: fmt.Sprintf("unknown outbox operation %q", entry.Op)}, nil
+}
+
+// ResolveConflict settles the conflict at index i. Keeping the local change
+// sends it to the server regardless of what's there; otherwise it's dropped
+// and the server's copy replaces the cached one.
+func (c *OfflineTodoClient) ResolveConflict(i int, keepLocal bool) error {
+	if i < 0 || i >= len(c.outbox.Conflicts) {
+		return fmt.Errorf("no conflict number %d", i+1)
+	}
+	conflict := c.outbox.Conflicts[i]
+	entry := conflict.Entry
+	id := c.resolveId(entry.Id)
+
+	if keepLocal {
+		var err error
+		switch entry.Op {
+		case outboxAdd:
+			var newId string
+			newId, err = c.remote.AddTodo(entry.Todo)
+			if err == nil {
+				c.outbox.LocalIds[entry.Id] = newId
+				delete(c.cache.Todos, entry.Id)
+				c.cache.Todos[newId] = entry.Todo
+			}
+		case outboxUpdateStatus:
+			if conflict.Server == nil {
+				// The todo is gone from the server, so recreate it
+				todo := c.cache.Todos[id]
+				var newId string
+				newId, err = c.remote.AddTodo(todo)
+				if err == nil {
+					delete(c.cache.Todos, id)
+					c.cache.Todos[newId] = todo
+				}
+			} else {
+				err = c.remote.UpdateTodoStatus(id, entry.Status)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		switch {
+		case conflict.Server != nil:
+			c.cache.Todos[id] = *conflict.Server
+		default:
+			delete(c.cache.Todos, id)
+		}
+	}
+
+	c.outbox.Conflicts = append(c.outbox.Conflicts[:i], c.outbox.Conflicts[i+1:]...)
+	c.saveCache()
+	return c.saveOutbox()
+}
+
+func (c *OfflineTodoClient) resolveId(id string) string {
+	if serverId, ok := c.outbox.LocalIds[id]; ok {
+		return serverId
+	}
+	return id
+}
+
+func (c *OfflineTodoClient) enqueue(entry OutboxEntry) error {
+	entry.Queued = time.Now()
+	c.outbox.Entries = append(c.outbox.Entries, entry)
+	c.saveCache()
+	return c.saveOutbox()
+}
+
+func (c *OfflineTodoClient) saveOutbox() error {
+	return atomicfile.WriteJSON(filepath.Join(c.dir, "outbox.json"), c.outbox)
+}
+
+// The cache can always be rebuilt from the server, so failures to save it
+// are only logged.
+func (c *OfflineTodoClient) saveCache() {
+	if err := atomicfile.WriteJSON(filepath.Join(c.dir, "cache.json"), c.cache); err != nil {
+		slog.Warn("Failed to save offline cache", "error", err.Error())
+	}
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("problem reading %s, %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("problem parsing %s, %v", path, err)
+	}
+	return nil
+}
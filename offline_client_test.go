@@ -0,0 +1,277 @@
+package todoapp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/filter"
+	"grantjames.github.io/todo-app/search"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+func TestOfflineTodoClient(t *testing.T) {
+	t.Run("Serves cached todos while the server is down", func(t *testing.T) {
+		remote := newStubRemote()
+		remote.todos["1"] = types.NewTodo("Cached todo", nil)
+		client, _ := NewOfflineTodoClient(remote, t.TempDir())
+
+		client.GetAllTodos()
+		remote.offline = true
+
+		todos, err := client.GetAllTodos()
+		if !errors.Is(err, ErrOffline) {
+			t.Fatalf("got error %v want ErrOffline", err)
+		}
+		if len(todos) != 1 {
+			t.Errorf("got %d cached todos want 1", len(todos))
+		}
+	})
+
+	t.Run("Queries the server, or the cache while it's down", func(t *testing.T) {
+		remote := newStubRemote()
+		remote.todos["1"] = types.NewTodo("Buy milk", nil)
+		remote.todos["2"] = types.NewTodo("Walk the dog", nil)
+		client, _ := NewOfflineTodoClient(remote, t.TempDir())
+		expr, _ := filter.Parse("text~milk")
+		q := types.TodoQuery{Filter: expr}
+
+		page, err := client.QueryTodos(q)
+		if err != nil || len(page.Todos) != 1 || page.Todos[0].Id != "1" {
+			t.Fatalf("got %+v and error %v want the matching todo", page, err)
+		}
+
+		remote.offline = true
+		page, err = client.QueryTodos(q)
+		if !errors.Is(err, ErrOffline) || len(page.Todos) != 1 || page.Todos[0].Id != "1" {
+			t.Errorf("got %+v and error %v want the cached todo", page, err)
+		}
+	})
+
+	t.Run("Queues changes made offline and replays them in order", func(t *testing.T) {
+		dir := t.TempDir()
+		remote := newStubRemote()
+		remote.offline = true
+		client, _ := NewOfflineTodoClient(remote, dir)
+
+		id, err := client.AddTodo(types.NewTodo("Offline todo", nil))
+		if !errors.Is(err, ErrOffline) {
+			t.Fatalf("got error %v want ErrOffline", err)
+		}
+		if err := client.UpdateTodoStatus(id, types.Started); !errors.Is(err, ErrOffline) {
+			t.Fatalf("got error %v want ErrOffline", err)
+		}
+
+		// A fresh client picks up the outbox from disk
+		client, _ = NewOfflineTodoClient(remote, dir)
+		if len(client.Pending()) != 2 {
+			t.Fatalf("got %d pending changes want 2", len(client.Pending()))
+		}
+
+		remote.offline = false
+		if err := client.Sync(); err != nil {
+			t.Fatalf("unexpected error syncing: %v", err)
+		}
+		if len(client.Pending()) != 0 || len(client.Conflicts()) != 0 {
+			t.Fatalf("expected the outbox to be empty, got %d pending and %d conflicts", len(client.Pending()), len(client.Conflicts()))
+		}
+
+		todo := remote.todos["remote-1"]
+		if todo.Description != "Offline todo" || todo.Status != types.Started {
+			t.Errorf("got %v on the server, want the offline todo started", todo)
+		}
+	})
+
+	t.Run("Later offline changes to a todo build on earlier ones", func(t *testing.T) {
+		remote := newStubRemote()
+		remote.todos["1"] = types.NewTodo("Edited twice", nil)
+		client, _ := NewOfflineTodoClient(remote, t.TempDir())
+		client.GetAllTodos()
+
+		remote.offline = true
+		client.UpdateTodoStatus("1", types.Started)
+		client.UpdateTodoStatus("1", types.Completed)
+
+		remote.offline = false
+		if err := client.Sync(); err != nil || len(client.Conflicts()) != 0 {
+			t.Fatalf("got error %v and conflicts %+v want none", err, client.Conflicts())
+		}
+		if remote.todos["1"].Status != types.Completed {
+			t.Errorf("got status %q want Completed", remote.todos["1"].Status)
+		}
+	})
+
+	t.Run("Reports conflicts for todos changed on the server", func(t *testing.T) {
+		remote := newStubRemote()
+		remote.todos["1"] = types.NewTodo("Shared todo", nil)
+		remote.todos["2"] = types.NewTodo("Deleted todo", nil)
+		client, _ := NewOfflineTodoClient(remote, t.TempDir())
+		client.GetAllTodos()
+
+		remote.offline = true
+		client.UpdateTodoStatus("1", types.Completed)
+		client.UpdateTodoStatus("2", types.Completed)
+
+		changed := remote.todos["1"]
+		changed.SetStatus(types.Started)
+		// Conflicts are found by version, so a server whose clock is behind
+		// doesn't hide them
+		changed.Updated = changed.Updated.Add(-time.Hour)
+		remote.todos["1"] = changed
+		delete(remote.todos, "2")
+
+		remote.offline = false
+		client.Sync()
+
+		if len(client.Conflicts()) != 2 {
+			t.Fatalf("got %d conflicts want 2", len(client.Conflicts()))
+		}
+
+		if err := client.ResolveConflict(0, true); err != nil {
+			t.Fatalf("unexpected error resolving conflict: %v", err)
+		}
+		if remote.todos["1"].Status != types.Completed {
+			t.Errorf("got status %q want the local change to win", remote.todos["1"].Status)
+		}
+
+		if err := client.ResolveConflict(0, false); err != nil {
+			t.Fatalf("unexpected error resolving conflict: %v", err)
+		}
+		if len(client.Conflicts()) != 0 {
+			t.Errorf("got %d conflicts want 0", len(client.Conflicts()))
+		}
+	})
+}
+
+type stubRemote struct {
+	todos   map[string]types.Todo
+	views   []views.View
+	offline bool
+	nextId  int
+}
+
+func newStubRemote() *stubRemote {
+	return &stubRemote{todos: map[string]types.Todo{}}
+}
+
+func (s *stubRemote) unreachable() error {
+	return &url.Error{Op: "Get", URL: "http://localhost:5000", Err: errors.New("connection refused")}
+}
+
+func (s *stubRemote) GetTodo(id string) (*types.Todo, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	todo, ok := s.todos[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to get todo: status code 404")
+	}
+	return &todo, nil
+}
+
+func (s *stubRemote) AddTodo(todo types.Todo) (string, error) {
+	if s.offline {
+		return "", s.unreachable()
+	}
+	s.nextId++
+	id := fmt.Sprintf("remote-%d", s.nextId)
+	s.todos[id] = todo
+	return id, nil
+}
+
+func (s *stubRemote) UpdateTodoStatus(id string, status types.Status) error {
+	if s.offline {
+		return s.unreachable()
+	}
+	todo, ok := s.todos[id]
+	if !ok {
+		return fmt.Errorf("failed to update todo status: status code 404")
+	}
+	todo.SetStatus(status)
+	s.todos[id] = todo
+	return nil
+}
+
+func (s *stubRemote) GetTodosByStatus(status types.Status) (map[string]types.Todo, error) {
+	return s.filter(func(t types.Todo) bool { return t.Status == status })
+}
+
+func (s *stubRemote) GetOverdueTodos() (map[string]types.Todo, error) {
+	return s.filter(func(t types.Todo) bool { return t.IsOverdue() })
+}
+
+func (s *stubRemote) GetAllTodos() (map[string]types.Todo, error) {
+	return s.filter(func(t types.Todo) bool { return t.Status != types.Completed })
+}
+
+func (s *stubRemote) QueryTodos(q types.TodoQuery) (*types.TodoPage, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	page, err := types.QueryTodos(s.todos, q)
+	return &page, err
+}
+
+func (s *stubRemote) GetStats(since, until time.Time) (*types.TodoStats, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	stats := types.ComputeStats(s.todos, since, until, time.Now(), types.StatsOldestOpen)
+	return &stats, nil
+}
+
+func (s *stubRemote) Search(query string, limit int) ([]types.SearchResult, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	return search.SearchTodos(s.todos, query, limit), nil
+}
+
+func (s *stubRemote) Bulk(ops []types.BulkOp) ([]types.BulkResult, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	todos, results, err := types.ApplyBulk(s.todos, ops, func() string {
+		s.nextId++
+		return fmt.Sprintf("remote-%d", s.nextId)
+	})
+	if err == nil {
+		s.todos = todos
+	}
+	return results, err
+}
+
+func (s *stubRemote) GetViews() ([]views.View, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	return s.views, nil
+}
+
+func (s *stubRemote) GetViewTodos(name string) ([]types.TodoItem, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	for _, v := range s.views {
+		if v.Name == name {
+			return v.Run(s.todos)
+		}
+	}
+	return nil, views.ErrUnknownView
+}
+
+func (s *stubRemote) filter(keep func(types.Todo) bool) (map[string]types.Todo, error) {
+	if s.offline {
+		return nil, s.unreachable()
+	}
+	results := map[string]types.Todo{}
+	for id, t := range s.todos {
+		if keep(t) {
+			results[id] = t
+		}
+	}
+	return results, nil
+}
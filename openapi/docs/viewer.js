@@ -0,0 +1,107 @@
+// Renders openapi.json as a list of operations grouped by tag, with their
+// parameters, request bodies and responses.
+(async function () {
+	const spec = await (await fetch("../openapi.json")).json();
+	const base = spec.servers && spec.servers.length ? spec.servers[0].url : "";
+
+	document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+	document.getElementById("description").textContent = spec.info.description || "";
+
+	const el = (tag, attrs, ...children) => {
+		const node = document.createElement(tag);
+		Object.assign(node, attrs);
+		node.append(...children.filter((c) => c != null));
+		return node;
+	};
+
+	// resolve follows a $ref within the document.
+	const resolve = (obj) => {
+		if (!obj || !obj.$ref) {
+			return obj;
+		}
+		return obj.$ref.slice(2).split("/").reduce((o, key) => o[key], spec);
+	};
+
+	// example builds a sample value for a schema, following references.
+	const example = (schema, depth = 0) => {
+		if (schema.$ref) {
+			return depth > 4 ? {} : example(resolve(schema), depth + 1);
+		}
+		if (schema.allOf) {
+			return Object.assign({}, ...schema.allOf.map((s) => example(s, depth + 1)));
+		}
+		if (schema.example !== undefined) {
+			return schema.example;
+		}
+		if (schema.enum) {
+			return schema.enum[0];
+		}
+		switch (schema.type) {
+		case "object":
+			if (schema.properties) {
+				return Object.fromEntries(Object.entries(schema.properties).map(([k, v]) => [k, example(v, depth + 1)]));
+			}
+			return schema.additionalProperties ? { key: example(schema.additionalProperties, depth + 1) } : {};
+		case "array":
+			return [example(schema.items, depth + 1)];
+		case "integer":
+		case "number":
+			return 0;
+		case "boolean":
+			return true;
+		default:
+			return schema.format === "date-time" ? "2026-01-02T15:04:05Z" : "string";
+		}
+	};
+
+	const content = (c) => {
+		if (!c) {
+			return null;
+		}
+		return el("div", {}, ...Object.entries(c).map(([type, media]) =>
+			el("div", {}, el("code", { textContent: type }),
+				el("pre", { textContent: JSON.stringify(example(media.schema), null, 2) }))));
+	};
+
+	const parameters = (params) => {
+		if (!params || !params.length) {
+			return null;
+		}
+		return el("table", {},
+			el("tr", {}, el("th", { textContent: "Parameter" }), el("th", { textContent: "In" }), el("th", { textContent: "Description" })),
+			...params.map(resolve).map((p) => el("tr", {},
+				el("td", {}, el("code", { textContent: p.name + (p.required ? " *" : "") })),
+				el("td", { textContent: p.in }),
+				el("td", { textContent: p.description || "" }))));
+	};
+
+	const responses = (rs) => el("table", {},
+		...Object.entries(rs).map(([status, r]) => {
+			r = resolve(r);
+			return el("tr", {}, el("td", {}, el("code", { textContent: status })),
+				el("td", {}, r.description, content(r.content)));
+		}));
+
+	const groups = {};
+	for (const [path, ops] of Object.entries(spec.paths)) {
+		for (const [method, op] of Object.entries(ops)) {
+			const tag = (op.tags && op.tags[0]) || "other";
+			(groups[tag] = groups[tag] || []).push(
+				el("details", { id: op.operationId },
+					el("summary", {}, el("span", { className: "method " + method, textContent: method.toUpperCase() }),
+						base + path + " ", el("em", { textContent: op.summary || "" })),
+					el("div", {},
+						op.description ? el("p", { textContent: op.description }) : null,
+						parameters(op.parameters),
+						op.requestBody ? el("h4", { textContent: "Request body" }) : null,
+						op.requestBody ? content(resolve(op.requestBody).content) : null,
+						el("h4", { textContent: "Responses" }),
+						responses(op.responses))));
+		}
+	}
+
+	const main = document.getElementById("operations");
+	for (const [tag, ops] of Object.entries(groups)) {
+		main.append(el("h2", { textContent: tag }), ...ops);
+	}
+})();
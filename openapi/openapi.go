@@ -0,0 +1,24 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Spec is the OpenAPI document for v1 of the API, which is served at /api
+// and /api/v1.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// SpecV2 is the OpenAPI document for v2 of the API, under /api/v2.
+//
+//go:embed openapi-v2.json
+var SpecV2 []byte
+
+//go:embed docs
+var docs embed.FS
+
+// Docs is the HTML viewer for Spec and SpecV2, which loads the version's
+// document from ../openapi.json.
+var Docs, _ = fs.Sub(docs, "docs")
@@ -0,0 +1,139 @@
+package todoapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/openapi"
+	"grantjames.github.io/todo-app/stores"
+)
+
+type openAPIDocument struct {
+	Paths map[string]map[string]struct {
+		OperationId string                     `json:"operationId"`
+		Responses   map[string]json.RawMessage `json:"responses"`
+	} `json:"paths"`
+	Components map[string]map[string]json.RawMessage `json:"components"`
+}
+
+// TestOpenAPIMatchesRoutes fails if a version's route is missing from its
+// OpenAPI document, or the document describes an operation the server
+// doesn't have.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	specs := map[APIVersion][]byte{APIv1: openapi.Spec, APIv2: openapi.SpecV2}
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+	router := server.router
+
+	for _, version := range APIVersions {
+		var doc openAPIDocument
+		if err := json.Unmarshal(specs[version], &doc); err != nil {
+			t.Fatalf("couldn't parse the %s document, %v", version, err)
+		}
+
+		for _, route := range version.Routes() {
+			t.Run("The "+string(version)+" document describes "+route.Name, func(t *testing.T) {
+				op, ok := doc.Paths[openAPIPath(route)][strings.ToLower(route.Method)]
+				if !ok {
+					t.Fatalf("no %s %s in the document", route.Method, openAPIPath(route))
+				}
+				if op.OperationId != route.Name {
+					t.Errorf("got operationId %q want %q", op.OperationId, route.Name)
+				}
+				if _, ok := op.Responses[strconv.Itoa(route.Status)]; !ok {
+					t.Errorf("no %d response, the status the route succeeds with", route.Status)
+				}
+			})
+		}
+
+		wildcard := regexp.MustCompile(`{[^}]+}`)
+		for path, ops := range doc.Paths {
+			for method, op := range ops {
+				t.Run("The server handles "+string(version)+" "+op.OperationId, func(t *testing.T) {
+					// A request for the documented path should reach the
+					// route with the same name
+					request := httptest.NewRequest(strings.ToUpper(method), version.Prefix()+wildcard.ReplaceAllString(path, "x"), nil)
+					_, pattern := router.Handler(request)
+					var found bool
+					for _, route := range version.Routes() {
+						if route.Name == op.OperationId {
+							found = true
+							if pattern != route.Pattern(version.Prefix()) {
+								t.Errorf("%s %s is handled by %q want %q", method, path, pattern, route.Pattern(version.Prefix()))
+							}
+						}
+					}
+					if !found {
+						t.Errorf("%s %s has no route named %q", method, path, op.OperationId)
+					}
+				})
+			}
+		}
+
+		t.Run("Every reference in the "+string(version)+" document resolves", func(t *testing.T) {
+			refs := regexp.MustCompile(`"\$ref": "#/components/(\w+)/(\w+)"`).FindAllSubmatch(specs[version], -1)
+			for _, ref := range refs {
+				if _, ok := doc.Components[string(ref[1])][string(ref[2])]; !ok {
+					t.Errorf("%s/%s isn't defined", ref[1], ref[2])
+				}
+			}
+		})
+	}
+}
+
+func TestServingTheOpenAPIDocument(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, url, nil))
+		return response
+	}
+
+	t.Run("The document is served as JSON", func(t *testing.T) {
+		response := get("/api/openapi.json")
+		assertStatus(t, response.Code, http.StatusOK)
+		if !bytes.Equal(response.Body.Bytes(), openapi.Spec) {
+			t.Error("the body isn't openapi.json")
+		}
+	})
+
+	t.Run("Each version serves its own document", func(t *testing.T) {
+		response := get("/api/v2/openapi.json")
+		assertStatus(t, response.Code, http.StatusOK)
+		if !bytes.Equal(response.Body.Bytes(), openapi.SpecV2) {
+			t.Error("the body isn't openapi-v2.json")
+		}
+		if !bytes.Equal(get("/api/v1/openapi.json").Body.Bytes(), openapi.Spec) {
+			t.Error("the v1 body isn't openapi.json")
+		}
+	})
+
+	t.Run("The docs index is the viewer's page", func(t *testing.T) {
+		response := get("/api/docs/")
+		assertStatus(t, response.Code, http.StatusOK)
+		if !strings.HasPrefix(response.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("got content type %q want text/html", response.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("The viewer's assets are served", func(t *testing.T) {
+		assertStatus(t, get("/api/docs/viewer.js").Code, http.StatusOK)
+	})
+
+	t.Run("Missing files are a not found problem", func(t *testing.T) {
+		assertProblem(t, get("/api/docs/missing.js"), http.StatusNotFound, ProblemNotFound)
+	})
+}
+
+// openAPIPath is the route's path as OpenAPI writes it, without ServeMux's
+// {$} and with {name...} wildcards as {name}.
+func openAPIPath(route Route) string {
+	path := strings.ReplaceAll(route.Path, "{$}", "")
+	return strings.ReplaceAll(path, "...}", "}")
+}
@@ -0,0 +1,136 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// ProblemMediaType is the content type of error responses.
+const ProblemMediaType = "application/problem+json"
+
+// Problem types identify the kind of error. They don't change, so clients
+// can rely on them, unlike titles and details.
+const (
+	problemTypeBase = "https://grantjames.github.io/todo-app/problems/"
+
+	ProblemBadRequest    = problemTypeBase + "bad-request"
+	ProblemValidation    = problemTypeBase + "validation"
+	ProblemNotFound      = problemTypeBase + "not-found"
+	ProblemConflict      = problemTypeBase + "conflict"
+	ProblemBulkFailed    = problemTypeBase + "bulk-failed"
+	ProblemKeyReused     = problemTypeBase + "idempotency-key-reused"
+	ProblemCanceled      = problemTypeBase + "request-canceled"
+	ProblemInternal      = problemTypeBase + "internal"
+	ProblemNotAvailable  = problemTypeBase + "not-available"
+	ProblemUpgradeNeeded = problemTypeBase + "upgrade-required"
+	ProblemTooLarge      = problemTypeBase + "too-large"
+	ProblemUnauthorized  = problemTypeBase + "unauthorized"
+	ProblemForbidden     = problemTypeBase + "forbidden"
+	ProblemRateLimited   = problemTypeBase + "rate-limited"
+	// The Accept or Content-Type header names a format there's no Codec for
+	ProblemNotAcceptable        = problemTypeBase + "not-acceptable"
+	ProblemUnsupportedMediaType = problemTypeBase + "unsupported-media-type"
+)
+
+var problemTitles = map[string]string{
+	ProblemBadRequest:           "The request couldn't be read",
+	ProblemValidation:           "The request isn't valid",
+	ProblemNotFound:             "Not found",
+	ProblemConflict:             "Conflicts with the current state",
+	ProblemBulkFailed:           "An operation failed so none were applied",
+	ProblemKeyReused:            "Idempotency-Key was already used for a different request",
+	ProblemCanceled:             "The request was canceled",
+	ProblemInternal:             "Something went wrong on the server",
+	ProblemNotAvailable:         "Not available on this server",
+	ProblemUpgradeNeeded:        "A protocol upgrade is needed",
+	ProblemTooLarge:             "The request body is too large",
+	ProblemUnauthorized:         "A valid API token is needed",
+	ProblemForbidden:            "You aren't allowed to do this",
+	ProblemRateLimited:          "Too many requests",
+	ProblemNotAcceptable:        "None of the accepted formats can be sent",
+	ProblemUnsupportedMediaType: "The request body's format isn't supported",
+}
+
+// Problem is an RFC 9457 problem details response, which every error from
+// the API is.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	TraceId  string             `json:"trace_id,omitempty"`
+	Errors   []types.FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(r *http.Request, status int, typ, detail string) Problem {
+	p := Problem{Type: typ, Title: problemTitles[typ], Status: status, Detail: detail, Instance: r.URL.Path}
+	p.TraceId, _ = r.Context().Value(TraceIdKey{}).(string)
+	return p
+}
+
+// writeProblem writes p, or any value embedding a Problem to add members of
+// its own.
+func writeProblem(w http.ResponseWriter, status int, p any) {
+	w.Header().Set("Content-Type", ProblemMediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func problem(w http.ResponseWriter, r *http.Request, status int, typ, detail string) {
+	writeProblem(w, status, newProblem(r, status, typ, detail))
+}
+
+// writeError responds with the problem matching err: a 404 for
+// types.ErrNotFound, a 409 for types.ErrConflict, a 400 for types.ErrInvalid
+// listing the invalid fields, a 403 for types.ErrForbidden, and otherwise a
+// 500.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var p Problem
+	switch {
+	case errors.Is(err, types.ErrNotFound):
+		p = newProblem(r, http.StatusNotFound, ProblemNotFound, err.Error())
+	case errors.Is(err, types.ErrConflict):
+		p = newProblem(r, http.StatusConflict, ProblemConflict, err.Error())
+	case errors.Is(err, types.ErrForbidden):
+		p = newProblem(r, http.StatusForbidden, ProblemForbidden, err.Error())
+	case errors.Is(err, types.ErrInvalid):
+		p = newProblem(r, http.StatusBadRequest, ProblemValidation, err.Error())
+		var validation *types.ValidationError
+		if errors.As(err, &validation) {
+			p.Errors = validation.Fields
+		}
+	default:
+		internalError(w, r, err)
+		return
+	}
+	writeProblem(w, p.Status, p)
+}
+
+// internalError logs err and responds with a 500 that doesn't say what went
+// wrong, as the error can have details of the server in it.
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "Request failed", slog.String("error", err.Error()))
+	problem(w, r, http.StatusInternalServerError, ProblemInternal, "an unexpected error occurred")
+}
+
+// badRequest is for requests that couldn't be read at all, like a body that
+// isn't JSON.
+func badRequest(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "Failed to decode request body", slog.String("error", err.Error()))
+	problem(w, r, http.StatusBadRequest, ProblemBadRequest, "Failed to read request body: "+err.Error())
+}
+
+// invalid is a validation problem with a single invalid field.
+func invalid(w http.ResponseWriter, r *http.Request, field, format string, args ...any) {
+	writeError(w, r, types.Invalid(field, format, args...))
+}
+
+func requestCanceled(w http.ResponseWriter, r *http.Request) {
+	problem(w, r, http.StatusRequestTimeout, ProblemCanceled, "request canceled")
+}
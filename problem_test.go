@@ -0,0 +1,138 @@
+package todoapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestProblemResponses(t *testing.T) {
+	store := &StubTodoStore{todos: map[string]types.Todo{"1": types.NewTodo("Existing todo", nil)}}
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	t.Run("A missing todo is a not found problem with the trace id", func(t *testing.T) {
+		request := newGetTodoRequest("missing")
+		request = request.WithContext(context.WithValue(request.Context(), TraceIdKey{}, "trace-1"))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		got := assertProblem(t, response, http.StatusNotFound, ProblemNotFound)
+		if got.TraceId != "trace-1" || got.Instance != "/api/todos/missing" || got.Title == "" {
+			t.Errorf("got %+v want the trace id, instance and a title", got)
+		}
+	})
+
+	t.Run("A body that can't be read when updating is a 400", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPut, "/api/todos/1", strings.NewReader("{"))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		assertProblem(t, response, http.StatusBadRequest, ProblemBadRequest)
+	})
+
+	t.Run("An unknown status names the invalid field", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPut, "/api/todos/1", strings.NewReader(`{"status":"Sleeping"}`))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		got := assertProblem(t, response, http.StatusBadRequest, ProblemValidation)
+		if len(got.Errors) != 1 || got.Errors[0].Field != "status" {
+			t.Errorf("got errors %+v want one for status", got.Errors)
+		}
+		if len(store.updateCalls) != 0 {
+			t.Errorf("got %d calls to UpdateTodoStatus want 0", len(store.updateCalls))
+		}
+	})
+
+	t.Run("A store error when adding is a 500", func(t *testing.T) {
+		store.addErr = errors.New("disk full")
+		defer func() { store.addErr = nil }()
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newPostTodoRequest())
+
+		got := assertProblem(t, response, http.StatusInternalServerError, ProblemInternal)
+		if strings.Contains(got.Detail, "disk full") {
+			t.Errorf("got detail %q want the error kept from the client", got.Detail)
+		}
+	})
+
+	t.Run("Invalid query parameters are validation problems", func(t *testing.T) {
+		for _, query := range []string{"/api/v2/todos/?limit=0", "/api/v2/todos/?sort=colour", "/api/todos/?status=done", "/api/stats?since=yesterday", "/api/search", "/api/events?status=nope"} {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, query, nil))
+
+			got := assertProblem(t, response, http.StatusBadRequest, ProblemValidation)
+			if len(got.Errors) != 1 {
+				t.Errorf("%s: got errors %+v want one", query, got.Errors)
+			}
+		}
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	ts := httptest.NewServer(NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore())))
+	defer ts.Close()
+	client := NewTodoAPIClient(ts.URL + APIPrefix)
+
+	t.Run("A missing todo is ErrNotFound", func(t *testing.T) {
+		_, err := client.GetTodo("missing")
+		if !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Type != ProblemNotFound {
+			t.Errorf("got %#v want a not found APIError", err)
+		}
+	})
+
+	t.Run("Invalid fields are available as a ValidationError", func(t *testing.T) {
+		_, err := client.Search("", 0)
+		if !errors.Is(err, types.ErrInvalid) {
+			t.Errorf("got %v want ErrInvalid", err)
+		}
+		var validation *types.ValidationError
+		if !errors.As(err, &validation) || len(validation.Fields) != 1 || validation.Fields[0].Field != "q" {
+			t.Errorf("got %v want q to be invalid", err)
+		}
+	})
+
+	t.Run("Errors that aren't problems keep their body", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}))
+		defer plain.Close()
+
+		_, err := NewTodoAPIClient(plain.URL).GetTodo("1")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Detail != "bad gateway" {
+			t.Errorf("got %#v want the gateway error", err)
+		}
+		if errors.Is(err, types.ErrNotFound) {
+			t.Error("a 502 shouldn't be ErrNotFound")
+		}
+	})
+}
+
+func assertProblem(t testing.TB, response *httptest.ResponseRecorder, status int, typ string) Problem {
+	t.Helper()
+	assertStatus(t, response.Code, status)
+	if got := response.Header().Get("Content-Type"); got != ProblemMediaType {
+		t.Errorf("got content type %q want %q", got, ProblemMediaType)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(response.Body).Decode(&p); err != nil {
+		t.Fatalf("Unable to parse problem %q, '%v'", response.Body, err)
+	}
+	if p.Type != typ || p.Status != status {
+		t.Errorf("got problem %+v want type %s and status %d", p, typ, status)
+	}
+	return p
+}
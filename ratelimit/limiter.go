@@ -0,0 +1,149 @@
+// Package ratelimit limits how often clients can make requests, with a
+// token bucket for each client.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// Limit allows Requests every Per, and bursts of up to Requests at once. The
+// zero Limit allows any number.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+var units = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+// ParseLimit parses a number of requests per second, minute or hour, such
+// as 600/m. An empty string or 0 is no limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+	n, unit, ok := strings.Cut(s, "/")
+	requests, err := strconv.Atoi(n)
+	if !ok || err != nil || requests < 1 || units[unit] == 0 {
+		return Limit{}, types.Invalidf("%q isn't a limit, expected a number of requests per s, m or h, like 600/m", s)
+	}
+	return Limit{Requests: requests, Per: units[unit]}, nil
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "0"
+	}
+	for unit, per := range units {
+		if per == l.Per {
+			return fmt.Sprintf("%d/%s", l.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d per %s", l.Requests, l.Per)
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// Decision is whether a request is allowed, and how the client stands
+// against its limit afterwards.
+type Decision struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is how many more requests can be made straight away
+	Remaining int
+	// Reset is how long until the client could make a full burst again
+	Reset time.Duration
+	// RetryAfter is how long until the next request will be allowed, if
+	// this one wasn't
+	RetryAfter time.Duration
+}
+
+// Limiter keeps a bucket of Limit.Requests tokens for each client, refilled
+// at Limit.Requests every Limit.Per. Each request takes a token, and is
+// refused if there isn't one.
+type Limiter struct {
+	limit Limit
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{limit: limit, buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow takes a token from the client's bucket, if there's one to take.
+// Clients are identified by key, such as their address.
+func (l *Limiter) Allow(key string) Decision {
+	if l.limit.Unlimited() {
+		return Decision{Allowed: true, Limit: l.limit}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.pruneLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Requests), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(float64(l.limit.Requests), b.tokens+l.refill(now.Sub(b.updated)))
+	b.updated = now
+
+	d := Decision{Limit: l.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.wait(1 - b.tokens)
+	}
+	d.Remaining = int(math.Floor(b.tokens))
+	d.Reset = l.wait(float64(l.limit.Requests) - b.tokens)
+	return d
+}
+
+// refill is how many tokens are added over d.
+func (l *Limiter) refill(d time.Duration) float64 {
+	return float64(l.limit.Requests) * float64(d) / float64(l.limit.Per)
+}
+
+// wait is how long it takes to add tokens.
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(l.limit.Per) / float64(l.limit.Requests)))
+}
+
+// pruneLocked forgets the buckets that have filled up again, at most once
+// every Limit.Per, as they're the same as a new one. It stops clients that
+// have gone away being kept forever.
+func (l *Limiter) pruneLocked(now time.Time) {
+	if now.Sub(l.pruned) < l.limit.Per {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.tokens+l.refill(now.Sub(b.updated)) >= float64(l.limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+	l.pruned = now
+}
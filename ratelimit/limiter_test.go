@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(Limit{Requests: 3, Per: time.Minute})
+	limiter.now = func() time.Time { return now }
+
+	t.Run("A burst of up to the limit is allowed", func(t *testing.T) {
+		for i := range 3 {
+			d := limiter.Allow("alice")
+			if !d.Allowed || d.Remaining != 2-i {
+				t.Errorf("request %d: got %+v want it allowed with %d remaining", i, d, 2-i)
+			}
+		}
+		d := limiter.Allow("alice")
+		if d.Allowed || d.RetryAfter != 20*time.Second || d.Reset != time.Minute {
+			t.Errorf("got %+v want it refused until the next token in 20s", d)
+		}
+	})
+
+	t.Run("Clients have their own buckets", func(t *testing.T) {
+		if d := limiter.Allow("bob"); !d.Allowed {
+			t.Errorf("got %+v want bob allowed", d)
+		}
+	})
+
+	t.Run("Tokens are added back over time", func(t *testing.T) {
+		now = now.Add(20 * time.Second)
+		if d := limiter.Allow("alice"); !d.Allowed || d.Remaining != 0 {
+			t.Errorf("got %+v want one more request allowed", d)
+		}
+		if d := limiter.Allow("alice"); d.Allowed {
+			t.Errorf("got %+v want it refused", d)
+		}
+	})
+
+	t.Run("Full buckets are forgotten", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		limiter.Allow("carol")
+		if len(limiter.buckets) != 1 {
+			t.Errorf("got %d buckets want only carol's", len(limiter.buckets))
+		}
+	})
+
+	t.Run("The zero limit allows everything", func(t *testing.T) {
+		unlimited := NewLimiter(Limit{})
+		for range 100 {
+			if d := unlimited.Allow("alice"); !d.Allowed {
+				t.Fatalf("got %+v want it allowed", d)
+			}
+		}
+	})
+}
+
+func TestParseLimit(t *testing.T) {
+	for s, want := range map[string]Limit{
+		"600/m": {600, time.Minute},
+		"10/s":  {10, time.Second},
+		"5/h":   {5, time.Hour},
+		"0":     {},
+		"":      {},
+	} {
+		got, err := ParseLimit(s)
+		if err != nil || got != want {
+			t.Errorf("%q: got %v and error %v want %v", s, got, err, want)
+		}
+		if err == nil && s != "" && got.String() != s {
+			t.Errorf("got %q formatting %q", got.String(), s)
+		}
+	}
+
+	for _, s := range []string{"600", "600/d", "-1/s", "x/m"} {
+		if _, err := ParseLimit(s); !errors.Is(err, types.ErrInvalid) {
+			t.Errorf("%q: got %v want it to be invalid", s, err)
+		}
+	}
+}
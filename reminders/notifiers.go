@@ -0,0 +1,117 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/webhooks"
+)
+
+// Reminder is one reminder being sent for a todo.
+type Reminder struct {
+	TodoId string
+	Todo   types.Todo
+	Rule   Rule
+	FireAt time.Time
+}
+
+func (r Reminder) Subject() string {
+	switch r.Rule.Kind {
+	case DueSoon:
+		return fmt.Sprintf("%q is due in %s", r.Todo.Description, formatDuration(r.Rule.Offset))
+	case Due:
+		return fmt.Sprintf("%q is due now", r.Todo.Description)
+	default:
+		return fmt.Sprintf("%q is overdue", r.Todo.Description)
+	}
+}
+
+func (r Reminder) Body() string {
+	return fmt.Sprintf("%s\n\nTodo %s\n%s\n", r.Subject(), r.TodoId, r.Todo.String())
+}
+
+// Notifier sends reminders somewhere people will see them.
+type Notifier interface {
+	Notify(ctx context.Context, r Reminder) error
+}
+
+// LogNotifier writes reminders to the server's log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	slog.InfoContext(ctx, "Reminder: "+r.Subject(), slog.String("todo_id", r.TodoId), slog.String("reminder", r.Rule.String()))
+	return nil
+}
+
+// WebhookNotifier sends reminders as todo.reminder webhook events.
+type WebhookNotifier struct {
+	Dispatcher *webhooks.Dispatcher
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, r Reminder) error {
+	n.Dispatcher.Remind(r.TodoId, r.Todo, r.Rule.String())
+	return nil
+}
+
+// MailDropNotifier writes each reminder as an RFC 5322 message file into
+// Dir, for a local mail system or anything else watching the directory.
+type MailDropNotifier struct {
+	Dir  string
+	From string
+	To   string
+}
+
+func (n MailDropNotifier) Notify(ctx context.Context, r Reminder) error {
+	if err := os.MkdirAll(n.Dir, 0o755); err != nil {
+		return fmt.Errorf("problem creating mail drop directory, %v", err)
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%d.%s.%s", now.UnixNano(), r.TodoId, strings.ReplaceAll(r.Rule.String(), ":", "-"))
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", n.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", r.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@todo-app>\r\n", id)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(r.Body(), "\n", "\r\n"))
+
+	// Write then rename so watchers never see half a message
+	path := filepath.Join(n.Dir, id+".eml")
+	if err := os.WriteFile(path+".tmp", []byte(msg.String()), 0o644); err != nil {
+		return fmt.Errorf("problem writing reminder mail, %v", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// CommandNotifier runs a command for each reminder with the subject and body
+// as its last two arguments, such as notify-send for desktop notifications.
+type CommandNotifier struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+func (n CommandNotifier) Notify(ctx context.Context, r Reminder) error {
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+
+	args := append(append([]string{}, n.Args...), r.Subject(), r.Body())
+	cmd := exec.CommandContext(ctx, n.Command, args...)
+	cmd.Env = append(os.Environ(), "TODO_ID="+r.TodoId, "TODO_REMINDER="+r.Rule.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("problem running %s, %v: %s", n.Command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package reminders
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	DueSoon Kind = "before"
+	Due     Kind = "due"
+	Overdue Kind = "overdue"
+)
+
+// DefaultRules reminds a day before, at the due time and daily while overdue.
+const DefaultRules = "before:1d,due,overdue:1d"
+
+// DueGrace is how late an "at due time" reminder can still be sent, for
+// example after the server was down when it was due.
+const DueGrace = 6 * time.Hour
+
+// Rule is one reminder offset. Written as "before:<duration>" for a single
+// reminder ahead of the due time, "due" for one at the due time, and
+// "overdue:<interval>" to repeat every interval while the todo is overdue.
+// Durations are Go durations or a number of days such as "2d".
+type Rule struct {
+	Kind   Kind
+	Offset time.Duration
+}
+
+func ParseRule(s string) (Rule, error) {
+	kind, value, hasValue := strings.Cut(strings.TrimSpace(s), ":")
+	r := Rule{Kind: Kind(kind)}
+
+	switch r.Kind {
+	case Due:
+		if hasValue {
+			return Rule{}, fmt.Errorf("reminder %q doesn't take a duration", s)
+		}
+		return r, nil
+	case DueSoon, Overdue:
+		if !hasValue {
+			return Rule{}, fmt.Errorf("reminder %q needs a duration, such as %s:1d", s, kind)
+		}
+		d, err := parseDuration(value)
+		if err != nil || d <= 0 {
+			return Rule{}, fmt.Errorf("reminder %q has an invalid duration", s)
+		}
+		r.Offset = d
+		return r, nil
+	default:
+		return Rule{}, fmt.Errorf("unknown reminder %q, expected before:<duration>, due or overdue:<interval>", s)
+	}
+}
+
+// ParseRules parses a comma separated list of rules.
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for part := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		r, err := ParseRule(part)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r Rule) String() string {
+	if r.Kind == Due {
+		return string(Due)
+	}
+	return string(r.Kind) + ":" + formatDuration(r.Offset)
+}
+
+// Occurrence is when the rule's most recent reminder for a todo due at due
+// was meant to fire, if there is one that should still be sent at now.
+func (r Rule) Occurrence(due, now time.Time) (time.Time, bool) {
+	switch r.Kind {
+	case DueSoon:
+		at := due.Add(-r.Offset)
+		return at, !now.Before(at) && now.Before(due)
+	case Due:
+		return due, !now.Before(due) && now.Before(due.Add(DueGrace))
+	case Overdue:
+		if now.Before(due.Add(r.Offset)) {
+			return time.Time{}, false
+		}
+		n := now.Sub(due) / r.Offset
+		return due.Add(n * r.Offset), true
+	}
+	return time.Time{}, false
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func formatDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
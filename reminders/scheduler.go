@@ -0,0 +1,147 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+const DefaultInterval = time.Minute
+
+// sent records the latest reminder sent for a todo and rule. The due date is
+// kept so that moving it starts the reminders again.
+type sent struct {
+	Due    time.Time `json:"due"`
+	FireAt time.Time `json:"fire_at"`
+}
+
+// Scheduler scans the todos' due dates every Interval and sends whichever
+// reminders have come due through its notifiers. What has been sent is saved
+// so that a restart doesn't send everything again.
+type Scheduler struct {
+	Interval time.Duration
+
+	rules     []Rule
+	notifiers []Notifier
+	path      string
+	sent      map[string]sent
+}
+
+// NewScheduler loads what was already sent from path. An empty path keeps
+// it in memory.
+func NewScheduler(rules []Rule, notifiers []Notifier, path string) (*Scheduler, error) {
+	s := &Scheduler{
+		Interval:  DefaultInterval,
+		rules:     rules,
+		notifiers: notifiers,
+		path:      path,
+		sent:      map[string]sent{},
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem reading reminders file %s, %v", path, err)
+	}
+	if err := json.Unmarshal(data, &s.sent); err != nil {
+		return nil, fmt.Errorf("problem parsing reminders file %s, %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *Scheduler) Run(ctx context.Context, actor *stores.TodoStoreActor) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		resp := make(chan types.GetAllTodosResponse, 1)
+		actor.Send(types.GetAllTodosRequest{Ctx: ctx, Resp: resp})
+		select {
+		case res := <-resp:
+			s.Check(ctx, res.Todos, time.Now())
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check sends the reminders due at now for the open todos given.
+func (s *Scheduler) Check(ctx context.Context, todos map[string]types.Todo, now time.Time) {
+	changed := false
+	for id, todo := range todos {
+		if todo.Due == nil || todo.Status == types.Completed {
+			continue
+		}
+		for _, rule := range s.rules {
+			at, ok := rule.Occurrence(*todo.Due, now)
+			if !ok {
+				continue
+			}
+			key := id + "/" + rule.String()
+			if prev, ok := s.sent[key]; ok && prev.Due.Equal(*todo.Due) && !prev.FireAt.Before(at) {
+				continue
+			}
+
+			if s.notify(ctx, Reminder{TodoId: id, Todo: todo, Rule: rule, FireAt: at}) {
+				s.sent[key] = sent{Due: *todo.Due, FireAt: at}
+				changed = true
+			}
+		}
+	}
+
+	// Forget todos that were completed or removed
+	for key := range s.sent {
+		id, _, _ := strings.Cut(key, "/")
+		if _, ok := todos[id]; !ok {
+			delete(s.sent, key)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.save(); err != nil {
+			slog.ErrorContext(ctx, "Failed to save sent reminders", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// notify sends r through every notifier. It counts as sent if any of them
+// succeeded, otherwise it's tried again on the next check.
+func (s *Scheduler) notify(ctx context.Context, r Reminder) bool {
+	delivered := false
+	for _, n := range s.notifiers {
+		if err := n.Notify(ctx, r); err != nil {
+			slog.ErrorContext(ctx, "Failed to send reminder", slog.String("todo_id", r.TodoId), slog.String("reminder", r.Rule.String()), slog.String("error", err.Error()))
+			continue
+		}
+		delivered = true
+	}
+	return delivered
+}
+
+func (s *Scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+	return atomicfile.WriteJSON(s.path, s.sent)
+}
@@ -0,0 +1,128 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestScheduler(t *testing.T) {
+	rules, _ := ParseRules(DefaultRules)
+	due := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	todos := map[string]types.Todo{"1": types.NewTodo("Renew the VPN certificate", &due)}
+
+	t.Run("Fires each reminder once as the due date approaches and passes", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		s, _ := NewScheduler(rules, []Notifier{notifier}, "")
+
+		checks := []struct {
+			at   time.Time
+			want string
+		}{
+			{due.Add(-48 * time.Hour), ""},
+			{due.Add(-23 * time.Hour), "before:1d"},
+			{due.Add(-22 * time.Hour), ""},
+			{due.Add(time.Minute), "due"},
+			{due.Add(time.Hour), ""},
+			{due.Add(25 * time.Hour), "overdue:1d"},
+			{due.Add(26 * time.Hour), ""},
+			{due.Add(49 * time.Hour), "overdue:1d"},
+		}
+		for _, c := range checks {
+			notifier.sent = nil
+			s.Check(context.Background(), todos, c.at)
+			if got := notifier.rules(); got != c.want {
+				t.Errorf("at due%+v got reminders %q want %q", c.at.Sub(due), got, c.want)
+			}
+		}
+	})
+
+	t.Run("Remembers what it sent across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reminders.json")
+		notifier := &recordingNotifier{}
+		s, _ := NewScheduler(rules, []Notifier{notifier}, path)
+		s.Check(context.Background(), todos, due.Add(time.Minute))
+
+		s, err := NewScheduler(rules, []Notifier{notifier}, path)
+		if err != nil {
+			t.Fatalf("unexpected error loading: %v", err)
+		}
+		s.Check(context.Background(), todos, due.Add(2*time.Minute))
+		if len(notifier.sent) != 1 {
+			t.Errorf("got %d reminders want 1", len(notifier.sent))
+		}
+	})
+
+	t.Run("Retries reminders that no notifier could send", func(t *testing.T) {
+		notifier := &recordingNotifier{err: errors.New("no route to host")}
+		s, _ := NewScheduler(rules, []Notifier{notifier}, "")
+		s.Check(context.Background(), todos, due.Add(time.Minute))
+
+		notifier.err = nil
+		s.Check(context.Background(), todos, due.Add(2*time.Minute))
+		if got := notifier.rules(); got != "due" {
+			t.Errorf("got reminders %q want the due reminder sent", got)
+		}
+	})
+
+	t.Run("Writes reminders to the mail drop", func(t *testing.T) {
+		dir := t.TempDir()
+		n := MailDropNotifier{Dir: dir, From: "todo@localhost", To: "me@localhost"}
+		rule, _ := ParseRule("before:1d")
+		if err := n.Notify(context.Background(), Reminder{TodoId: "1", Todo: todos["1"], Rule: rule}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) != 1 {
+			t.Fatalf("got %d messages want 1", len(files))
+		}
+		msg, _ := os.ReadFile(files[0])
+		if !strings.Contains(string(msg), "Subject: \"Renew the VPN certificate\" is due in 1d\r\n") {
+			t.Errorf("got message %q without the expected subject", msg)
+		}
+	})
+}
+
+func TestParseRule(t *testing.T) {
+	for _, s := range []string{"before:1d", "before:1h30m0s", "due", "overdue:12h0m0s"} {
+		r, err := ParseRule(s)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", s, err)
+		} else if r.String() != s {
+			t.Errorf("got %q back from parsing %q", r.String(), s)
+		}
+	}
+	for _, s := range []string{"before", "due:1h", "overdue:-1d", "after:1d"} {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
+
+type recordingNotifier struct {
+	sent []Reminder
+	err  error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, r Reminder) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, r)
+	return nil
+}
+
+func (n *recordingNotifier) rules() string {
+	var rules []string
+	for _, r := range n.sent {
+		rules = append(rules, r.Rule.String())
+	}
+	return strings.Join(rules, ",")
+}
@@ -0,0 +1,46 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxBodySize limits request bodies, other than bulk requests which are
+	// limited to MaxBulkBodySize.
+	MaxBodySize     = 64 << 10
+	MaxBulkBodySize = 4 << 20
+)
+
+// decodeJSON reads a JSON body of at most limit bytes into v, rejecting
+// fields v doesn't have and anything after the value. If it can't, it
+// responds with a problem and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any, limit int64) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errors.New("unexpected data after the JSON value")
+		}
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		problem(w, r, http.StatusRequestEntityTooLarge, ProblemTooLarge, fmt.Sprintf("the body must be at most %d bytes", limit))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		invalid(w, r, field, "unknown field")
+	default:
+		badRequest(w, r, err)
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package todoapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestValidatingTodoRequests(t *testing.T) {
+	store := &StubTodoStore{todos: map[string]types.Todo{}}
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/todos/", strings.NewReader(body)))
+		return response
+	}
+
+	cases := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"An empty description is rejected", `{"description": ""}`, "description"},
+		{"Unknown fields are rejected", `{"description": "Walk the dog", "colour": "red"}`, "colour"},
+		{"Arbitrary statuses are rejected", `{"description": "Walk the dog", "status": "Sleeping"}`, "status"},
+		{"Clients can't set when it was updated", `{"description": "Walk the dog", "updated": "2026-10-01T00:00:00Z"}`, "updated"},
+		{"Due dates must be sensible", `{"description": "Walk the dog", "due": "0026-10-01T00:00:00Z"}`, "due"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := assertProblem(t, post(c.body), http.StatusBadRequest, ProblemValidation)
+			if len(got.Errors) != 1 || got.Errors[0].Field != c.field {
+				t.Errorf("got errors %+v want one for %s", got.Errors, c.field)
+			}
+		})
+	}
+	if len(store.addCalls) != 0 {
+		t.Errorf("got %d calls to AddTodo want 0, invalid todos shouldn't reach the store", len(store.addCalls))
+	}
+
+	t.Run("Data after the todo is rejected", func(t *testing.T) {
+		assertProblem(t, post(`{"description": "Walk the dog"} {}`), http.StatusBadRequest, ProblemBadRequest)
+	})
+
+	t.Run("Bodies over the limit are rejected", func(t *testing.T) {
+		body := `{"description": "` + strings.Repeat("a", MaxBodySize) + `"}`
+		assertProblem(t, post(body), http.StatusRequestEntityTooLarge, ProblemTooLarge)
+	})
+
+	t.Run("A valid todo is created with the server's fields set", func(t *testing.T) {
+		response := post(`{"description": "Walk the dog", "status": "started", "tags": ["pets"]}`)
+		assertStatus(t, response.Code, http.StatusCreated)
+		got := store.addCalls[len(store.addCalls)-1]
+		if got.Status != types.Started || got.Created.IsZero() || got.Version != 1 {
+			t.Errorf("got %+v want a started todo at version 1", got)
+		}
+	})
+
+	t.Run("Unknown fields in a status update are rejected", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodPut, "/api/todos/1", strings.NewReader(`{"status": "Completed", "due": null}`)))
+		assertProblem(t, response, http.StatusBadRequest, ProblemValidation)
+	})
+}
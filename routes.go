@@ -0,0 +1,162 @@
+package todoapp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIPrefix is where the API is served. Each version is served under it, at
+// APIVersion.Prefix, and v1 at APIPrefix itself too. The base URLs given to
+// NewTodoAPIClient are APIPrefix on a server.
+const APIPrefix = "/api"
+
+type APIVersion string
+
+const (
+	// APIv1 is deprecated, see V1Deprecated and V1Sunset
+	APIv1 APIVersion = "v1"
+	// APIv2 always represents todos with their id, and a few derived
+	// fields, and responds to a status update with the changed todo
+	APIv2 APIVersion = "v2"
+)
+
+// APIVersions is every version the server serves.
+var APIVersions = []APIVersion{APIv1, APIv2}
+
+// Route is one operation in the API. The server registers its handlers from
+// Routes and TodoAPIClient builds its requests from the same values, so the
+// two can't disagree about paths, methods or status codes.
+type Route struct {
+	Name   string
+	Method string
+	// Path is a http.ServeMux pattern path relative to the version's prefix
+	Path string
+	// Status is the code of a successful response
+	Status int
+}
+
+var (
+	RouteListTodos        = Route{"ListTodos", http.MethodGet, "/todos/{$}", http.StatusOK}
+	RouteAddTodo          = Route{"AddTodo", http.MethodPost, "/todos/{$}", http.StatusCreated}
+	RouteGetTodo          = Route{"GetTodo", http.MethodGet, "/todos/{id}", http.StatusOK}
+	RouteUpdateTodoStatus = Route{"UpdateTodoStatus", http.MethodPut, "/todos/{id}", http.StatusAccepted}
+	RouteBulkTodos        = Route{"BulkTodos", http.MethodPost, "/todos/bulk", http.StatusOK}
+	RouteGetStats         = Route{"GetStats", http.MethodGet, "/stats", http.StatusOK}
+	RouteSearchTodos      = Route{"SearchTodos", http.MethodGet, "/search", http.StatusOK}
+	RouteStreamEvents     = Route{"StreamEvents", http.MethodGet, "/events", http.StatusOK}
+	RouteWebSocket        = Route{"WebSocket", http.MethodGet, "/ws", http.StatusSwitchingProtocols}
+
+	RouteListWebhooks          = Route{"ListWebhooks", http.MethodGet, "/webhooks", http.StatusOK}
+	RouteAddWebhook            = Route{"AddWebhook", http.MethodPost, "/webhooks", http.StatusCreated}
+	RouteGetWebhook            = Route{"GetWebhook", http.MethodGet, "/webhooks/{id}", http.StatusOK}
+	RouteDeleteWebhook         = Route{"DeleteWebhook", http.MethodDelete, "/webhooks/{id}", http.StatusNoContent}
+	RouteGetWebhookDeliveries  = Route{"GetWebhookDeliveries", http.MethodGet, "/webhooks/{id}/deliveries", http.StatusOK}
+	RouteGetWebhookDeadLetters = Route{"GetWebhookDeadLetters", http.MethodGet, "/webhooks/dead-letters", http.StatusOK}
+	RouteRedeliverWebhook      = Route{"RedeliverWebhook", http.MethodPost, "/webhooks/dead-letters/{id}/redeliver", http.StatusAccepted}
+
+	RouteListViews    = Route{"ListViews", http.MethodGet, "/views", http.StatusOK}
+	RouteAddView      = Route{"AddView", http.MethodPost, "/views", http.StatusCreated}
+	RouteGetView      = Route{"GetView", http.MethodGet, "/views/{name}", http.StatusOK}
+	RoutePutView      = Route{"PutView", http.MethodPut, "/views/{name}", http.StatusOK}
+	RouteDeleteView   = Route{"DeleteView", http.MethodDelete, "/views/{name}", http.StatusNoContent}
+	RouteGetViewTodos = Route{"GetViewTodos", http.MethodGet, "/views/{name}/todos", http.StatusOK}
+
+	RouteGetTodoMembers   = Route{"GetTodoMembers", http.MethodGet, "/todos/{id}/members", http.StatusOK}
+	RoutePutTodoMember    = Route{"PutTodoMember", http.MethodPut, "/todos/{id}/members/{user}", http.StatusOK}
+	RouteDeleteTodoMember = Route{"DeleteTodoMember", http.MethodDelete, "/todos/{id}/members/{user}", http.StatusNoContent}
+	RouteGetListMembers   = Route{"GetListMembers", http.MethodGet, "/lists/{list}/members", http.StatusOK}
+	RoutePutListMember    = Route{"PutListMember", http.MethodPut, "/lists/{list}/members/{user}", http.StatusOK}
+	RouteDeleteListMember = Route{"DeleteListMember", http.MethodDelete, "/lists/{list}/members/{user}", http.StatusNoContent}
+
+	RouteListTokens  = Route{"ListTokens", http.MethodGet, "/tokens", http.StatusOK}
+	RouteAddToken    = Route{"AddToken", http.MethodPost, "/tokens", http.StatusCreated}
+	RouteDeleteToken = Route{"DeleteToken", http.MethodDelete, "/tokens/{id}", http.StatusNoContent}
+
+	RouteGetOpenAPI = Route{"GetOpenAPI", http.MethodGet, "/openapi.json", http.StatusOK}
+	RouteGetDocs    = Route{"GetDocs", http.MethodGet, "/docs/{file...}", http.StatusOK}
+
+	RouteV2UpdateTodoStatus = Route{"UpdateTodoStatus", http.MethodPut, "/todos/{id}", http.StatusOK}
+)
+
+// Routes is every operation in v1 of the API. Each one must be described in
+// openapi/openapi.json too, which TestOpenAPIMatchesRoutes checks.
+var Routes = []Route{
+	RouteListTodos, RouteAddTodo, RouteGetTodo, RouteUpdateTodoStatus, RouteBulkTodos,
+	RouteGetStats, RouteSearchTodos, RouteStreamEvents, RouteWebSocket,
+	RouteListWebhooks, RouteAddWebhook, RouteGetWebhook, RouteDeleteWebhook,
+	RouteGetWebhookDeliveries, RouteGetWebhookDeadLetters, RouteRedeliverWebhook,
+	RouteListViews, RouteAddView, RouteGetView, RoutePutView, RouteDeleteView, RouteGetViewTodos,
+	RouteGetTodoMembers, RoutePutTodoMember, RouteDeleteTodoMember,
+	RouteGetListMembers, RoutePutListMember, RouteDeleteListMember,
+	RouteListTokens, RouteAddToken, RouteDeleteToken,
+	RouteGetOpenAPI, RouteGetDocs,
+}
+
+// RoutesV2 is every operation in v2 of the API. Each one must be described
+// in openapi/openapi-v2.json.
+var RoutesV2 = []Route{
+	RouteListTodos, RouteAddTodo, RouteGetTodo, RouteV2UpdateTodoStatus, RouteBulkTodos,
+	RouteGetStats, RouteSearchTodos, RouteStreamEvents, RouteWebSocket,
+	RouteListWebhooks, RouteAddWebhook, RouteGetWebhook, RouteDeleteWebhook,
+	RouteGetWebhookDeliveries, RouteGetWebhookDeadLetters, RouteRedeliverWebhook,
+	RouteListViews, RouteAddView, RouteGetView, RoutePutView, RouteDeleteView, RouteGetViewTodos,
+	RouteGetTodoMembers, RoutePutTodoMember, RouteDeleteTodoMember,
+	RouteGetListMembers, RoutePutListMember, RouteDeleteListMember,
+	RouteListTokens, RouteAddToken, RouteDeleteToken,
+	RouteGetOpenAPI, RouteGetDocs,
+}
+
+// Prefix is where the version is served, such as /api/v2.
+func (v APIVersion) Prefix() string {
+	return APIPrefix + "/" + string(v)
+}
+
+// Routes is every operation in the version.
+func (v APIVersion) Routes() []Route {
+	if v == APIv2 {
+		return RoutesV2
+	}
+	return Routes
+}
+
+// Route is the version's form of r, which has the same name.
+func (v APIVersion) Route(r Route) Route {
+	for _, route := range v.Routes() {
+		if route.Name == r.Name {
+			return route
+		}
+	}
+	return r
+}
+
+// Pattern is the route's pattern for http.ServeMux, under prefix.
+func (r Route) Pattern(prefix string) string {
+	return r.Method + " " + prefix + r.Path
+}
+
+// URL is the route under base with its wildcards replaced by params in
+// order, escaped, and query appended if it isn't empty.
+func (r Route) URL(base string, query url.Values, params ...string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(base, "/"))
+	path := strings.ReplaceAll(r.Path, "{$}", "")
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(path, "}")
+		b.WriteString(path[:start])
+		if len(params) > 0 {
+			b.WriteString(url.PathEscape(params[0]))
+			params = params[1:]
+		}
+		path = path[end+1:]
+	}
+	b.WriteString(path)
+	if len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	return b.String()
+}
@@ -0,0 +1,290 @@
+package search
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	// SnippetLength is roughly how many bytes of a description are shown.
+	SnippetLength = 120
+
+	// A query word matching the start of a longer term counts for less
+	// than matching it exactly.
+	prefixWeight = 0.5
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+type word struct {
+	text       string
+	start, end int
+}
+
+// words splits text into lower case words of letters and digits, keeping
+// where each one was.
+func words(text string) []word {
+	var result []word
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			result = append(result, word{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return result
+}
+
+// Terms is how text is indexed: its words without stop words, stemmed.
+func Terms(text string) []string {
+	var terms []string
+	for _, w := range words(text) {
+		if !stopWords[w.text] {
+			terms = append(terms, Stem(w.text))
+		}
+	}
+	return terms
+}
+
+type document struct {
+	todo   types.Todo
+	terms  map[string]int
+	length int
+}
+
+// Index is an inverted index over todos' descriptions, tags and lists.
+type Index struct {
+	lock     sync.RWMutex
+	docs     map[string]*document
+	postings map[string]map[string]int
+	// Every indexed term in order, for prefix matching
+	terms []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[string]*document{},
+		postings: map[string]map[string]int{},
+	}
+}
+
+// Put indexes todo under id, replacing what was indexed for it before.
+func (ix *Index) Put(id string, todo types.Todo) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+
+	ix.removeLocked(id)
+
+	text := todo.Description + " " + strings.Join(todo.Tags, " ") + " " + todo.List
+	doc := &document{todo: todo, terms: map[string]int{}}
+	for _, term := range Terms(text) {
+		doc.terms[term]++
+		doc.length++
+	}
+	for term, tf := range doc.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string]int{}
+			i, _ := slices.BinarySearch(ix.terms, term)
+			ix.terms = slices.Insert(ix.terms, i, term)
+		}
+		ix.postings[term][id] = tf
+	}
+	ix.docs[id] = doc
+}
+
+func (ix *Index) Remove(id string) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	ix.removeLocked(id)
+}
+
+func (ix *Index) removeLocked(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+			if i, found := slices.BinarySearch(ix.terms, term); found {
+				ix.terms = slices.Delete(ix.terms, i, i+1)
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) Len() int {
+	ix.lock.RLock()
+	defer ix.lock.RUnlock()
+	return len(ix.docs)
+}
+
+// Search returns up to limit todos containing every word in query, best
+// first. Words match exactly after stemming, or as the start of a longer
+// word so results come back while a word is still being typed.
+func (ix *Index) Search(query string, limit int) []types.SearchResult {
+	ix.lock.RLock()
+	defer ix.lock.RUnlock()
+
+	var queryWords []word
+	for _, w := range words(query) {
+		if !stopWords[w.text] {
+			queryWords = append(queryWords, w)
+		}
+	}
+	if len(queryWords) == 0 {
+		return []types.SearchResult{}
+	}
+
+	scores := map[string]float64{}
+	matched := map[string]map[string]bool{}
+	for i, w := range queryWords {
+		wordScores := map[string]float64{}
+		for term, weight := range ix.matchingTerms(w.text) {
+			idf := math.Log(1 + float64(len(ix.docs))/float64(len(ix.postings[term])))
+			for id, tf := range ix.postings[term] {
+				wordScores[id] += weight * float64(tf) * idf
+				if matched[id] == nil {
+					matched[id] = map[string]bool{}
+				}
+				matched[id][term] = true
+			}
+		}
+
+		// Every word has to match
+		if i == 0 {
+			scores = wordScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := wordScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := []types.SearchResult{}
+	for id, score := range scores {
+		doc := ix.docs[id]
+		snippet, highlights := makeSnippet(doc.todo.Description, matched[id])
+		results = append(results, types.SearchResult{
+			Id:         id,
+			Todo:       doc.todo,
+			Score:      score / math.Sqrt(float64(max(doc.length, 1))),
+			Snippet:    snippet,
+			Highlights: highlights,
+		})
+	}
+	slices.SortFunc(results, func(a, b types.SearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Id, b.Id)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchingTerms finds the indexed terms a query word matches and how much
+// each one counts.
+func (ix *Index) matchingTerms(text string) map[string]float64 {
+	stem := Stem(text)
+	found := map[string]float64{}
+	if _, ok := ix.postings[stem]; ok {
+		found[stem] = 1
+	}
+
+	prefixes := []string{stem}
+	if !strings.HasPrefix(text, stem) {
+		// e.g. "daily" stems to "daili"
+		prefixes = append(prefixes, text)
+	}
+	for _, prefix := range prefixes {
+		i, _ := slices.BinarySearch(ix.terms, prefix)
+		for ; i < len(ix.terms) && strings.HasPrefix(ix.terms[i], prefix); i++ {
+			if _, ok := found[ix.terms[i]]; !ok {
+				found[ix.terms[i]] = prefixWeight
+			}
+		}
+	}
+	return found
+}
+
+// makeSnippet cuts long descriptions down to the part around the first
+// match, and finds the words in it that matched.
+func makeSnippet(text string, terms map[string]bool) (string, []types.TextRange) {
+	var highlights []types.TextRange
+	for _, w := range words(text) {
+		if terms[Stem(w.text)] {
+			highlights = append(highlights, types.TextRange{Start: w.start, End: w.end})
+		}
+	}
+	if len(text) <= SnippetLength {
+		return text, highlights
+	}
+
+	start := 0
+	if len(highlights) > 0 {
+		start = max(0, highlights[0].Start-SnippetLength/3)
+	}
+	end := min(len(text), start+SnippetLength)
+	start = max(0, end-SnippetLength)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+
+	var kept []types.TextRange
+	for _, h := range highlights {
+		if h.Start >= start && h.End <= end {
+			offset := len(prefix) - start
+			kept = append(kept, types.TextRange{Start: h.Start + offset, End: h.End + offset})
+		}
+	}
+	return prefix + text[start:end] + suffix, kept
+}
+
+// SearchTodos indexes todos just for this search, for clients that don't
+// have an index to hand.
+func SearchTodos(todos map[string]types.Todo, query string, limit int) []types.SearchResult {
+	ix := NewIndex()
+	for id, t := range todos {
+		ix.Put(id, t)
+	}
+	return ix.Search(query, limit)
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"testing"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		"connected":   "connect",
+		"connecting":  "connect",
+		"connections": "connect",
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"hopping":     "hop",
+		"relational":  "relat",
+		"generalize":  "gener",
+		"vpn":         "vpn",
+		"2fa":         "2fa",
+	}
+	for word, want := range cases {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q): got %q want %q", word, got, want)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ix := NewIndex()
+	ix.Put("vpn", types.NewTodo("Renew the VPN certificate before it expires", nil))
+	ix.Put("printer", types.NewTodo("Connect the printer", nil))
+	ix.Put("certs", types.NewTodo("Certificates, certificates, certificates", nil))
+	tagged := types.NewTodo("Book flights", nil)
+	tagged.Tags = []string{"travel"}
+	ix.Put("flights", tagged)
+
+	ids := func(results []types.SearchResult) []string {
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.Id)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"certificate", []string{"certs", "vpn"}},
+		{"renew certificates", []string{"vpn"}},
+		{"connecting", []string{"printer"}},
+		{"vp", []string{"vpn"}},
+		{"cert exp", []string{"vpn"}},
+		{"travel", []string{"flights"}},
+		{"the", nil},
+		{"boat", nil},
+	}
+	for _, c := range cases {
+		if got := ids(ix.Search(c.query, 0)); !equal(got, c.want) {
+			t.Errorf("%q: got %v want %v", c.query, got, c.want)
+		}
+	}
+
+	t.Run("Highlights the matching words", func(t *testing.T) {
+		results := ix.Search("renewing vpn", 0)
+		var got []string
+		for _, h := range results[0].Highlights {
+			got = append(got, results[0].Snippet[h.Start:h.End])
+		}
+		if !equal(got, []string{"Renew", "VPN"}) {
+			t.Errorf("got %v want Renew and VPN", got)
+		}
+	})
+
+	t.Run("Updates and removals replace what was indexed", func(t *testing.T) {
+		ix.Put("printer", types.NewTodo("Fix the scanner", nil))
+		ix.Remove("vpn")
+		if got := ids(ix.Search("printer", 0)); len(got) != 0 {
+			t.Errorf("got %v want no results", got)
+		}
+		if got := ids(ix.Search("certificate", 0)); !equal(got, []string{"certs"}) {
+			t.Errorf("got %v want only certs", got)
+		}
+		if ix.Len() != 3 {
+			t.Errorf("got %d documents want 3", ix.Len())
+		}
+	})
+}
+
+func TestSnippet(t *testing.T) {
+	text := "Long preamble that goes on and on about nothing in particular at all, really, " +
+		"before finally mentioning the invoice that needs sending and then carrying on for a while longer still."
+	snippet, highlights := makeSnippet(text, map[string]bool{Stem("invoice"): true})
+	if len(highlights) != 1 || snippet[highlights[0].Start:highlights[0].End] != "invoice" {
+		t.Fatalf("got %q with highlights %v want invoice highlighted", snippet, highlights)
+	}
+	if len(snippet) > SnippetLength+2*len("…") {
+		t.Errorf("got a %d byte snippet want at most %d", len(snippet), SnippetLength)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
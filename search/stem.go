@@ -0,0 +1,209 @@
+package search
+
+import "strings"
+
+// Stem reduces an English word to its stem using the Porter algorithm, so
+// "connected", "connecting" and "connections" all index as "connect". The
+// word must already be lower case.
+func Stem(word string) string {
+	if len(word) <= 2 || !isASCIILetters(word) {
+		return word
+	}
+	w := step1a(word)
+	w = step1b(w)
+	w = step1c(w)
+	w = replaceSuffix(w, step2, 0)
+	w = replaceSuffix(w, step3, 0)
+	w = step4(w)
+	w = step5(w)
+	return w
+}
+
+func isASCIILetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isConsonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w, m in [C](VC)^m[V].
+func measure(w string) int {
+	m := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func hasVowel(w string) bool {
+	for i := range len(w) {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC is true when w ends consonant-vowel-consonant and the last
+// consonant isn't w, x or y, as in "hop" but not "snow".
+func endsCVC(w string) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	c := w[n-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func step1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func step1b(w string) string {
+	if stem, ok := strings.CutSuffix(w, "eed"); ok {
+		if measure(stem) > 0 {
+			return stem + "ee"
+		}
+		return w
+	}
+
+	stem, ok := strings.CutSuffix(w, "ed")
+	if !ok {
+		stem, ok = strings.CutSuffix(w, "ing")
+	}
+	if !ok || !hasVowel(stem) {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case endsDoubleConsonant(stem):
+		if last := stem[len(stem)-1]; last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func step1c(w string) string {
+	if stem, ok := strings.CutSuffix(w, "y"); ok && hasVowel(stem) {
+		return stem + "i"
+	}
+	return w
+}
+
+type suffixRule struct{ suffix, replacement string }
+
+// Longer suffixes come first where one ends another.
+var step2 = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3 = []suffixRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+// replaceSuffix applies the rule for the longest matching suffix if the
+// remaining stem's measure is more than minMeasure.
+func replaceSuffix(w string, rules []suffixRule, minMeasure int) string {
+	best := -1
+	for i, r := range rules {
+		if strings.HasSuffix(w, r.suffix) && (best < 0 || len(r.suffix) > len(rules[best].suffix)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(rules[best].suffix)]
+	if measure(stem) > minMeasure {
+		return stem + rules[best].replacement
+	}
+	return w
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(w string) string {
+	longest := ""
+	for _, s := range step4Suffixes {
+		if strings.HasSuffix(w, s) && len(s) > len(longest) {
+			longest = s
+		}
+	}
+	if longest == "" {
+		return w
+	}
+	stem := w[:len(w)-len(longest)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if longest == "ion" && !strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "t") {
+		return w
+	}
+	return stem
+}
+
+func step5(w string) string {
+	if stem, ok := strings.CutSuffix(w, "e"); ok {
+		if m := measure(stem); m > 1 || m == 1 && !endsCVC(stem) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && strings.HasSuffix(w, "ll") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
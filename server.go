@@ -1,39 +1,198 @@
 package todoapp
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"log/slog"
+	"maps"
 	"net/http"
-	"strings"
-	"text/template"
+	"time"
 
 	"github.com/google/uuid"
+	"grantjames.github.io/todo-app/ratelimit"
+	"grantjames.github.io/todo-app/reminders"
+	"grantjames.github.io/todo-app/sessions"
+	"grantjames.github.io/todo-app/sharing"
 	"grantjames.github.io/todo-app/stores"
 	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+	"grantjames.github.io/todo-app/views"
+	"grantjames.github.io/todo-app/webhooks"
 )
 
 type TraceIdKey struct{}
 
+// UserKey is the context key for the users.User a request was authenticated
+// as, when the server has users.
+type UserKey struct{}
+
 type TodoServer struct {
-	actor *stores.TodoStoreActor
+	actor     *stores.TodoStoreActor
+	heartbeat time.Duration
+	webhooks  *webhooks.Dispatcher
+	reminders *reminders.Scheduler
+	views     *views.Store
+	// Who can use the API, or nil if anyone can
+	users *users.Store
+	// Who users have shared their lists with
+	sharing *sharing.Store
+	// Responses to requests with an Idempotency-Key
+	idempotency *idempotencyCache
+	// Browsers using the web pages
+	sessions      *sessions.Store
+	secureCookies bool
+	// How often each client can read and write, or nil for no limit
+	readLimiter  *ratelimit.Limiter
+	writeLimiter *ratelimit.Limiter
+	// router has every route, behind the rate limits in Handler
+	router *http.ServeMux
 	http.Handler
 }
 
-func NewTodoServer(actor *stores.TodoStoreActor) *TodoServer {
+type ServerOption func(*TodoServer)
+
+// WithWebhooks has the server deliver webhooks through d, for example one
+// that persists its subscriptions. Otherwise they're only kept in memory.
+func WithWebhooks(d *webhooks.Dispatcher) ServerOption {
+	return func(s *TodoServer) {
+		s.webhooks = d
+	}
+}
+
+// WithReminders runs the reminder scheduler alongside the server.
+func WithReminders(r *reminders.Scheduler) ServerOption {
+	return func(s *TodoServer) {
+		s.reminders = r
+	}
+}
+
+// WithViews keeps saved views in v, for example one that persists them.
+// Otherwise they're only kept in memory.
+func WithViews(v *views.Store) ServerOption {
+	return func(s *TodoServer) {
+		s.views = v
+	}
+}
+
+// WithUsers has every request but those for the API's documentation
+// authenticate with one of u's tokens, and only see that user's todos and
+// those shared with them. Otherwise anyone can use the API and see every
+// todo.
+func WithUsers(u *users.Store) ServerOption {
+	return func(s *TodoServer) {
+		s.users = u
+	}
+}
+
+// WithSharing keeps who lists are shared with in sh, for example one that
+// persists them. Otherwise they're only kept in memory.
+func WithSharing(sh *sharing.Store) ServerOption {
+	return func(s *TodoServer) {
+		s.sharing = sh
+	}
+}
+
+// WithSecureCookies only has browsers send the session cookie over HTTPS,
+// for a server behind a proxy that serves it. It always is when the server
+// serves HTTPS itself.
+func WithSecureCookies() ServerOption {
+	return func(s *TodoServer) {
+		s.secureCookies = true
+	}
+}
+
+// WithRateLimits limits how often each client, by API token or address, can
+// make reads (GET and HEAD requests) and writes. A zero ratelimit.Limit is
+// no limit, which is the default.
+func WithRateLimits(reads, writes ratelimit.Limit) ServerOption {
+	return func(s *TodoServer) {
+		s.readLimiter = newLimiter(reads)
+		s.writeLimiter = newLimiter(writes)
+	}
+}
+
+func NewTodoServer(actor *stores.TodoStoreActor, opts ...ServerOption) *TodoServer {
 	s := new(TodoServer)
 
 	s.actor = actor
+	s.heartbeat = DefaultEventsHeartbeat
+	s.idempotency = newIdempotencyCache(DefaultIdempotencyRetention)
+	s.sessions = sessions.NewStore(sessions.DefaultLifetime)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.webhooks == nil {
+		s.webhooks, _ = webhooks.NewDispatcher("")
+	}
+	if s.views == nil {
+		s.views, _ = views.NewStore("")
+	}
+	if s.sharing == nil {
+		s.sharing, _ = sharing.NewStore("")
+	}
 	go s.actor.Run(context.Background())
+	go s.webhooks.Run(context.Background(), s.actor)
+	if s.reminders != nil {
+		go s.reminders.Run(context.Background(), s.actor)
+	}
 
 	router := http.NewServeMux()
-	router.Handle("/api/todos/", http.HandlerFunc(s.todosHandler))
+	s.router = router
+	v1 := map[Route]http.HandlerFunc{
+		RouteListTodos:             s.ListTodos,
+		RouteAddTodo:               s.idempotent(s.AddTodo),
+		RouteGetTodo:               s.GetTodo,
+		RouteUpdateTodoStatus:      s.UpdateTodoStatus,
+		RouteBulkTodos:             s.idempotent(s.BulkTodos),
+		RouteGetStats:              s.GetStats,
+		RouteSearchTodos:           s.SearchTodos,
+		RouteStreamEvents:          s.StreamEvents,
+		RouteWebSocket:             s.ServeWebSocket,
+		RouteListWebhooks:          s.ListWebhooks,
+		RouteAddWebhook:            s.AddWebhook,
+		RouteGetWebhook:            s.GetWebhook,
+		RouteDeleteWebhook:         s.DeleteWebhook,
+		RouteGetWebhookDeliveries:  s.GetWebhookDeliveries,
+		RouteGetWebhookDeadLetters: s.GetWebhookDeadLetters,
+		RouteRedeliverWebhook:      s.RedeliverWebhook,
+		RouteListViews:             s.ListViews,
+		RouteAddView:               s.AddView,
+		RouteGetView:               s.GetView,
+		RoutePutView:               s.PutView,
+		RouteDeleteView:            s.DeleteView,
+		RouteGetViewTodos:          s.GetViewTodos,
+		RouteGetTodoMembers:        s.GetTodoMembers,
+		RoutePutTodoMember:         s.PutTodoMember,
+		RouteDeleteTodoMember:      s.DeleteTodoMember,
+		RouteGetListMembers:        s.GetListMembers,
+		RoutePutListMember:         s.PutListMember,
+		RouteDeleteListMember:      s.DeleteListMember,
+		RouteListTokens:            s.ListTokens,
+		RouteAddToken:              s.AddToken,
+		RouteDeleteToken:           s.DeleteToken,
+		RouteGetOpenAPI:            s.GetOpenAPI,
+		RouteGetDocs:               s.GetDocs,
+	}
+	// v2 shares the handlers whose responses didn't change
+	v2 := maps.Clone(v1)
+	maps.Copy(v2, map[Route]http.HandlerFunc{
+		RouteListTodos:          s.ListTodosV2,
+		RouteAddTodo:            s.idempotent(s.AddTodoV2),
+		RouteGetTodo:            s.GetTodoV2,
+		RouteV2UpdateTodoStatus: s.UpdateTodoStatusV2,
+		RouteGetViewTodos:       s.GetViewTodosV2,
+	})
+	for _, handlers := range []map[Route]http.HandlerFunc{v1, v2} {
+		for route, handler := range handlers {
+			handlers[route] = s.authenticated(route, handler)
+		}
+	}
+	registerRoutes(router, APIv1, v1, deprecated, APIv1.Prefix(), APIPrefix)
+	registerRoutes(router, APIv2, v2, nil, APIv2.Prefix())
 
-	static := http.FileServer(http.Dir("./static/about"))
-	router.Handle("/about/", http.StripPrefix("/about/", static))
-	router.HandleFunc("/list", s.handleListPage)
+	s.registerWebPages(router)
 
-	s.Handler = router
+	s.Handler = s.rateLimited(router)
 
 	return s
 }
@@ -47,64 +206,35 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func (s *TodoServer) handleListPage(w http.ResponseWriter, r *http.Request) {
-	resp := make(chan types.GetAllTodosResponse)
-	s.actor.Send(types.GetAllTodosRequest{Ctx: r.Context(), Resp: resp})
-
-	tmpl, err := template.ParseFiles("templates/list.html")
-	if err != nil {
-		slog.InfoContext(r.Context(), "template parse error", "error", err.Error())
-		http.Error(w, "template error", http.StatusInternalServerError)
-		return
-	}
-
-	select {
-	case res := <-resp:
-		slog.InfoContext(r.Context(), "Received response from actor")
-		if res.Err != nil {
-			http.Error(w, res.Err.Error(), http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := tmpl.Execute(w, res.Todos); err != nil {
-			slog.InfoContext(r.Context(), "template execute error", "error", err.Error())
-			http.Error(w, "template error", http.StatusInternalServerError)
+// ListTodos responds with v1's map of every todo keyed by id, the ones
+// with a status or overdue if they're asked for. Ordered pages of todos
+// are only in v2, see ListTodosV2.
+func (s *TodoServer) ListTodos(w http.ResponseWriter, r *http.Request) {
+	if status := r.URL.Query().Get("status"); status != "" {
+		parsed, err := types.ParseStatus(status)
+		if err != nil {
+			invalid(w, r, "status", "%v", err)
 			return
 		}
-	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		s.GetTodosByStatus(w, r, parsed)
+		return
 	}
+	if r.URL.Query().Has("overdue") {
+		s.GetOverdueTodos(w, r)
+		return
+	}
+	s.GetAllTodos(w, r)
 }
 
-func (s *TodoServer) todosHandler(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/todos/")
-
-	switch r.Method {
-	case http.MethodGet:
-		if id == "" {
-			status := r.URL.Query().Get("status")
-			if status != "" {
-				s.GetTodosByStatus(w, r, types.Status(status))
-				return
-			}
-
-			if r.URL.Query().Has("overdue") {
-				s.GetOverdueTodos(w, r)
-				return
-			}
-
-			s.GetAllTodos(w, r)
-			return
-		}
-		s.GetTodo(w, r, id)
-	case http.MethodPost:
-		s.AddTodo(w, r)
-	case http.MethodPut:
-		s.UpdateTodoStatus(w, r, id)
+func (s *TodoServer) GetTodo(w http.ResponseWriter, r *http.Request) {
+	if todo, ok := s.getTodo(w, r, r.PathValue("id")); ok {
+		writeTodos(w, r, RouteGetTodo.Status, todo)
 	}
 }
 
-func (s *TodoServer) GetTodo(w http.ResponseWriter, r *http.Request, id string) {
+// getTodo fetches the todo from the actor. If it can't, it responds with a
+// problem and returns false.
+func (s *TodoServer) getTodo(w http.ResponseWriter, r *http.Request, id string) (types.Todo, bool) {
 	logEndpointCall(r, "GetTodo", map[string]string{"todo_id": id})
 
 	resp := make(chan types.GetTodoResponse)
@@ -114,26 +244,37 @@ func (s *TodoServer) GetTodo(w http.ResponseWriter, r *http.Request, id string)
 	case res := <-resp:
 		slog.InfoContext(r.Context(), "Received response from actor", slog.String("todo_id", id))
 		if res.Err != nil {
-			http.Error(w, res.Err.Error(), http.StatusNotFound)
-			return
+			writeError(w, r, res.Err)
+			return types.Todo{}, false
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(res.Todo)
+		return res.Todo, true
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
+		return types.Todo{}, false
 	}
 }
 
 func (s *TodoServer) AddTodo(w http.ResponseWriter, r *http.Request) {
+	if item, ok := s.addTodo(w, r); ok {
+		writeTodos(w, r, RouteAddTodo.Status, item)
+	}
+}
+
+// addTodo validates the todo in the body and has the actor add it, setting
+// the Location header to the new todo. If it can't, it responds with a
+// problem and returns false.
+func (s *TodoServer) addTodo(w http.ResponseWriter, r *http.Request) (types.TodoItem, bool) {
 	logEndpointCall(r, "AddTodo", nil)
 
 	var todo types.Todo
-	err := json.NewDecoder(r.Body).Decode(&todo)
-	if err != nil {
-		slog.ErrorContext(r.Context(), "Failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		return
+	if !decodeTodos(w, r, &todo, MaxBodySize) {
+		return types.TodoItem{}, false
 	}
+	if err := cmp.Or(todo.ValidateNew(), s.checkUser("assignee", todo.Assignee)); err != nil {
+		writeError(w, r, err)
+		return types.TodoItem{}, false
+	}
+	todo.Init(time.Now())
 
 	resp := make(chan types.AddTodoResponse)
 	s.actor.Send(types.AddTodoRequest{Ctx: r.Context(), Todo: todo, Resp: resp})
@@ -142,40 +283,54 @@ func (s *TodoServer) AddTodo(w http.ResponseWriter, r *http.Request) {
 	case res := <-resp:
 		slog.InfoContext(r.Context(), "Received response from actor", slog.String("todo_id", res.Id))
 		if res.Err != nil {
-			http.Error(w, res.Err.Error(), http.StatusNotFound)
-			return
+			writeError(w, r, res.Err)
+			return types.TodoItem{}, false
 		}
-		w.WriteHeader(http.StatusAccepted)
+		w.Header().Set("Location", RouteGetTodo.URL(routePrefix(r), nil, res.Id))
+		// As the store did
+		todo.Own(r.Context())
+		return types.TodoItem{Id: res.Id, Todo: todo}, true
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
+		return types.TodoItem{}, false
+	}
+}
+
+func (s *TodoServer) UpdateTodoStatus(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.updateTodoStatus(w, r, r.PathValue("id")); ok {
+		w.WriteHeader(RouteUpdateTodoStatus.Status)
 	}
 }
 
-func (s *TodoServer) UpdateTodoStatus(w http.ResponseWriter, r *http.Request, id string) {
+// updateTodoStatus has the actor change the todo's status to the one in the
+// body, returning the changed todo. If it can't, it responds with a problem
+// and returns false.
+func (s *TodoServer) updateTodoStatus(w http.ResponseWriter, r *http.Request, id string) (types.Todo, bool) {
 	logEndpointCall(r, "UpdateTodoStatus", map[string]string{"todo_id": id})
 
-	var req struct {
-		Status types.Status `json:"status"`
+	var req statusUpdate
+	if !decodeTodos(w, r, &req, MaxBodySize) {
+		return types.Todo{}, false
 	}
-	err := json.NewDecoder(r.Body).Decode(&req)
+	status, err := types.ParseStatus(string(req.Status))
 	if err != nil {
-		slog.Error("Failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
-		return
+		invalid(w, r, "status", "%v", err)
+		return types.Todo{}, false
 	}
 
 	resp := make(chan types.UpdateTodoStatusResponse)
-	s.actor.Send(types.UpdateTodoStatusRequest{Ctx: r.Context(), Id: id, Status: req.Status, Resp: resp})
+	s.actor.Send(types.UpdateTodoStatusRequest{Ctx: r.Context(), Id: id, Status: status, Resp: resp})
 
 	select {
 	case res := <-resp:
 		if res.Err != nil {
-			http.Error(w, res.Err.Error(), http.StatusInternalServerError)
-			return
+			writeError(w, r, res.Err)
+			return types.Todo{}, false
 		}
-		w.WriteHeader(http.StatusAccepted)
+		return res.Todo, true
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
+		return types.Todo{}, false
 	}
 }
 
@@ -187,11 +342,9 @@ func (s *TodoServer) GetTodosByStatus(w http.ResponseWriter, r *http.Request, st
 
 	select {
 	case res := <-resp:
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(res.Todos)
+		writeJSON(w, http.StatusOK, res.Todos)
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
 	}
 }
 
@@ -203,11 +356,9 @@ func (s *TodoServer) GetOverdueTodos(w http.ResponseWriter, r *http.Request) {
 
 	select {
 	case res := <-resp:
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(res.Todos)
+		writeJSON(w, http.StatusOK, res.Todos)
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
 	}
 }
 
@@ -220,14 +371,32 @@ func (s *TodoServer) GetAllTodos(w http.ResponseWriter, r *http.Request) {
 	select {
 	case res := <-resp:
 		if res.Err != nil {
-			http.Error(w, res.Err.Error(), http.StatusInternalServerError)
+			writeError(w, r, res.Err)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(res.Todos)
+		writeJSON(w, http.StatusOK, res.Todos)
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+	}
+}
+
+func (s *TodoServer) GetStats(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "GetStats", map[string]string{"since": r.URL.Query().Get("since"), "until": r.URL.Query().Get("until")})
+
+	since, until, err := types.ParseStatsRange(r.URL.Query().Get("since"), r.URL.Query().Get("until"), time.Now(), time.UTC)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := make(chan types.GetStatsResponse)
+	s.actor.Send(types.GetStatsRequest{Ctx: r.Context(), Since: since, Until: until, Resp: resp})
+
+	select {
+	case res := <-resp:
+		writeJSON(w, RouteGetStats.Status, res.Stats)
 	case <-r.Context().Done():
-		http.Error(w, "request canceled", http.StatusRequestTimeout)
+		requestCanceled(w, r)
 	}
 }
 
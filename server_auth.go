@@ -0,0 +1,123 @@
+package todoapp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+// publicRoutes can be used without a token, so the API can be explored
+// before having one.
+var publicRoutes = []Route{RouteGetOpenAPI, RouteGetDocs}
+
+// adminRoutes can only be used by admins. Webhooks are sent every user's
+// changes, so only admins can manage them.
+var adminRoutes = []Route{
+	RouteListWebhooks, RouteAddWebhook, RouteGetWebhook, RouteDeleteWebhook,
+	RouteGetWebhookDeliveries, RouteGetWebhookDeadLetters, RouteRedeliverWebhook,
+}
+
+// authenticated has every route but the public ones require a Bearer token,
+// when the server has users, and checks the user has the role the route
+// needs. The request is given the user, see withUser.
+func (s *TodoServer) authenticated(route Route, next http.HandlerFunc) http.HandlerFunc {
+	if s.users == nil || isRoute(publicRoutes, route) {
+		return next
+	}
+	admin := isRoute(adminRoutes, route)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, r, "send a token in an Authorization: Bearer header")
+			return
+		}
+		user, err := s.authenticate(r, token)
+		if err != nil {
+			unauthorized(w, r, err.Error())
+			return
+		}
+		if admin && !user.Admin {
+			problem(w, r, http.StatusForbidden, ProblemForbidden, route.Name+" is only for admins")
+			return
+		}
+
+		r = s.withUser(r, user)
+		if !s.authorize(w, r, route) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// tokenLookup is what a request's token was found to be, kept in its
+// context so the token is only looked up once however many handlers need it.
+type tokenLookup struct {
+	token string
+	user  users.User
+	err   error
+}
+
+type tokenLookupKey struct{}
+
+// authenticate looks up the user token belongs to, unless the request
+// already has.
+func (s *TodoServer) authenticate(r *http.Request, token string) (users.User, error) {
+	if lookup, ok := r.Context().Value(tokenLookupKey{}).(tokenLookup); ok && lookup.token == token {
+		return lookup.user, lookup.err
+	}
+	return s.users.Authenticate(token)
+}
+
+// withTokenLookup authenticates the request's token, if it has one, and
+// keeps the result for authenticate.
+func (s *TodoServer) withTokenLookup(r *http.Request) (*http.Request, tokenLookup) {
+	token, ok := bearerToken(r)
+	if !ok || s.users == nil {
+		return r, tokenLookup{}
+	}
+	user, err := s.users.Authenticate(token)
+	lookup := tokenLookup{token: token, user: user, err: err}
+	return r.WithContext(context.WithValue(r.Context(), tokenLookupKey{}, lookup)), lookup
+}
+
+// withUser gives the request's context the user, under UserKey, and scopes
+// it to the todos they have a role on.
+func (s *TodoServer) withUser(r *http.Request, user users.User) *http.Request {
+	ctx := context.WithValue(r.Context(), UserKey{}, user)
+	ctx = types.WithAccess(ctx, types.Access{User: user.Id, Lists: s.sharing.SharedWith(user.Id)})
+	return r.WithContext(ctx)
+}
+
+func isRoute(routes []Route, route Route) bool {
+	for _, r := range routes {
+		if r.Name == route.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="todo-app"`)
+	problem(w, r, http.StatusUnauthorized, ProblemUnauthorized, detail)
+}
+
+// userFrom returns the user the request was authenticated as, if the server
+// has users.
+func userFrom(r *http.Request) (users.User, bool) {
+	user, ok := r.Context().Value(UserKey{}).(users.User)
+	return user, ok
+}
@@ -0,0 +1,121 @@
+package todoapp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+func TestAuthentication(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	alice, _ := userStore.AddUser("alice", false)
+	bob, _ := userStore.AddUser("bob", true)
+	_, aliceToken, _ := userStore.CreateToken(alice.Id, "laptop")
+	_, bobToken, _ := userStore.CreateToken(bob.Id, "")
+
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	aliceClient := NewTodoAPIClient(ts.URL+APIPrefix, WithToken(aliceToken))
+	bobClient := NewTodoAPIClient(ts.URL+APIPrefix, WithToken(bobToken))
+
+	t.Run("Requests without a valid token are unauthorized", func(t *testing.T) {
+		for _, header := range []string{"", "Basic abc", "Bearer todo_nope"} {
+			request := httptest.NewRequest(http.MethodGet, "/api/todos/", nil)
+			if header != "" {
+				request.Header.Set("Authorization", header)
+			}
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+
+			assertProblem(t, response, http.StatusUnauthorized, ProblemUnauthorized)
+			if response.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%q: got no WWW-Authenticate header", header)
+			}
+		}
+
+		_, err := NewTodoAPIClient(ts.URL + APIPrefix).GetAllTodos()
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("got %v want ErrUnauthorized", err)
+		}
+	})
+
+	t.Run("The docs don't need a token", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+		assertStatus(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("Users only see their own todos", func(t *testing.T) {
+		aliceId, err := aliceClient.AddTodo(types.NewTodo("Alice's todo", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bobClient.AddTodo(types.NewTodo("Bob's todo", nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		todos, err := aliceClient.GetAllTodos()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 1 || todos[aliceId].Owner != alice.Id {
+			t.Errorf("got %+v want only alice's todo, owned by her", todos)
+		}
+
+		if _, err := bobClient.GetTodo(aliceId); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound getting someone else's todo", err)
+		}
+		if err := bobClient.UpdateTodoStatus(aliceId, types.Completed); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound updating someone else's todo", err)
+		}
+	})
+
+	t.Run("Only admins can manage webhooks", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/api/webhooks", nil)
+		request.Header.Set("Authorization", "Bearer "+aliceToken)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertProblem(t, response, http.StatusForbidden, ProblemForbidden)
+
+		request = httptest.NewRequest(http.MethodGet, "/api/webhooks", nil)
+		request.Header.Set("Authorization", "Bearer "+bobToken)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("Users manage their own tokens", func(t *testing.T) {
+		token, secret, err := aliceClient.CreateToken("phone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.UserId != alice.Id || secret == "" {
+			t.Fatalf("got %+v %q want alice's token and its secret", token, secret)
+		}
+
+		tokens, err := aliceClient.Tokens()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != 2 {
+			t.Errorf("got %d tokens want alice's 2", len(tokens))
+		}
+
+		if err := bobClient.RevokeToken(token.Id); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound revoking someone else's token", err)
+		}
+		phone := NewTodoAPIClient(ts.URL+APIPrefix, WithToken(secret))
+		if err := phone.RevokeToken(token.Id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := phone.GetAllTodos(); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("got %v want ErrUnauthorized with a revoked token", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package todoapp
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+type bulkRequestBody struct {
+	Ops []types.BulkOp `json:"ops"`
+}
+
+type bulkResponseBody struct {
+	Applied bool               `json:"applied"`
+	Results []types.BulkResult `json:"results"`
+}
+
+// bulkProblem is the problem for a failed batch, with the result of each op
+// as extension members.
+type bulkProblem struct {
+	Problem
+	bulkResponseBody
+}
+
+// BulkTodos applies a batch of creates, updates and deletes as one change.
+// Either every op is applied, or none are and the response is a 422 problem
+// with the reason each failing op failed.
+func (s *TodoServer) BulkTodos(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "BulkTodos", nil)
+
+	var body bulkRequestBody
+	if !decodeJSON(w, r, &body, MaxBulkBodySize) {
+		return
+	}
+	if len(body.Ops) == 0 || len(body.Ops) > types.MaxBulkOps {
+		invalid(w, r, "ops", "must have between 1 and %d operations", types.MaxBulkOps)
+		return
+	}
+	if err := s.checkBulkUsers(body.Ops); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := make(chan types.BulkResponse)
+	s.actor.Send(types.BulkRequest{Ctx: r.Context(), Ops: body.Ops, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor", slog.Int("ops", len(res.Results)))
+		if errors.Is(res.Err, types.ErrBulkFailed) {
+			p := newProblem(r, http.StatusUnprocessableEntity, ProblemBulkFailed, "")
+			writeProblem(w, p.Status, bulkProblem{Problem: p, bulkResponseBody: bulkResponseBody{Results: res.Results}})
+			return
+		}
+		if res.Err != nil {
+			writeError(w, r, res.Err)
+			return
+		}
+		writeJSON(w, http.StatusOK, bulkResponseBody{Applied: true, Results: res.Results})
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+	}
+}
@@ -0,0 +1,83 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestBulkTodos(t *testing.T) {
+	actor := stores.NewTodoStoreActor(stores.NewInMemoryTodoStore())
+	server := NewTodoServer(actor)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewTodoAPIClient(ts.URL + "/api")
+
+	first := addTodo(actor, types.NewTodo("Close the sprint", nil))
+	second := addTodo(actor, types.NewTodo("Write the retro", nil))
+
+	t.Run("Applies nothing if an op fails", func(t *testing.T) {
+		results, err := client.Bulk([]types.BulkOp{
+			{Op: types.BulkUpdate, Id: first, Status: types.Completed},
+			{Op: types.BulkDelete, Id: "missing"},
+		})
+		if !errors.Is(err, types.ErrBulkFailed) || len(results) != 2 || results[1].Error == "" {
+			t.Fatalf("got %+v and error %v want the missing todo reported", results, err)
+		}
+		todo, _ := client.GetTodo(first)
+		if todo.Status != types.NotStarted {
+			t.Errorf("got status %s want the update rolled back", todo.Status)
+		}
+	})
+
+	t.Run("Applies every op", func(t *testing.T) {
+		results, err := client.Bulk([]types.BulkOp{
+			{Op: types.BulkCreate, Todo: &types.Todo{Description: "Plan the next sprint"}},
+			{Op: types.BulkUpdate, Id: first, Status: types.Completed},
+			{Op: types.BulkDelete, Id: second},
+		})
+		if err != nil || len(results) != 3 {
+			t.Fatalf("got %+v and error %v want three results", results, err)
+		}
+		created, err := client.GetTodo(results[0].Id)
+		if err != nil || created.Status != types.NotStarted || created.Version != 1 {
+			t.Errorf("got %+v and error %v want a new todo", created, err)
+		}
+		if _, err := client.GetTodo(second); err == nil {
+			t.Errorf("got the deleted todo back")
+		}
+		if found, _ := client.Search("retro", 0); len(found) != 0 {
+			t.Errorf("got %v want the deleted todo gone from search", found)
+		}
+
+		// Replay what came after the two adds
+		sub, events, _ := actor.Events().Subscribe(2, 1)
+		sub.Close()
+		if len(events) != 3 || events[2].Type != types.EventDeleted || events[2].TodoId != second || events[2].Todo.Description != "Write the retro" {
+			t.Errorf("got events %+v want the batch's add, update and delete", events)
+		}
+	})
+
+	t.Run("Rejects malformed batches", func(t *testing.T) {
+		for _, body := range []string{`{"ops": []}`, `[{"op": "create"}]`} {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/todos/bulk", strings.NewReader(body)))
+			assertStatus(t, response.Code, http.StatusBadRequest)
+		}
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/todos/bulk", strings.NewReader(`{"ops": [{"op": "create"}]}`)))
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		var body bulkResponseBody
+		json.NewDecoder(response.Body).Decode(&body)
+		if body.Applied || len(body.Results) != 1 || body.Results[0].Error == "" {
+			t.Errorf("got %+v want the create's error", body)
+		}
+	})
+}
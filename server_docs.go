@@ -0,0 +1,44 @@
+package todoapp
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+
+	"grantjames.github.io/todo-app/openapi"
+	"grantjames.github.io/todo-app/types"
+)
+
+// GetOpenAPI serves the OpenAPI document describing the version of the API
+// it was requested from.
+func (s *TodoServer) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "GetOpenAPI", nil)
+
+	spec := openapi.Spec
+	if routePrefix(r) == APIv2.Prefix() {
+		spec = openapi.SpecV2
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(RouteGetOpenAPI.Status)
+	w.Write(spec)
+}
+
+// GetDocs serves the viewer for the OpenAPI document, index.html when no
+// file is given.
+func (s *TodoServer) GetDocs(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	logEndpointCall(r, "GetDocs", map[string]string{"file": file})
+
+	if file == "" {
+		file = "index.html"
+	}
+	data, err := fs.ReadFile(openapi.Docs, file)
+	if err != nil {
+		writeError(w, r, types.NotFoundf("no docs file %s", file))
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(file)))
+	w.WriteHeader(RouteGetDocs.Status)
+	w.Write(data)
+}
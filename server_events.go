@@ -0,0 +1,111 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	// DefaultEventsHeartbeat is how often a comment is written to idle event
+	// streams so proxies don't close them.
+	DefaultEventsHeartbeat = 15 * time.Second
+	// How many events can be waiting for a slow client before it's dropped.
+	eventsBuffer = 64
+	// How long clients wait before reconnecting, in milliseconds.
+	eventsRetry = 3000
+)
+
+// StreamEvents sends every change to the user's todos as a Server-Sent
+// Event. Clients can filter by ?list=, ?tag= and ?status=, and resume with
+// a Last-Event-ID header.
+func (s *TodoServer) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "StreamEvents", map[string]string{"last_event_id": r.Header.Get("Last-Event-ID")})
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem(w, r, http.StatusInternalServerError, ProblemNotAvailable, "streaming unsupported")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.EventFilter{
+		List: query.Get("list"),
+		Tag:  query.Get("tag"),
+	}
+	if status := query.Get("status"); status != "" {
+		var err error
+		if filter.Status, err = types.ParseStatus(status); err != nil {
+			invalid(w, r, "status", "%v", err)
+			return
+		}
+	}
+	if access, ok := types.AccessFrom(r.Context()); ok {
+		filter.Access = &access
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = query.Get("last_event_id")
+	}
+	var lastId uint64
+	if lastEventId != "" {
+		var err error
+		lastId, err = strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			invalid(w, r, "Last-Event-ID", "must be a number")
+			return
+		}
+	}
+
+	sub, missed, complete := s.actor.Events().Subscribe(lastId, eventsBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry)
+
+	if !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", types.EventReset)
+	}
+	for _, e := range missed {
+		if filter.Matches(e) {
+			writeEvent(w, e)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				slog.WarnContext(r.Context(), "Event stream dropped for falling behind")
+				return
+			}
+			if filter.Matches(e) {
+				writeEvent(w, e)
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e types.TodoEvent) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+}
@@ -0,0 +1,88 @@
+package todoapp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestEventStream(t *testing.T) {
+	actor := stores.NewTodoStoreActor(stores.NewInMemoryTodoStore())
+	server := NewTodoServer(actor)
+	server.heartbeat = 10 * time.Millisecond
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := NewTodoAPIClient(ts.URL + "/api")
+	events, err := client.SubscribeFiltered(ctx, types.EventFilter{List: "ops"})
+	if err != nil {
+		t.Fatalf("unexpected error subscribing: %v", err)
+	}
+
+	ignored := types.NewTodo("Another list", nil)
+	ignored.List = "home"
+	wanted := types.NewTodo("Ops todo", nil)
+	wanted.List = "ops"
+	addTodo(actor, ignored)
+	id := addTodo(actor, wanted)
+
+	select {
+	case e := <-events:
+		if e.Type != types.EventAdded || e.TodoId != id || e.Todo.Description != "Ops todo" {
+			t.Errorf("got event %v want the ops todo being added", e)
+		}
+		if e.Id != 2 {
+			t.Errorf("got event id %d want 2", e.Id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func addTodo(actor *stores.TodoStoreActor, todo types.Todo) string {
+	resp := make(chan types.AddTodoResponse)
+	actor.Send(types.AddTodoRequest{Ctx: context.Background(), Todo: todo, Resp: resp})
+	return (<-resp).Id
+}
+
+func TestEventStreamAfterARestart(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// An id from before the restart, which the new server hasn't reached
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events", nil)
+	request.Header.Set("Last-Event-ID", "42")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+
+	lines := bufio.NewScanner(response.Body)
+	for lines.Scan() {
+		if strings.HasPrefix(lines.Text(), "event: ") {
+			if got := strings.TrimPrefix(lines.Text(), "event: "); got != string(types.EventReset) {
+				t.Errorf("got a %q event want %q", got, types.EventReset)
+			}
+			return
+		}
+	}
+	t.Fatal("the stream ended without a reset event")
+}
@@ -0,0 +1,120 @@
+package todoapp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"grantjames.github.io/todo-app/filter"
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	// TodoMapMediaType is v1's list format, an unordered JSON object of
+	// todos keyed by id.
+	TodoMapMediaType = "application/vnd.todo.map+json"
+
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+
+	NextCursorHeader = "X-Next-Cursor"
+)
+
+// parseTodoQuery reads the list endpoint's query parameters: status,
+// overdue, all, assignee, q (a filter expression), sort, limit and cursor.
+// An assignee of me is the user making the request.
+func parseTodoQuery(r *http.Request) (types.TodoQuery, error) {
+	var q types.TodoQuery
+	var err error
+	values := r.URL.Query()
+
+	if s := values.Get("status"); s != "" {
+		if q.Status, err = types.ParseStatus(s); err != nil {
+			return q, types.Invalid("status", "%v", err)
+		}
+	}
+	q.Overdue = values.Has("overdue")
+	q.All = values.Has("all")
+	if q.Assignee = values.Get("assignee"); q.Assignee == "me" {
+		user, ok := userFrom(r)
+		if !ok {
+			return q, types.Invalid("assignee", "can only be me on a server with users")
+		}
+		q.Assignee = user.Id
+	}
+	if q.Sort, err = types.ParseSort(values.Get("sort")); err != nil {
+		return q, err
+	}
+
+	if err := parsePage(values, &q); err != nil {
+		return q, err
+	}
+
+	if s := values.Get("q"); s != "" {
+		expr, err := filter.Parse(s)
+		if syntaxErr, ok := err.(*filter.SyntaxError); ok {
+			return q, types.Invalid("q", "%v\n%s", err, syntaxErr.Caret(s))
+		}
+		if err != nil {
+			return q, types.Invalid("q", "%v", err)
+		}
+		q.Filter = expr
+	}
+	return q, nil
+}
+
+// parsePage reads the limit and cursor parameters into q.
+func parsePage(values url.Values, q *types.TodoQuery) error {
+	q.Limit = DefaultPageSize
+	if s := values.Get("limit"); s != "" {
+		var err error
+		q.Limit, err = strconv.Atoi(s)
+		if err != nil || q.Limit < 1 || q.Limit > MaxPageSize {
+			return types.Invalid("limit", "must be between 1 and %d", MaxPageSize)
+		}
+	}
+	q.Cursor = values.Get("cursor")
+	return nil
+}
+
+// writeTodoPage runs q through the actor and responds with the page, in the
+// version's representation, with a link to the next one if there is one.
+func (s *TodoServer) writeTodoPage(w http.ResponseWriter, r *http.Request, q types.TodoQuery, version APIVersion) {
+	resp := make(chan types.QueryTodosResponse)
+	s.actor.Send(types.QueryTodosRequest{Ctx: r.Context(), Query: q, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor")
+		if errors.Is(res.Err, types.ErrInvalidCursor) {
+			invalid(w, r, "cursor", "%v", res.Err)
+			return
+		}
+		if res.Err != nil {
+			writeError(w, r, res.Err)
+			return
+		}
+
+		if res.Page.NextCursor != "" {
+			next := r.URL.Query()
+			next.Set("cursor", res.Page.NextCursor)
+			next.Set("limit", strconv.Itoa(q.Limit))
+			w.Header().Set(NextCursorHeader, res.Page.NextCursor)
+			// Ahead of v1's successor-version link, for clients that only
+			// read the first
+			link := fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, next.Encode())
+			w.Header()["Link"] = slices.Insert(w.Header()["Link"], 0, link)
+		}
+		if version == APIv2 {
+			writeTodos(w, r, http.StatusOK, todosV2(res.Page.Todos))
+			return
+		}
+		writeTodos(w, r, http.StatusOK, res.Page.Todos)
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+	}
+}
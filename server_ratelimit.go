@@ -0,0 +1,84 @@
+package todoapp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"grantjames.github.io/todo-app/ratelimit"
+)
+
+// Rate limit headers, as in the IETF's draft RateLimit header fields
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// rateLimited refuses requests from clients going faster than the server's
+// limit for reads (GET and HEAD requests) or for writes (everything else),
+// with a 429 saying when to try again. Every response says how the client
+// stands against its limit. It's in front of every route, so a client
+// hammering the API can't starve the actor for everyone else.
+func (s *TodoServer) rateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := s.writeLimiter
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			limiter = s.readLimiter
+		}
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r, lookup := s.withTokenLookup(r)
+		d := limiter.Allow(rateLimitKey(r, lookup))
+		w.Header().Set(RateLimitLimitHeader, strconv.Itoa(d.Limit.Requests))
+		w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(d.Remaining))
+		w.Header().Set(RateLimitResetHeader, strconv.Itoa(seconds(d.Reset)))
+		w.Header().Set(RateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", d.Limit.Requests, seconds(d.Limit.Per)))
+		if !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(d.RetryAfter)))
+			problem(w, r, http.StatusTooManyRequests, ProblemRateLimited,
+				fmt.Sprintf("the limit is %s, try again in %ds", d.Limit, seconds(d.RetryAfter)))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey is who the request counts against: the API token it was
+// sent with, or else the address it came from. Tokens that aren't valid
+// count against the address, so making them up doesn't get around the
+// limit. The lookup is passed on in the request so authenticated doesn't
+// repeat it.
+func rateLimitKey(r *http.Request, lookup tokenLookup) string {
+	if lookup.token != "" && lookup.err == nil {
+		sum := sha256.Sum256([]byte(lookup.token))
+		return "token:" + hex.EncodeToString(sum[:16])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// seconds rounds d up to whole seconds, as the headers have them.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// newLimiter is nil for no limit, so rateLimited can skip it.
+func newLimiter(limit ratelimit.Limit) *ratelimit.Limiter {
+	if limit.Unlimited() {
+		return nil
+	}
+	return ratelimit.NewLimiter(limit)
+}
@@ -0,0 +1,122 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/ratelimit"
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+func TestRateLimits(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	alice, _ := userStore.AddUser("alice", false)
+	_, aliceToken, _ := userStore.CreateToken(alice.Id, "")
+
+	reads, writes := ratelimit.Limit{Requests: 2, Per: time.Hour}, ratelimit.Limit{Requests: 1, Per: time.Hour}
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore), WithRateLimits(reads, writes))
+
+	send := func(method, token string) *httptest.ResponseRecorder {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(`{"description": "Limited"}`)
+		}
+		request := httptest.NewRequest(method, "/api/todos/", body)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("Responses say how many requests are left", func(t *testing.T) {
+		for _, remaining := range []string{"1", "0"} {
+			response := send(http.MethodGet, aliceToken)
+			assertStatus(t, response.Code, http.StatusOK)
+			if got := response.Header().Get(RateLimitRemainingHeader); got != remaining {
+				t.Errorf("got %s remaining want %s", got, remaining)
+			}
+			if got := response.Header().Get(RateLimitPolicyHeader); got != "2;w=3600" {
+				t.Errorf("got policy %q want 2;w=3600", got)
+			}
+		}
+	})
+
+	t.Run("Going over the limit is a 429 saying when to retry", func(t *testing.T) {
+		response := send(http.MethodGet, aliceToken)
+		assertProblem(t, response, http.StatusTooManyRequests, ProblemRateLimited)
+		if got := response.Header().Get("Retry-After"); got != "1800" {
+			t.Errorf("got Retry-After %q want 1800, when the next request is allowed", got)
+		}
+		if got := response.Header().Get(RateLimitResetHeader); got != "3600" {
+			t.Errorf("got reset %q want 3600", got)
+		}
+	})
+
+	t.Run("Writes have their own limit", func(t *testing.T) {
+		assertStatus(t, send(http.MethodPost, aliceToken).Code, http.StatusCreated)
+		assertProblem(t, send(http.MethodPost, aliceToken), http.StatusTooManyRequests, ProblemRateLimited)
+	})
+
+	t.Run("Invalid tokens count against the address", func(t *testing.T) {
+		assertStatus(t, send(http.MethodGet, "todo_made_up_1").Code, http.StatusUnauthorized)
+		assertStatus(t, send(http.MethodGet, "todo_made_up_2").Code, http.StatusUnauthorized)
+		assertProblem(t, send(http.MethodGet, "todo_made_up_3"), http.StatusTooManyRequests, ProblemRateLimited)
+	})
+}
+
+func TestClientRetriesAfterRateLimit(t *testing.T) {
+	t.Run("Requests are sent again after Retry-After", func(t *testing.T) {
+		var bodies []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) < 3 {
+				w.Header().Set("Retry-After", "0")
+				problem(w, r, http.StatusTooManyRequests, ProblemRateLimited, "")
+				return
+			}
+			writeJSON(w, http.StatusCreated, types.TodoItem{Id: "1"})
+		}))
+		defer ts.Close()
+
+		id, err := NewTodoAPIClient(ts.URL + APIPrefix).AddTodo(types.NewTodo("Retried", nil))
+		if err != nil || id != "1" {
+			t.Fatalf("got %q and error %v want the todo added", id, err)
+		}
+		var todo types.Todo
+		if len(bodies) != 3 || json.Unmarshal([]byte(bodies[2]), &todo) != nil || todo.Description != "Retried" {
+			t.Errorf("got bodies %q want the todo sent 3 times", bodies)
+		}
+	})
+
+	t.Run("Waiting too long is an error", func(t *testing.T) {
+		attempts := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "3600")
+			problem(w, r, http.StatusTooManyRequests, ProblemRateLimited, "")
+		}))
+		defer ts.Close()
+
+		_, err := NewTodoAPIClient(ts.URL + APIPrefix).GetTodo("1")
+		if !errors.Is(err, ErrRateLimited) || attempts != 1 {
+			t.Errorf("got %v after %d attempts want ErrRateLimited straight away", err, attempts)
+		}
+	})
+
+	t.Run("Retry-After can be a date", func(t *testing.T) {
+		now := time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)
+		got, ok := retryAfter(now.Add(5*time.Second).Format(http.TimeFormat), now)
+		if !ok || got != 5*time.Second {
+			t.Errorf("got %v want 5s", got)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package todoapp
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchTodos responds with the todos matching ?q=, best match first.
+func (s *TodoServer) SearchTodos(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	logEndpointCall(r, "SearchTodos", map[string]string{"query": query})
+
+	if query == "" {
+		invalid(w, r, "q", "missing search query")
+		return
+	}
+	limit := DefaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > MaxSearchLimit {
+			invalid(w, r, "limit", "must be between 1 and %d", MaxSearchLimit)
+			return
+		}
+	}
+
+	resp := make(chan types.SearchTodosResponse)
+	s.actor.Send(types.SearchTodosRequest{Ctx: r.Context(), Query: query, Limit: limit, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor", slog.Int("results", len(res.Results)))
+		writeJSON(w, http.StatusOK, res.Results)
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+	}
+}
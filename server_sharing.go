@@ -0,0 +1,334 @@
+package todoapp
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+// todoRoles is the role on the todo in the path each route needs, and
+// listRoles the role on the list in the path. authenticated checks them
+// before a request reaches its handler, and so the actor. Reads of todos
+// need no check, as the stores only see the todos the user has a role on,
+// and bulk changes are checked op by op by types.ApplyScopedBulk.
+var (
+	todoRoles = map[string]types.Role{
+		RouteUpdateTodoStatus.Name: types.RoleEditor,
+		RouteGetTodoMembers.Name:   types.RoleViewer,
+		RoutePutTodoMember.Name:    types.RoleOwner,
+		RouteDeleteTodoMember.Name: types.RoleOwner,
+	}
+	listRoles = map[string]types.Role{
+		RouteGetListMembers.Name:   types.RoleViewer,
+		RoutePutListMember.Name:    types.RoleOwner,
+		RouteDeleteListMember.Name: types.RoleOwner,
+	}
+)
+
+type memberRequestBody struct {
+	Role types.Role `json:"role"`
+}
+
+// authorize responds with a problem and returns false unless the request's
+// user has the role the route needs on the todo or list in its path.
+func (s *TodoServer) authorize(w http.ResponseWriter, r *http.Request, route Route) bool {
+	if need, ok := todoRoles[route.Name]; ok {
+		todo, ok := s.getTodo(w, r, r.PathValue("id"))
+		if !ok {
+			return false
+		}
+		if err := todo.Allow(r.Context(), need); err != nil {
+			writeError(w, r, err)
+			return false
+		}
+	}
+	if need, ok := listRoles[route.Name]; ok {
+		list, ok := s.listRef(w, r)
+		if !ok {
+			return false
+		}
+		access, _ := types.AccessFrom(r.Context())
+		role := listRole(access, list)
+		if role == "" {
+			writeError(w, r, types.NotFoundf("no list %q is shared with you", list.List))
+			return false
+		}
+		if !role.Allows(need) {
+			writeError(w, r, types.Forbiddenf("this needs the %s role on the list, not %s", need, role))
+			return false
+		}
+	}
+	return true
+}
+
+// listRef is the list in the request's path. It's the user's own, or with
+// an owner parameter the list of that name shared with them by that user.
+func (s *TodoServer) listRef(w http.ResponseWriter, r *http.Request) (types.ListRef, bool) {
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return types.ListRef{}, false
+	}
+	list := types.ListRef{Owner: user.Id, List: r.PathValue("list")}
+	if name := r.URL.Query().Get("owner"); name != "" {
+		owner, err := s.users.UserNamed(name)
+		if err != nil {
+			writeError(w, r, types.NotFoundf("no user named %q", name))
+			return types.ListRef{}, false
+		}
+		list.Owner = owner.Id
+	}
+	return list, true
+}
+
+func listRole(a types.Access, list types.ListRef) types.Role {
+	if list.Owner == a.User {
+		return types.RoleOwner
+	}
+	return a.Lists[list]
+}
+
+// memberUser is the user named in the request's path.
+func (s *TodoServer) memberUser(w http.ResponseWriter, r *http.Request) (users.User, bool) {
+	name := r.PathValue("user")
+	user, err := s.users.UserNamed(name)
+	if err != nil {
+		writeError(w, r, types.NotFoundf("no user named %q", name))
+		return users.User{}, false
+	}
+	return user, true
+}
+
+// decodeRole reads the role from a request to share a todo or list.
+func decodeRole(w http.ResponseWriter, r *http.Request) (types.Role, bool) {
+	var body memberRequestBody
+	if !decodeJSON(w, r, &body, MaxBodySize) {
+		return "", false
+	}
+	role, err := types.ParseRole(string(body.Role))
+	if err != nil {
+		invalid(w, r, "role", "%v", err)
+		return "", false
+	}
+	return role, true
+}
+
+// members lists the owner and then the members, by name, with their names
+// filled in.
+func (s *TodoServer) members(owner string, members map[string]types.Role) []types.Member {
+	list := []types.Member{s.member(owner, types.RoleOwner)}
+	for id, role := range members {
+		list = append(list, s.member(id, role))
+	}
+	slices.SortFunc(list[1:], func(a, b types.Member) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.UserId, b.UserId))
+	})
+	return list
+}
+
+func (s *TodoServer) member(id string, role types.Role) types.Member {
+	m := types.Member{UserId: id, Role: role}
+	if user, err := s.users.User(id); err == nil {
+		m.Name = user.Name
+	}
+	return m
+}
+
+// checkUser returns a validation error for field if id isn't a user's. An
+// empty id, or any id on a server without users, is fine.
+func (s *TodoServer) checkUser(field, id string) error {
+	if s.users == nil || id == "" {
+		return nil
+	}
+	_, err := s.users.User(id)
+	if errors.Is(err, users.ErrUnknownUser) {
+		return types.Invalid(field, "%q isn't a user", id)
+	}
+	return err
+}
+
+// checkBulkUsers is checkUser for every user the ops assign todos to or
+// share them with.
+func (s *TodoServer) checkBulkUsers(ops []types.BulkOp) error {
+	for i, op := range ops {
+		var err error
+		if op.Todo != nil {
+			err = s.checkUser(fmt.Sprintf("ops[%d].todo.assignee", i), op.Todo.Assignee)
+		}
+		if op.Assignee != nil && err == nil {
+			err = s.checkUser(fmt.Sprintf("ops[%d].assignee", i), *op.Assignee)
+		}
+		for id := range op.Members {
+			if err == nil {
+				err = s.checkUser(fmt.Sprintf("ops[%d].members", i), id)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shareTodo has the actor change the members of the todo, as a bulk update
+// so that it's one change. If it can't, it responds with a problem and
+// returns false.
+func (s *TodoServer) shareTodo(w http.ResponseWriter, r *http.Request, id string, members map[string]types.Role) bool {
+	resp := make(chan types.BulkResponse)
+	op := types.BulkOp{Op: types.BulkUpdate, Id: id, Members: members}
+	s.actor.Send(types.BulkRequest{Ctx: r.Context(), Ops: []types.BulkOp{op}, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor", slog.String("todo_id", id))
+		if errors.Is(res.Err, types.ErrBulkFailed) {
+			// The todo changed since authorize read it
+			problem(w, r, http.StatusConflict, ProblemConflict, res.Results[0].Error)
+			return false
+		}
+		if res.Err != nil {
+			writeError(w, r, res.Err)
+			return false
+		}
+		return true
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+		return false
+	}
+}
+
+// GetTodoMembers responds with the todo's owner and who it's shared with.
+// Anyone it's shared with can see who else it is.
+func (s *TodoServer) GetTodoMembers(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "GetTodoMembers", map[string]string{"todo_id": id})
+
+	if _, ok := s.requireUser(w, r); !ok {
+		return
+	}
+	todo, ok := s.getTodo(w, r, id)
+	if !ok {
+		return
+	}
+	writeJSON(w, RouteGetTodoMembers.Status, s.members(todo.Owner, todo.Members))
+}
+
+// PutTodoMember shares the todo with a user, or changes their role on it.
+func (s *TodoServer) PutTodoMember(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "PutTodoMember", map[string]string{"todo_id": id, "user": r.PathValue("user")})
+
+	if _, ok := s.requireUser(w, r); !ok {
+		return
+	}
+	role, ok := decodeRole(w, r)
+	if !ok {
+		return
+	}
+	member, ok := s.memberUser(w, r)
+	if !ok {
+		return
+	}
+	todo, ok := s.getTodo(w, r, id)
+	if !ok {
+		return
+	}
+	if member.Id == todo.Owner {
+		invalid(w, r, "user", "%s already owns the todo", member.Name)
+		return
+	}
+
+	if s.shareTodo(w, r, id, map[string]types.Role{member.Id: role}) {
+		writeJSON(w, RoutePutTodoMember.Status, types.Member{UserId: member.Id, Name: member.Name, Role: role})
+	}
+}
+
+// DeleteTodoMember stops sharing the todo with a user.
+func (s *TodoServer) DeleteTodoMember(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "DeleteTodoMember", map[string]string{"todo_id": id, "user": r.PathValue("user")})
+
+	if _, ok := s.requireUser(w, r); !ok {
+		return
+	}
+	member, ok := s.memberUser(w, r)
+	if !ok {
+		return
+	}
+	todo, ok := s.getTodo(w, r, id)
+	if !ok {
+		return
+	}
+	if _, ok := todo.Members[member.Id]; !ok {
+		writeError(w, r, types.NotFoundf("the todo isn't shared with %s", member.Name))
+		return
+	}
+
+	if s.shareTodo(w, r, id, map[string]types.Role{member.Id: ""}) {
+		w.WriteHeader(RouteDeleteTodoMember.Status)
+	}
+}
+
+// GetListMembers responds with the list's owner and who it's shared with.
+func (s *TodoServer) GetListMembers(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "GetListMembers", map[string]string{"list": r.PathValue("list")})
+
+	list, ok := s.listRef(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, RouteGetListMembers.Status, s.members(list.Owner, s.sharing.Members(list)))
+}
+
+// PutListMember shares every todo in the list with a user, including those
+// added to it later, or changes their role on it.
+func (s *TodoServer) PutListMember(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "PutListMember", map[string]string{"list": r.PathValue("list"), "user": r.PathValue("user")})
+
+	list, ok := s.listRef(w, r)
+	if !ok {
+		return
+	}
+	role, ok := decodeRole(w, r)
+	if !ok {
+		return
+	}
+	member, ok := s.memberUser(w, r)
+	if !ok {
+		return
+	}
+	if member.Id == list.Owner {
+		invalid(w, r, "user", "%s already owns the list", member.Name)
+		return
+	}
+
+	if err := s.sharing.Share(list, member.Id, role); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, RoutePutListMember.Status, types.Member{UserId: member.Id, Name: member.Name, Role: role})
+}
+
+// DeleteListMember stops sharing the list with a user.
+func (s *TodoServer) DeleteListMember(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "DeleteListMember", map[string]string{"list": r.PathValue("list"), "user": r.PathValue("user")})
+
+	list, ok := s.listRef(w, r)
+	if !ok {
+		return
+	}
+	member, ok := s.memberUser(w, r)
+	if !ok {
+		return
+	}
+	if err := s.sharing.Unshare(list, member.Id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(RouteDeleteListMember.Status)
+}
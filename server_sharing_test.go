@@ -0,0 +1,160 @@
+package todoapp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+func TestSharing(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	newUser := func(name string) (users.User, *TodoAPIClient) {
+		user, _ := userStore.AddUser(name, false)
+		_, token, _ := userStore.CreateToken(user.Id, "")
+		return user, NewTodoAPIClient(ts.URL+APIPrefix, WithToken(token))
+	}
+	alice, aliceClient := newUser("alice")
+	bob, bobClient := newUser("bob")
+	_, carolClient := newUser("carol")
+
+	t.Run("A todo shared with a viewer can be seen but not changed", func(t *testing.T) {
+		id, _ := aliceClient.AddTodo(types.NewTodo("Book the venue", nil))
+		if _, err := aliceClient.ShareTodo(id, "bob", types.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := bobClient.GetTodo(id); err != nil {
+			t.Errorf("got %v want bob to see the todo", err)
+		}
+		if err := bobClient.UpdateTodoStatus(id, types.Started); !errors.Is(err, ErrForbidden) {
+			t.Errorf("got %v want ErrForbidden", err)
+		}
+		if _, err := carolClient.GetTodo(id); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want carol not to see the todo", err)
+		}
+
+		aliceClient.ShareTodo(id, "bob", types.RoleEditor)
+		if err := bobClient.UpdateTodoStatus(id, types.Started); err != nil {
+			t.Errorf("got %v want an editor to change the status", err)
+		}
+		if _, err := bobClient.ShareTodo(id, "carol", types.RoleViewer); !errors.Is(err, ErrForbidden) {
+			t.Errorf("got %v want only owners to share", err)
+		}
+
+		members, err := bobClient.TodoMembers(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []types.Member{{UserId: alice.Id, Name: "alice", Role: types.RoleOwner}, {UserId: bob.Id, Name: "bob", Role: types.RoleEditor}}
+		if len(members) != 2 || members[0] != want[0] || members[1] != want[1] {
+			t.Errorf("got %+v want %+v", members, want)
+		}
+
+		if err := aliceClient.UnshareTodo(id, "bob"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bobClient.GetTodo(id); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want bob not to see the todo once it's unshared", err)
+		}
+	})
+
+	t.Run("A shared list shares every todo in it", func(t *testing.T) {
+		aliceClient.AddTodo(types.Todo{Description: "Write the report", List: "work"})
+		aliceClient.AddTodo(types.Todo{Description: "Water the plants", List: "home"})
+		if _, err := aliceClient.ShareList("", "work", "carol", types.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := carolClient.QueryTodos(types.TodoQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Todos) != 1 || page.Todos[0].Description != "Write the report" {
+			t.Errorf("got %+v want only the work todo", page.Todos)
+		}
+
+		members, err := carolClient.ListMembers("alice", "work")
+		if err != nil || len(members) != 2 || members[1].Name != "carol" {
+			t.Errorf("got %+v and error %v want alice and carol", members, err)
+		}
+		if _, err := carolClient.ShareList("alice", "work", "bob", types.RoleViewer); !errors.Is(err, ErrForbidden) {
+			t.Errorf("got %v want only owners to share the list", err)
+		}
+		if _, err := carolClient.ListMembers("alice", "home"); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("got %v want a list that isn't shared to be not found", err)
+		}
+	})
+
+	t.Run("Todos can be assigned and listed by assignee", func(t *testing.T) {
+		id, err := aliceClient.AddTodo(types.Todo{Description: "Order the food", Assignee: bob.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := bobClient.QueryTodos(types.TodoQuery{Assignee: "me"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Todos) != 1 || page.Todos[0].Id != id {
+			t.Errorf("got %+v want the todo assigned to bob", page.Todos)
+		}
+		if err := bobClient.UpdateTodoStatus(id, types.Completed); err != nil {
+			t.Errorf("got %v want the assignee to change the status", err)
+		}
+
+		_, err = aliceClient.AddTodo(types.Todo{Description: "Order the cake", Assignee: "nobody"})
+		if !errors.Is(err, types.ErrInvalid) {
+			t.Errorf("got %v want an unknown assignee to be invalid", err)
+		}
+	})
+
+	t.Run("Bulk changes need the role for each op", func(t *testing.T) {
+		id, _ := aliceClient.AddTodo(types.NewTodo("Send the invites", nil))
+		aliceClient.ShareTodo(id, "bob", types.RoleEditor)
+
+		results, err := bobClient.Bulk([]types.BulkOp{
+			{Op: types.BulkUpdate, Id: id, Status: types.Started},
+			{Op: types.BulkDelete, Id: id},
+		})
+		if !errors.Is(err, types.ErrBulkFailed) {
+			t.Fatalf("got %v want the batch to fail", err)
+		}
+		if results[0].Error != types.BulkNotApplied || !strings.Contains(results[1].Error, "owner") {
+			t.Errorf("got %+v want the delete to need the owner role", results)
+		}
+	})
+
+	t.Run("Moving a todo to another list needs the owner", func(t *testing.T) {
+		id, _ := aliceClient.AddTodo(types.Todo{Description: "Print the agenda", List: "work"})
+		aliceClient.ShareTodo(id, "bob", types.RoleEditor)
+
+		same, other := "work", "home"
+		if _, err := bobClient.Bulk([]types.BulkOp{{Op: types.BulkUpdate, Id: id, List: &same}}); err != nil {
+			t.Errorf("got %v want an editor to send the list unchanged", err)
+		}
+		results, err := bobClient.Bulk([]types.BulkOp{{Op: types.BulkUpdate, Id: id, List: &other}})
+		if !errors.Is(err, types.ErrBulkFailed) || !strings.Contains(results[0].Error, "owner") {
+			t.Errorf("got %+v and error %v want the move to need the owner role", results, err)
+		}
+		if _, err := aliceClient.Bulk([]types.BulkOp{{Op: types.BulkUpdate, Id: id, List: &other}}); err != nil {
+			t.Errorf("got %v want the owner to move the todo", err)
+		}
+	})
+
+	t.Run("Sharing needs a server with users", func(t *testing.T) {
+		open := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+		response := httptest.NewRecorder()
+		open.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/lists/work/members", nil))
+		assertProblem(t, response, http.StatusNotFound, ProblemNotAvailable)
+	})
+}
@@ -27,6 +27,7 @@ func TestGETTodos(t *testing.T) {
 		[]types.Status{},
 		0,
 		0,
+		nil,
 	}
 	server := NewTodoServer(stores.NewTodoStoreActor(&store))
 
@@ -76,6 +77,7 @@ func TestStoreTodos(t *testing.T) {
 		[]types.Status{},
 		0,
 		0,
+		nil,
 	}
 	server := NewTodoServer(stores.NewTodoStoreActor(&store))
 
@@ -85,7 +87,10 @@ func TestStoreTodos(t *testing.T) {
 
 		server.ServeHTTP(response, request)
 
-		assertStatus(t, response.Code, http.StatusAccepted)
+		assertStatus(t, response.Code, http.StatusCreated)
+		if got := response.Header().Get("Location"); got != "/api/todos/stub-id" {
+			t.Errorf("got location %q want /api/todos/stub-id", got)
+		}
 
 		if len(store.addCalls) != 1 {
 			t.Errorf("got %d calls to AddTodo want %d", len(store.addCalls), 1)
@@ -106,6 +111,7 @@ func TestPUTTodos(t *testing.T) {
 		[]types.Status{},
 		0,
 		0,
+		nil,
 	}
 	server := NewTodoServer(stores.NewTodoStoreActor(&store))
 
@@ -158,10 +164,15 @@ type StubTodoStore struct {
 	statusCalls  []types.Status
 	overdueCalls int
 	allCalls     int
+	// addErr is returned by AddTodo if it's set
+	addErr error
 }
 
 func (s *StubTodoStore) AddTodo(ctx context.Context, todo types.Todo) (string, error) {
 	s.addCalls = append(s.addCalls, todo)
+	if s.addErr != nil {
+		return "", s.addErr
+	}
 	return "stub-id", nil
 }
 
@@ -169,7 +180,7 @@ func (s *StubTodoStore) GetTodo(ctx context.Context, id string) (types.Todo, err
 	if todo, ok := s.todos[id]; ok {
 		return todo, nil
 	} else {
-		return types.Todo{}, fmt.Errorf("todo not found")
+		return types.Todo{}, types.NotFoundf("todo not found")
 	}
 }
 
@@ -195,3 +206,15 @@ func (s *StubTodoStore) GetAllTodos(ctx context.Context) map[string]types.Todo {
 	s.allCalls++
 	return map[string]types.Todo{}
 }
+
+func (s *StubTodoStore) QueryTodos(ctx context.Context, q types.TodoQuery) (types.TodoPage, error) {
+	return types.QueryTodos(s.todos, q)
+}
+
+func (s *StubTodoStore) ApplyBulk(ctx context.Context, ops []types.BulkOp) ([]types.BulkResult, error) {
+	todos, results, err := types.ApplyBulk(s.todos, ops, func() string { return "stub-id" })
+	if err == nil {
+		s.todos = todos
+	}
+	return results, err
+}
@@ -0,0 +1,81 @@
+package todoapp
+
+import (
+	"net/http"
+
+	"grantjames.github.io/todo-app/users"
+)
+
+// newToken is how a token is shown when it's created, the only time the
+// token itself is returned.
+type newToken struct {
+	users.Token
+	Secret string `json:"token"`
+}
+
+// requireUser is the user making the request, for endpoints that only make
+// sense with users. If the server doesn't have users it responds with a
+// problem and returns false.
+func (s *TodoServer) requireUser(w http.ResponseWriter, r *http.Request) (users.User, bool) {
+	user, ok := userFrom(r)
+	if !ok {
+		problem(w, r, http.StatusNotFound, ProblemNotAvailable, "this server doesn't have users")
+	}
+	return user, ok
+}
+
+// ListTokens responds with the user's tokens, without the tokens themselves.
+func (s *TodoServer) ListTokens(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "ListTokens", nil)
+
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := s.users.Tokens(user.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, RouteListTokens.Status, tokens)
+}
+
+// AddToken creates another token for the user, such as one for each device.
+func (s *TodoServer) AddToken(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "AddToken", nil)
+
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !decodeJSON(w, r, &req, MaxBodySize) {
+		return
+	}
+
+	token, secret, err := s.users.CreateToken(user.Id, req.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, RouteAddToken.Status, newToken{Token: token, Secret: secret})
+}
+
+// DeleteToken revokes one of the user's tokens, which can be the one the
+// request was made with.
+func (s *TodoServer) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "DeleteToken", map[string]string{"token_id": id})
+
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	if err := s.users.RevokeToken(user.Id, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(RouteDeleteToken.Status)
+}
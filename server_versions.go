@@ -0,0 +1,131 @@
+package todoapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+var (
+	// V1Deprecated is when v1 was deprecated in favour of v2, and V1Sunset
+	// when it may stop being served.
+	V1Deprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	V1Sunset     = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
+type routePrefixKey struct{}
+
+// registerRoutes registers the version's routes under each of prefixes,
+// wrapping their handlers with wrap if it isn't nil. It panics if a route
+// has no handler.
+func registerRoutes(router *http.ServeMux, version APIVersion, handlers map[Route]http.HandlerFunc, wrap func(Route, http.Handler) http.Handler, prefixes ...string) {
+	for _, route := range version.Routes() {
+		handler, ok := handlers[route]
+		if !ok {
+			panic("no handler for " + string(version) + " route " + route.Name)
+		}
+		for _, prefix := range prefixes {
+			var h http.Handler = handler
+			if wrap != nil {
+				h = wrap(route, h)
+			}
+			router.Handle(route.Pattern(prefix), withRoutePrefix(prefix, h))
+		}
+	}
+}
+
+func withRoutePrefix(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routePrefixKey{}, prefix)))
+	})
+}
+
+// routePrefix is the prefix the request was routed under, such as /api/v2,
+// for building URLs to other routes in the same version.
+func routePrefix(r *http.Request) string {
+	if prefix, ok := r.Context().Value(routePrefixKey{}).(string); ok {
+		return prefix
+	}
+	return APIPrefix
+}
+
+// deprecated adds the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// for v1 to the route's responses, along with a link to the route in v2 if
+// it has one.
+func deprecated(route Route, next http.Handler) http.Handler {
+	successor := slices.ContainsFunc(RoutesV2, func(r Route) bool { return r.Name == route.Name })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", V1Deprecated.Unix()))
+		w.Header().Set("Sunset", V1Sunset.Format(http.TimeFormat))
+		if successor {
+			path := APIv2.Prefix() + strings.TrimPrefix(r.URL.Path, routePrefix(r))
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", path))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// todoV2 is how v2 represents a todo: always with its id, and with fields
+// derived from it so clients don't have to work them out.
+type todoV2 struct {
+	types.TodoItem
+	Overdue bool   `json:"overdue"`
+	URL     string `json:"url"`
+}
+
+func newTodoV2(id string, todo types.Todo) todoV2 {
+	return todoV2{
+		TodoItem: types.TodoItem{Id: id, Todo: todo},
+		Overdue:  todo.IsOverdue(),
+		URL:      RouteGetTodo.URL(APIv2.Prefix(), nil, id),
+	}
+}
+
+func todosV2(items []types.TodoItem) []todoV2 {
+	todos := make([]todoV2, len(items))
+	for i, item := range items {
+		todos[i] = newTodoV2(item.Id, item.Todo)
+	}
+	return todos
+}
+
+// ListTodosV2 responds with an ordered page of todos. When there are more,
+// the next page's cursor is in the X-Next-Cursor and Link headers. There's
+// no map format in v2, so asking for one is like asking for JSON.
+func (s *TodoServer) ListTodosV2(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "ListTodosV2", map[string]string{"query": r.URL.RawQuery})
+
+	q, err := parseTodoQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.writeTodoPage(w, r, q, APIv2)
+}
+
+func (s *TodoServer) GetTodoV2(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if todo, ok := s.getTodo(w, r, id); ok {
+		writeTodos(w, r, RouteGetTodo.Status, newTodoV2(id, todo))
+	}
+}
+
+func (s *TodoServer) AddTodoV2(w http.ResponseWriter, r *http.Request) {
+	if item, ok := s.addTodo(w, r); ok {
+		writeTodos(w, r, RouteAddTodo.Status, newTodoV2(item.Id, item.Todo))
+	}
+}
+
+// UpdateTodoStatusV2 responds with the changed todo, rather than v1's empty
+// 202 Accepted.
+func (s *TodoServer) UpdateTodoStatusV2(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if todo, ok := s.updateTodoStatus(w, r, id); ok {
+		writeTodos(w, r, RouteV2UpdateTodoStatus.Status, newTodoV2(id, todo))
+	}
+}
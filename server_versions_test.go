@@ -0,0 +1,102 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestAPIVersions(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	past := time.Now().AddDate(0, 0, -2)
+	id, _ := store.AddTodo(t.Context(), types.NewTodo("Renew the passport", &past))
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set("Accept", TodoMapMediaType)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	for _, prefix := range []string{APIPrefix, APIv1.Prefix()} {
+		t.Run("v1 at "+prefix+" is marked deprecated", func(t *testing.T) {
+			response := serve(http.MethodGet, prefix+"/todos/"+id, "")
+			assertStatus(t, response.Code, http.StatusOK)
+			if response.Header().Get("Deprecation") == "" || response.Header().Get("Sunset") == "" {
+				t.Errorf("got headers %v want Deprecation and Sunset", response.Header())
+			}
+			want := `</api/v2/todos/` + id + `>; rel="successor-version"`
+			if got := response.Header().Get("Link"); got != want {
+				t.Errorf("got Link %q want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("v2 isn't deprecated", func(t *testing.T) {
+		response := serve(http.MethodGet, "/api/v2/todos/"+id, "")
+		if response.Header().Get("Deprecation") != "" {
+			t.Errorf("got Deprecation %q want none", response.Header().Get("Deprecation"))
+		}
+	})
+
+	t.Run("v2 todos include their id and derived fields", func(t *testing.T) {
+		response := serve(http.MethodGet, "/api/v2/todos/"+id, "")
+		assertStatus(t, response.Code, http.StatusOK)
+		got := decodeTodoV2(t, response)
+		if got.Id != id || !got.Overdue || got.URL != "/api/v2/todos/"+id {
+			t.Errorf("got %+v want todo %s, overdue, with its v2 url", got, id)
+		}
+	})
+
+	t.Run("v2 lists are arrays even when a map is asked for", func(t *testing.T) {
+		response := serve(http.MethodGet, "/api/v2/todos/", "")
+		assertStatus(t, response.Code, http.StatusOK)
+		var got []todoV2
+		if err := json.NewDecoder(response.Body).Decode(&got); err != nil || len(got) != 1 || got[0].Id != id {
+			t.Errorf("got %v and error %v want an array of the one todo", got, err)
+		}
+	})
+
+	t.Run("v2 responds to a status update with the changed todo", func(t *testing.T) {
+		response := serve(http.MethodPut, "/api/v2/todos/"+id, `{"status": "Completed"}`)
+		assertStatus(t, response.Code, http.StatusOK)
+		got := decodeTodoV2(t, response)
+		if got.Status != types.Completed || got.Overdue {
+			t.Errorf("got %+v want a completed todo that's no longer overdue", got)
+		}
+	})
+
+	t.Run("v1 still accepts a status update without a body", func(t *testing.T) {
+		response := serve(http.MethodPut, "/api/todos/"+id, `{"status": "Started"}`)
+		assertStatus(t, response.Code, http.StatusAccepted)
+		if response.Body.Len() != 0 {
+			t.Errorf("got body %q want none", response.Body)
+		}
+	})
+
+	t.Run("Created todos are located in the same version", func(t *testing.T) {
+		response := serve(http.MethodPost, "/api/v2/todos/", `{"description": "Book the ferry"}`)
+		assertStatus(t, response.Code, http.StatusCreated)
+		got := decodeTodoV2(t, response)
+		if location := response.Header().Get("Location"); location != got.URL {
+			t.Errorf("got Location %q want %q", location, got.URL)
+		}
+	})
+}
+
+func decodeTodoV2(t testing.TB, response *httptest.ResponseRecorder) todoV2 {
+	t.Helper()
+	var got todoV2
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("Unable to parse response from server %q into a todo, '%v'", response.Body, err)
+	}
+	return got
+}
@@ -0,0 +1,126 @@
+package todoapp
+
+import (
+	"net/http"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// viewOwner is whose views a request can see and change: the user making
+// it, or nobody's on a server without users.
+func viewOwner(r *http.Request) string {
+	user, _ := userFrom(r)
+	return user.Id
+}
+
+// ListViews responds with the views of the user making the request.
+func (s *TodoServer) ListViews(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "ListViews", nil)
+	writeJSON(w, http.StatusOK, s.views.Views(viewOwner(r)))
+}
+
+// AddView saves a new view. Use PUT /api/views/{name} to replace one.
+func (s *TodoServer) AddView(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "AddView", nil)
+
+	var view views.View
+	if !decodeJSON(w, r, &view, MaxBodySize) {
+		return
+	}
+	if _, err := s.views.View(viewOwner(r), view.Name); err == nil {
+		writeError(w, r, types.Conflictf("view %q already exists", view.Name))
+		return
+	}
+
+	view, _, err := s.views.Put(viewOwner(r), view)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", RouteGetView.URL(routePrefix(r), nil, view.Name))
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (s *TodoServer) GetView(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logEndpointCall(r, "GetView", map[string]string{"view": name})
+
+	view, err := s.views.View(viewOwner(r), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// PutView creates or replaces the named view.
+func (s *TodoServer) PutView(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logEndpointCall(r, "PutView", map[string]string{"view": name})
+
+	var view views.View
+	if !decodeJSON(w, r, &view, MaxBodySize) {
+		return
+	}
+	if view.Name != "" && view.Name != name {
+		invalid(w, r, "name", "doesn't match the URL")
+		return
+	}
+	view.Name = name
+
+	view, created, err := s.views.Put(viewOwner(r), view)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if created {
+		writeJSON(w, http.StatusCreated, view)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *TodoServer) DeleteView(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logEndpointCall(r, "DeleteView", map[string]string{"view": name})
+
+	if err := s.views.Delete(viewOwner(r), name); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetViewTodos runs the named view, responding like the list endpoint with
+// pages of limit todos.
+func (s *TodoServer) GetViewTodos(w http.ResponseWriter, r *http.Request) {
+	s.getViewTodos(w, r, APIv1)
+}
+
+// GetViewTodosV2 is GetViewTodos with todos in v2's representation.
+func (s *TodoServer) GetViewTodosV2(w http.ResponseWriter, r *http.Request) {
+	s.getViewTodos(w, r, APIv2)
+}
+
+func (s *TodoServer) getViewTodos(w http.ResponseWriter, r *http.Request, version APIVersion) {
+	name := r.PathValue("name")
+	logEndpointCall(r, "GetViewTodos", map[string]string{"view": name, "query": r.URL.RawQuery})
+
+	view, err := s.views.View(viewOwner(r), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	q, err := view.Query()
+	if err != nil {
+		// Only possible if the file was edited by hand
+		internalError(w, r, err)
+		return
+	}
+	if err := parsePage(r.URL.Query(), &q); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.writeTodoPage(w, r, q, version)
+}
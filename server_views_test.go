@@ -0,0 +1,127 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+	"grantjames.github.io/todo-app/views"
+)
+
+func TestViewsAPI(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	ops := types.NewTodo("Rotate the ops keys", nil)
+	ops.Tags = []string{"ops"}
+	store.AddTodo(t.Context(), ops)
+	store.AddTodo(t.Context(), types.NewTodo("Water the plants", nil))
+	server := NewTodoServer(stores.NewTodoStoreActor(store))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return response
+	}
+
+	t.Run("Creates a view and lists its todos", func(t *testing.T) {
+		response := serve(http.MethodPost, "/api/views", `{"name": "ops", "filter": "tag:ops", "sort": "-created"}`)
+		assertStatus(t, response.Code, http.StatusCreated)
+		if got := response.Header().Get("Location"); got != "/api/views/ops" {
+			t.Errorf("got location %q want /api/views/ops", got)
+		}
+
+		response = serve(http.MethodGet, "/api/views/ops/todos", "")
+		assertStatus(t, response.Code, http.StatusOK)
+		var todos []types.TodoItem
+		json.NewDecoder(response.Body).Decode(&todos)
+		if len(todos) != 1 || todos[0].Description != "Rotate the ops keys" {
+			t.Errorf("got %v want just the ops todo", todos)
+		}
+	})
+
+	t.Run("Creating a view that exists is a conflict", func(t *testing.T) {
+		response := serve(http.MethodPost, "/api/views", `{"name": "ops"}`)
+		assertStatus(t, response.Code, http.StatusConflict)
+	})
+
+	t.Run("Replaces a view with PUT", func(t *testing.T) {
+		response := serve(http.MethodPut, "/api/views/ops", `{"filter": "NOT tag:ops", "columns": ["id", "description"]}`)
+		assertStatus(t, response.Code, http.StatusOK)
+
+		todos, err := NewTodoAPIClient(ts.URL + "/api").GetViewTodos("ops")
+		if err != nil || len(todos) != 1 || todos[0].Description != "Water the plants" {
+			t.Errorf("got %v and error %v want just the plants todo", todos, err)
+		}
+	})
+
+	t.Run("Rejects views that don't parse", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name": "bad name"}`,
+			`{"name": "bad", "filter": "colour:red"}`,
+			`{"name": "bad", "sort": "colour"}`,
+			`{"name": "bad", "columns": ["colour"]}`,
+		} {
+			response := serve(http.MethodPost, "/api/views", body)
+			assertStatus(t, response.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Deletes views", func(t *testing.T) {
+		assertStatus(t, serve(http.MethodDelete, "/api/views/ops", "").Code, http.StatusNoContent)
+		assertStatus(t, serve(http.MethodGet, "/api/views/ops", "").Code, http.StatusNotFound)
+		assertStatus(t, serve(http.MethodGet, "/api/views/ops/todos", "").Code, http.StatusNotFound)
+	})
+}
+
+func TestViewsBelongToTheirUser(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore))
+
+	tokenFor := func(name string) string {
+		user, _ := userStore.AddUser(name, false)
+		_, token, _ := userStore.CreateToken(user.Id, "")
+		return token
+	}
+	alice, bob := tokenFor("alice"), tokenFor("bob")
+	serve := func(token, method, path, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer "+token)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response
+	}
+
+	assertStatus(t, serve(alice, http.MethodPost, "/api/views", `{"name": "ops", "filter": "tag:ops"}`).Code, http.StatusCreated)
+
+	t.Run("Other users can't see a view", func(t *testing.T) {
+		response := serve(bob, http.MethodGet, "/api/views", "")
+		var got []views.View
+		json.NewDecoder(response.Body).Decode(&got)
+		if len(got) != 0 {
+			t.Errorf("got %+v want bob to have no views", got)
+		}
+		assertStatus(t, serve(bob, http.MethodGet, "/api/views/ops", "").Code, http.StatusNotFound)
+		assertStatus(t, serve(bob, http.MethodGet, "/api/views/ops/todos", "").Code, http.StatusNotFound)
+	})
+
+	t.Run("Other users can't delete a view", func(t *testing.T) {
+		assertStatus(t, serve(bob, http.MethodDelete, "/api/views/ops", "").Code, http.StatusNotFound)
+		assertStatus(t, serve(alice, http.MethodGet, "/api/views/ops", "").Code, http.StatusOK)
+	})
+
+	t.Run("Users can have views with the same name", func(t *testing.T) {
+		assertStatus(t, serve(bob, http.MethodPut, "/api/views/ops", `{"filter": "tag:work"}`).Code, http.StatusCreated)
+
+		var view views.View
+		json.NewDecoder(serve(alice, http.MethodGet, "/api/views/ops", "").Body).Decode(&view)
+		if view.Filter != "tag:ops" {
+			t.Errorf("got %+v want alice's view unchanged", view)
+		}
+	})
+}
@@ -0,0 +1,458 @@
+package todoapp
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/sessions"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/web"
+)
+
+const (
+	// SessionCookie holds the id of the browser's session
+	SessionCookie = "todo_session"
+	// NonceCookie holds the nonce of a signed out browser's CSRF token, see
+	// sessions.Store.SignedOut
+	NonceCookie = "todo_nonce"
+	// CSRFField is the form field every form is sent with its session's
+	// CSRF token in
+	CSRFField = "csrf_token"
+
+	// datetimeLocal is the format of a datetime-local input's value
+	datetimeLocal = "2006-01-02T15:04"
+)
+
+// webPages are parsed once, when the server starts, from the templates
+// embedded in the binary.
+var webPages = parseWebPages("login.html", "list.html", "edit.html")
+
+func parseWebPages(names ...string) map[string]*template.Template {
+	funcs := template.FuncMap{
+		"datetime":      func(t time.Time) string { return t.Local().Format("2 Jan 2006 15:04") },
+		"datetimeLocal": func(t time.Time) string { return t.Local().Format(datetimeLocal) },
+	}
+	pages := map[string]*template.Template{}
+	for _, name := range names {
+		pages[name] = template.Must(template.New(name).Funcs(funcs).ParseFS(web.Templates, "layout.html", name))
+	}
+	return pages
+}
+
+// webPage is what the templates are executed with. Only the fields the page
+// uses are set.
+type webPage struct {
+	Title string
+	// User is the name of the user who's signed in, if the server has users
+	User      string
+	CSRFToken string
+	Flashes   []sessions.Flash
+
+	// list.html
+	Todos []webTodo
+	All   bool
+	More  bool
+	// edit.html
+	Todo     types.TodoItem
+	Statuses []types.Status
+	// login.html
+	Next string
+}
+
+// webTodo is a todo in the list, with what the user can do to it.
+type webTodo struct {
+	types.TodoItem
+	CanEdit   bool
+	CanDelete bool
+}
+
+// webHandler handles a request for a web page, from the browser with the
+// session.
+type webHandler func(w http.ResponseWriter, r *http.Request, session sessions.Session)
+
+// registerWebPages serves the web pages alongside the API, along with the
+// static files they use.
+func (s *TodoServer) registerWebPages(router *http.ServeMux) {
+	router.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(web.Static)))
+	router.Handle("GET /about/", http.FileServerFS(web.Static))
+	router.Handle("GET /list", http.RedirectHandler("/", http.StatusMovedPermanently))
+
+	router.HandleFunc("GET /login", s.LoginPage)
+	router.HandleFunc("POST /login", s.Login)
+	router.HandleFunc("POST /logout", s.signedIn(s.Logout))
+	router.HandleFunc("GET /{$}", s.signedIn(s.ListPage))
+	router.HandleFunc("POST /todos", s.signedIn(s.AddTodoForm))
+	router.HandleFunc("GET /todos/{id}/edit", s.signedIn(s.EditTodoPage))
+	router.HandleFunc("POST /todos/{id}/edit", s.signedIn(s.EditTodoForm))
+	router.HandleFunc("POST /todos/{id}/complete", s.signedIn(s.CompleteTodoForm))
+	router.HandleFunc("POST /todos/{id}/delete", s.signedIn(s.DeleteTodoForm))
+}
+
+// signedIn has a page redirect to the login page unless the browser is
+// signed in, when the server has users, in which case the request is scoped
+// to the user like API requests are. Forms must be sent with the session's
+// CSRF token.
+func (s *TodoServer) signedIn(next webHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := s.webSession(w, r)
+		if r.Method == http.MethodPost && !checkCSRF(w, r, session) {
+			return
+		}
+		if s.users != nil {
+			user, err := s.users.User(session.UserId)
+			if err != nil {
+				// Not signed in, or the user has since been removed
+				login := url.URL{Path: "/login"}
+				if r.Method == http.MethodGet {
+					login.RawQuery = url.Values{"next": {r.URL.RequestURI()}}.Encode()
+				}
+				http.Redirect(w, r, login.String(), http.StatusSeeOther)
+				return
+			}
+			r = s.withUser(r, user)
+		}
+		next(w, r, session)
+	}
+}
+
+// webSession is the browser's session. One that doesn't have a session
+// stored gets a signed out one that isn't, and is given a nonce cookie for
+// its CSRF token if it doesn't have one.
+func (s *TodoServer) webSession(w http.ResponseWriter, r *http.Request) sessions.Session {
+	if c, err := r.Cookie(SessionCookie); err == nil {
+		if session, ok := s.sessions.Get(c.Value); ok {
+			return session
+		}
+	}
+	if c, err := r.Cookie(NonceCookie); err == nil && c.Value != "" {
+		return s.sessions.SignedOut(c.Value)
+	}
+	nonce := sessions.NewNonce()
+	s.setCookie(w, r, NonceCookie, nonce, time.Time{})
+	return s.sessions.SignedOut(nonce)
+}
+
+// startSession creates a session for the user with the id, or a signed out
+// one, and gives the browser its cookie.
+func (s *TodoServer) startSession(w http.ResponseWriter, r *http.Request, userId string) sessions.Session {
+	session := s.sessions.Create(userId)
+	s.setCookie(w, r, SessionCookie, session.Id, session.Expires)
+	return session
+}
+
+// setCookie gives the browser a cookie that can't be read by scripts, isn't
+// sent along with requests from other sites, other than for following a
+// link, and is only sent over HTTPS if the server is served over it.
+func (s *TodoServer) setCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.secureCookies || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkCSRF parses the form, which must have the session's CSRF token. If
+// it doesn't, it responds with a problem and returns false.
+func checkCSRF(w http.ResponseWriter, r *http.Request, session sessions.Session) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodySize)
+	if err := r.ParseForm(); err != nil {
+		problem(w, r, http.StatusBadRequest, ProblemBadRequest, "couldn't read the form, "+err.Error())
+		return false
+	}
+	if !session.CheckCSRF(r.PostForm.Get(CSRFField)) {
+		problem(w, r, http.StatusForbidden, ProblemForbidden, "the form is out of date, go back, reload the page and try again")
+		return false
+	}
+	return true
+}
+
+// render executes the page's template, taking the session's flashes. It's
+// executed into a buffer first so a failure can still be responded to
+// properly.
+func (s *TodoServer) render(w http.ResponseWriter, r *http.Request, session sessions.Session, name string, page webPage) {
+	page.CSRFToken = session.CSRFToken
+	page.Flashes = s.sessions.TakeFlashes(session.Id)
+	if user, ok := userFrom(r); ok {
+		page.User = user.Name
+	}
+
+	var buf bytes.Buffer
+	if err := webPages[name].ExecuteTemplate(&buf, "layout", page); err != nil {
+		slog.ErrorContext(r.Context(), "template execute error", "template", name, "error", err.Error())
+		problem(w, r, http.StatusInternalServerError, ProblemInternal, "template error")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Pages have the session's CSRF token in them
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// redirect responds to a form by sending the browser to the page at path,
+// with a flash to show there. A signed out session is stored to keep it in.
+func (s *TodoServer) redirect(w http.ResponseWriter, r *http.Request, session sessions.Session, path string, f sessions.Flash) {
+	if session.Id == "" {
+		session = s.startSession(w, r, "")
+	}
+	s.sessions.AddFlash(session.Id, f)
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+func flashError(err error) sessions.Flash {
+	return sessions.Flash{Kind: sessions.FlashError, Message: err.Error()}
+}
+
+func flashInfo(message string) sessions.Flash {
+	return sessions.Flash{Kind: sessions.FlashInfo, Message: message}
+}
+
+// safeNext is where to go after signing in, which has to be a path on this
+// server so the login page can't be used to send people elsewhere.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// LoginPage shows the form for signing in, or goes straight on if there's
+// no need to.
+func (s *TodoServer) LoginPage(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "LoginPage", nil)
+
+	next := safeNext(r.URL.Query().Get("next"))
+	session := s.webSession(w, r)
+	if s.users == nil || session.UserId != "" {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	s.render(w, r, session, "login.html", webPage{Title: "Sign in", Next: next})
+}
+
+// Login signs the browser in with a user's name and password. It's given a
+// new session, so one from before signing in can't be used to act as the
+// user.
+func (s *TodoServer) Login(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "Login", nil)
+
+	session := s.webSession(w, r)
+	if !checkCSRF(w, r, session) {
+		return
+	}
+	next := safeNext(r.PostForm.Get("next"))
+	if s.users == nil {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	user, err := s.users.Login(r.PostForm.Get("name"), r.PostForm.Get("password"))
+	if err != nil {
+		login := "/login?" + url.Values{"next": {next}}.Encode()
+		s.redirect(w, r, session, login, flashError(err))
+		return
+	}
+	s.sessions.Delete(session.Id)
+	s.startSession(w, r, user.Id)
+	slog.InfoContext(r.Context(), "Signed in", slog.String("user_id", user.Id))
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// Logout ends the session, starting a signed out one to say so.
+func (s *TodoServer) Logout(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	logEndpointCall(r, "Logout", nil)
+
+	s.sessions.Delete(session.Id)
+	session = s.startSession(w, r, "")
+	s.redirect(w, r, session, "/login", flashInfo("Signed out"))
+}
+
+// ListPage shows the todos the user can see, with forms for adding and
+// changing them. Completed todos are hidden unless all is given.
+func (s *TodoServer) ListPage(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	logEndpointCall(r, "ListPage", map[string]string{"query": r.URL.RawQuery})
+
+	q := types.TodoQuery{All: r.URL.Query().Has("all"), Limit: MaxPageSize}
+	resp := make(chan types.QueryTodosResponse)
+	s.actor.Send(types.QueryTodosRequest{Ctx: r.Context(), Query: q, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor")
+		if res.Err != nil {
+			writeError(w, r, res.Err)
+			return
+		}
+		page := webPage{Title: "Todos", All: q.All, More: res.Page.NextCursor != "", Todos: []webTodo{}}
+		for _, item := range res.Page.Todos {
+			role, _ := item.Role(r.Context())
+			page.Todos = append(page.Todos, webTodo{
+				TodoItem:  item,
+				CanEdit:   role.Allows(types.RoleEditor),
+				CanDelete: role.Allows(types.RoleOwner),
+			})
+		}
+		s.render(w, r, session, "list.html", page)
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+	}
+}
+
+// AddTodoForm adds a todo from the list page's form.
+func (s *TodoServer) AddTodoForm(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	logEndpointCall(r, "AddTodoForm", nil)
+
+	todo := types.Todo{Description: r.PostForm.Get("description"), List: r.PostForm.Get("list")}
+	due, err := parseDueInput(r.PostForm.Get("due"))
+	if err != nil {
+		s.redirect(w, r, session, "/", flashError(err))
+		return
+	}
+	todo.Due = due
+
+	op := types.BulkOp{Op: types.BulkCreate, Todo: &todo}
+	if result, ok := s.applyForm(w, r, session, op); ok {
+		s.redirect(w, r, session, "/", flashInfo("Added "+result.Todo.Description))
+	}
+}
+
+// EditTodoPage shows the form for changing a todo.
+func (s *TodoServer) EditTodoPage(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "EditTodoPage", map[string]string{"todo_id": id})
+
+	todo, ok := s.editableTodo(w, r, session, id)
+	if !ok {
+		return
+	}
+	page := webPage{Title: "Edit todo", Todo: types.TodoItem{Id: id, Todo: todo}, Statuses: types.Statuses}
+	s.render(w, r, session, "edit.html", page)
+}
+
+// EditTodoForm changes a todo to what's in the edit page's form. A due date
+// can be changed but not removed.
+func (s *TodoServer) EditTodoForm(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "EditTodoForm", map[string]string{"todo_id": id})
+
+	todo, ok := s.editableTodo(w, r, session, id)
+	if !ok {
+		return
+	}
+	edit := "/todos/" + url.PathEscape(id) + "/edit"
+	due, err := parseDueInput(r.PostForm.Get("due"))
+	if err != nil {
+		s.redirect(w, r, session, edit, flashError(err))
+		return
+	}
+	description := r.PostForm.Get("description")
+	op := types.BulkOp{Op: types.BulkUpdate, Id: id, Description: &description, Due: due}
+	// Only a change of status moves the todo into it, and only a change of
+	// list needs the owner
+	if status := types.Status(r.PostForm.Get("status")); status != todo.Status {
+		op.Status = status
+	}
+	if list := r.PostForm.Get("list"); list != todo.List {
+		op.List = &list
+	}
+
+	if result, ok := s.applyForm(w, r, session, op); ok {
+		s.redirect(w, r, session, "/", flashInfo("Saved "+result.Todo.Description))
+	}
+}
+
+// CompleteTodoForm marks a todo as completed.
+func (s *TodoServer) CompleteTodoForm(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "CompleteTodoForm", map[string]string{"todo_id": id})
+
+	op := types.BulkOp{Op: types.BulkUpdate, Id: id, Status: types.Completed}
+	if result, ok := s.applyForm(w, r, session, op); ok {
+		s.redirect(w, r, session, "/", flashInfo("Completed "+result.Todo.Description))
+	}
+}
+
+// DeleteTodoForm deletes a todo.
+func (s *TodoServer) DeleteTodoForm(w http.ResponseWriter, r *http.Request, session sessions.Session) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "DeleteTodoForm", map[string]string{"todo_id": id})
+
+	op := types.BulkOp{Op: types.BulkDelete, Id: id}
+	if result, ok := s.applyForm(w, r, session, op); ok {
+		s.redirect(w, r, session, "/", flashInfo("Deleted "+result.Todo.Description))
+	}
+}
+
+// applyForm has the actor apply the change a form makes, as a bulk op so
+// the user's role on the todo is checked the same way as the API's. If it
+// can't, it sends the browser back to the list with the reason and returns
+// false.
+func (s *TodoServer) applyForm(w http.ResponseWriter, r *http.Request, session sessions.Session, op types.BulkOp) (types.BulkResult, bool) {
+	resp := make(chan types.BulkResponse)
+	s.actor.Send(types.BulkRequest{Ctx: r.Context(), Ops: []types.BulkOp{op}, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor", slog.String("todo_id", op.Id))
+		if errors.Is(res.Err, types.ErrBulkFailed) {
+			res.Err = errors.New(res.Results[0].Error)
+		}
+		if res.Err != nil {
+			s.redirect(w, r, session, "/", flashError(res.Err))
+			return types.BulkResult{}, false
+		}
+		return res.Results[0], true
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+		return types.BulkResult{}, false
+	}
+}
+
+// editableTodo fetches the todo from the actor, if the user can edit it. If
+// it can't, it sends the browser back to the list with the reason and
+// returns false.
+func (s *TodoServer) editableTodo(w http.ResponseWriter, r *http.Request, session sessions.Session, id string) (types.Todo, bool) {
+	resp := make(chan types.GetTodoResponse)
+	s.actor.Send(types.GetTodoRequest{Ctx: r.Context(), Id: id, Resp: resp})
+
+	select {
+	case res := <-resp:
+		slog.InfoContext(r.Context(), "Received response from actor", slog.String("todo_id", id))
+		err := res.Err
+		if err == nil {
+			err = res.Todo.Allow(r.Context(), types.RoleEditor)
+		}
+		if err != nil {
+			s.redirect(w, r, session, "/", flashError(err))
+			return types.Todo{}, false
+		}
+		return res.Todo, true
+	case <-r.Context().Done():
+		requestCanceled(w, r)
+		return types.Todo{}, false
+	}
+}
+
+// parseDueInput reads a datetime-local input, in the server's time zone,
+// which is nil if it was left empty.
+func parseDueInput(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	due, err := time.ParseInLocation(datetimeLocal, s, time.Local)
+	if err != nil {
+		return nil, types.Invalid("due", "%q isn't a date and time", s)
+	}
+	return &due, nil
+}
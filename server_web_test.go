@@ -0,0 +1,276 @@
+package todoapp
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// browser is a client that keeps cookies, like a browser does, and
+// remembers the last page it was shown.
+type browser struct {
+	t      *testing.T
+	base   string
+	client *http.Client
+	page   string
+}
+
+func newBrowser(t *testing.T, base string) *browser {
+	jar, _ := cookiejar.New(nil)
+	return &browser{t: t, base: base, client: &http.Client{Jar: jar}}
+}
+
+func (b *browser) get(path string) *http.Response {
+	b.t.Helper()
+	resp, err := b.client.Get(b.base + path)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return b.read(resp)
+}
+
+// submit posts a form with the CSRF token from the last page.
+func (b *browser) submit(path string, form url.Values) *http.Response {
+	b.t.Helper()
+	if match := csrfInput.FindStringSubmatch(b.page); match != nil && !form.Has(CSRFField) {
+		form.Set(CSRFField, match[1])
+	}
+	resp, err := b.client.PostForm(b.base+path, form)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return b.read(resp)
+}
+
+func (b *browser) read(resp *http.Response) *http.Response {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	b.page = string(body)
+	return resp
+}
+
+func (b *browser) assertPageContains(want string) {
+	b.t.Helper()
+	if !strings.Contains(b.page, want) {
+		b.t.Errorf("got page %s want it to contain %q", b.page, want)
+	}
+}
+
+func TestWebPages(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	alice, _ := userStore.AddUser("alice", false)
+	bob, _ := userStore.AddUser("bob", false)
+	userStore.SetPassword(alice.Id, "alice's password")
+	userStore.SetPassword(bob.Id, "bob's password")
+
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	b := newBrowser(t, ts.URL)
+
+	t.Run("Pages need signing in", func(t *testing.T) {
+		resp := b.get("/?all")
+		if resp.Request.URL.Path != "/login" || resp.Request.URL.Query().Get("next") != "/?all" {
+			t.Errorf("got sent to %s want the login page, then back", resp.Request.URL)
+		}
+		b.assertPageContains(`<input type="hidden" name="next" value="/?all">`)
+	})
+
+	t.Run("Signed out browsers aren't given a session", func(t *testing.T) {
+		resp, _ := http.Get(ts.URL + "/login")
+		resp.Body.Close()
+		cookies := resp.Cookies()
+		if len(cookies) != 1 || cookies[0].Name != NonceCookie {
+			t.Fatalf("got cookies %+v want only the nonce", cookies)
+		}
+		if !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+			t.Errorf("got %+v want an HttpOnly, SameSite=Lax cookie", cookies[0])
+		}
+	})
+
+	t.Run("The session cookie can't be read by scripts", func(t *testing.T) {
+		login := newBrowser(t, ts.URL)
+		login.get("/login")
+		login.client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		resp := login.submit("/login", url.Values{"name": {"alice"}, "password": {"alice's password"}})
+		var cookie *http.Cookie
+		for _, c := range resp.Cookies() {
+			if c.Name == SessionCookie {
+				cookie = c
+			}
+		}
+		if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+			t.Errorf("got %+v want an HttpOnly, SameSite=Lax session cookie", cookie)
+		}
+	})
+
+	t.Run("A wrong password is shown on the login page", func(t *testing.T) {
+		b.submit("/login", url.Values{"name": {"alice"}, "password": {"bob's password"}, "next": {"/"}})
+		b.assertPageContains(users.ErrInvalidLogin.Error())
+	})
+
+	t.Run("Signing in goes on to the page", func(t *testing.T) {
+		resp := b.submit("/login", url.Values{"name": {"alice"}, "password": {"alice's password"}, "next": {"/?all"}})
+		if resp.Request.URL.String() != ts.URL+"/?all" {
+			t.Errorf("got sent to %s want /?all", resp.Request.URL)
+		}
+		b.assertPageContains("Signed in as alice")
+	})
+
+	t.Run("Forms need the CSRF token", func(t *testing.T) {
+		for _, token := range []string{"", "not-the-token"} {
+			resp := b.submit("/todos", url.Values{CSRFField: {token}, "description": {"Forged"}})
+			assertStatus(t, resp.StatusCode, http.StatusForbidden)
+		}
+		b.get("/")
+		if strings.Contains(b.page, "Forged") {
+			t.Errorf("got a todo added by a form without the CSRF token")
+		}
+	})
+
+	t.Run("Todos can be added, edited and completed", func(t *testing.T) {
+		b.get("/")
+		b.submit("/todos", url.Values{"description": {"Buy <milk>"}, "due": {"2030-01-02T15:04"}})
+		b.assertPageContains("Added Buy &lt;milk&gt;")
+		b.assertPageContains("due 2 Jan 2030 15:04")
+
+		id := regexp.MustCompile(`/todos/([^/]+)/edit`).FindStringSubmatch(b.page)[1]
+		b.get("/todos/" + id + "/edit")
+		b.assertPageContains(`value="2030-01-02T15:04"`)
+		b.submit("/todos/"+id+"/edit", url.Values{"description": {"Buy oat milk"}, "status": {"Started"}, "due": {""}, "list": {"shopping"}})
+		b.assertPageContains("Saved Buy oat milk")
+		b.assertPageContains("Started")
+		b.assertPageContains("shopping")
+
+		b.submit("/todos/"+id+"/complete", url.Values{})
+		b.assertPageContains("Completed Buy oat milk")
+		if strings.Contains(b.page, `<p class="description">Buy oat milk</p>`) {
+			t.Errorf("got the completed todo in the list without all")
+		}
+		b.get("/?all")
+		b.assertPageContains(`<p class="description">Buy oat milk</p>`)
+	})
+
+	t.Run("Mistakes are shown as flashes", func(t *testing.T) {
+		b.get("/")
+		b.submit("/todos", url.Values{"description": {" "}})
+		b.assertPageContains(`class="flash error"`)
+		b.assertPageContains("description: is required")
+	})
+
+	t.Run("Other users can't see or change the todo", func(t *testing.T) {
+		other := newBrowser(t, ts.URL)
+		other.get("/login")
+		other.submit("/login", url.Values{"name": {"bob"}, "password": {"bob's password"}})
+		other.get("/?all")
+		if strings.Contains(other.page, "Buy oat milk") {
+			t.Errorf("got alice's todo in bob's list")
+		}
+
+		b.get("/?all")
+		id := regexp.MustCompile(`/todos/([^/]+)/delete`).FindStringSubmatch(b.page)[1]
+		other.submit("/todos/"+id+"/delete", url.Values{})
+		other.assertPageContains("no todo with id")
+	})
+
+	t.Run("Todos can be deleted", func(t *testing.T) {
+		b.get("/?all")
+		id := regexp.MustCompile(`/todos/([^/]+)/delete`).FindStringSubmatch(b.page)[1]
+		b.submit("/todos/"+id+"/delete", url.Values{})
+		b.assertPageContains("Deleted Buy oat milk")
+		if strings.Contains(b.page, "/todos/"+id) {
+			t.Errorf("got the deleted todo in the list")
+		}
+	})
+
+	t.Run("Signing out ends the session", func(t *testing.T) {
+		b.get("/")
+		resp := b.submit("/logout", url.Values{})
+		if resp.Request.URL.Path != "/login" {
+			t.Errorf("got sent to %s want the login page", resp.Request.URL)
+		}
+		b.assertPageContains("Signed out")
+		if resp := b.get("/"); resp.Request.URL.Path != "/login" {
+			t.Errorf("got %s after signing out want the login page", resp.Request.URL)
+		}
+	})
+
+	t.Run("Only paths on the server are gone on to", func(t *testing.T) {
+		for _, next := range []string{"https://example.com", "//example.com", `/\example.com`} {
+			if got := safeNext(next); got != "/" {
+				t.Errorf("got %q for %q want /", got, next)
+			}
+		}
+	})
+}
+
+func TestWebPagesWithoutUsers(t *testing.T) {
+	store := stores.NewInMemoryTodoStore()
+	store.AddTodo(t.Context(), types.NewTodo("Anyone's todo", nil))
+	ts := httptest.NewServer(NewTodoServer(stores.NewTodoStoreActor(store)))
+	defer ts.Close()
+	b := newBrowser(t, ts.URL)
+
+	t.Run("There's no need to sign in", func(t *testing.T) {
+		resp := b.get("/login")
+		if resp.Request.URL.Path != "/" {
+			t.Errorf("got sent to %s want the list", resp.Request.URL)
+		}
+		b.assertPageContains("Anyone&#39;s todo")
+	})
+
+	t.Run("The old list page and static files are served", func(t *testing.T) {
+		if resp := b.get("/list"); resp.Request.URL.Path != "/" {
+			t.Errorf("got sent to %s want the list", resp.Request.URL)
+		}
+		for _, path := range []string{"/about/", "/static/style.css"} {
+			resp := b.get(path)
+			assertStatus(t, resp.StatusCode, http.StatusOK)
+		}
+	})
+}
+
+func TestWebPagesForEditors(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	alice, _ := userStore.AddUser("alice", false)
+	bob, _ := userStore.AddUser("bob", false)
+	userStore.SetPassword(bob.Id, "bob's password")
+
+	store := stores.NewInMemoryTodoStore()
+	todo := types.NewTodo("Print the agenda", nil)
+	todo.List, todo.Owner, todo.Members = "work", alice.Id, map[string]types.Role{bob.Id: types.RoleEditor}
+	id, _ := store.AddTodo(t.Context(), todo)
+
+	ts := httptest.NewServer(NewTodoServer(stores.NewTodoStoreActor(store), WithUsers(userStore)))
+	defer ts.Close()
+	b := newBrowser(t, ts.URL)
+	b.get("/login")
+	b.submit("/login", url.Values{"name": {"bob"}, "password": {"bob's password"}})
+
+	t.Run("Editors can change a todo without moving it", func(t *testing.T) {
+		b.get("/todos/" + id + "/edit")
+		b.submit("/todos/"+id+"/edit", url.Values{"description": {"Print the new agenda"}, "status": {"Started"}, "list": {"work"}})
+		b.assertPageContains("Saved Print the new agenda")
+	})
+
+	t.Run("Only the owner can move it to another list", func(t *testing.T) {
+		b.get("/todos/" + id + "/edit")
+		b.submit("/todos/"+id+"/edit", url.Values{"description": {"Print the new agenda"}, "status": {"Started"}, "list": {"home"}})
+		b.assertPageContains(`class="flash error"`)
+		if got, _ := store.GetTodo(t.Context(), id); got.List != "work" {
+			t.Errorf("got the todo moved to %q by an editor", got.List)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"grantjames.github.io/todo-app/webhooks"
+)
+
+// webhookSubscription is how subscriptions are shown once created, with the
+// secret left out.
+type webhookSubscription struct {
+	webhooks.Subscription
+	Secret string `json:"secret,omitempty"`
+}
+
+func (s *TodoServer) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "ListWebhooks", nil)
+
+	subs := []webhookSubscription{}
+	for _, sub := range s.webhooks.Subscriptions() {
+		subs = append(subs, webhookSubscription{Subscription: sub})
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// AddWebhook creates a subscription. The response is the only time the
+// secret is returned, whether it was supplied or generated.
+func (s *TodoServer) AddWebhook(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "AddWebhook", nil)
+
+	var sub webhooks.Subscription
+	if !decodeJSON(w, r, &sub, MaxBodySize) {
+		return
+	}
+
+	sub, err := s.webhooks.AddSubscription(sub)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (s *TodoServer) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "GetWebhook", map[string]string{"webhook_id": id})
+
+	sub, ok := s.webhooks.Subscription(id)
+	if !ok {
+		writeError(w, r, webhooks.ErrUnknownSubscription)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookSubscription{Subscription: sub})
+}
+
+func (s *TodoServer) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "DeleteWebhook", map[string]string{"webhook_id": id})
+
+	err := s.webhooks.RemoveSubscription(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *TodoServer) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "GetWebhookDeliveries", map[string]string{"webhook_id": id})
+
+	if _, ok := s.webhooks.Subscription(id); !ok {
+		writeError(w, r, webhooks.ErrUnknownSubscription)
+		return
+	}
+	deliveries := s.webhooks.Deliveries(id)
+	if deliveries == nil {
+		deliveries = []webhooks.Delivery{}
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (s *TodoServer) GetWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "GetWebhookDeadLetters", nil)
+	writeJSON(w, http.StatusOK, s.webhooks.DeadLetters())
+}
+
+func (s *TodoServer) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	logEndpointCall(r, "RedeliverWebhook", map[string]string{"delivery_id": id})
+
+	delivery, err := s.webhooks.Redeliver(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
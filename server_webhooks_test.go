@@ -0,0 +1,58 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/webhooks"
+)
+
+func TestWebhooksAPI(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+
+	t.Run("Creating a subscription returns its secret once", func(t *testing.T) {
+		body := `{"url": "http://example.com/hook", "events": ["todo.created"]}`
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusCreated)
+
+		var created webhooks.Subscription
+		json.NewDecoder(response.Body).Decode(&created)
+		if created.Id == "" || created.Secret == "" {
+			t.Fatalf("got %+v want an id and a generated secret", created)
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/webhooks/"+created.Id, nil)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusOK)
+		if strings.Contains(response.Body.String(), created.Secret) {
+			t.Errorf("got %s which includes the secret", response.Body.String())
+		}
+
+		request = httptest.NewRequest(http.MethodDelete, "/api/webhooks/"+created.Id, nil)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusNoContent)
+	})
+
+	t.Run("Rejects subscriptions to unknown events", func(t *testing.T) {
+		body := `{"url": "http://example.com/hook", "events": ["todo.exploded"]}`
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("Redelivering an unknown delivery is a 404", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/api/webhooks/dead-letters/nope/redeliver", nil)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,294 @@
+package todoapp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	// How many messages can be waiting to be written to a websocket before
+	// the connection is closed for being too slow.
+	wsOutboxSize = 64
+	// How long a command waits for the actor before giving up.
+	wsCommandTimeout = 10 * time.Second
+)
+
+// wsCommand is a message sent by a websocket client. Op is one of get,
+// list, add or update_status, and Id is echoed back in the reply.
+type wsCommand struct {
+	Id      string       `json:"id"`
+	Op      string       `json:"op"`
+	TodoId  string       `json:"todo_id,omitempty"`
+	Status  types.Status `json:"status,omitempty"`
+	Overdue bool         `json:"overdue,omitempty"`
+	Todo    *types.Todo  `json:"todo,omitempty"`
+}
+
+// wsMessage is a reply to a command, or a change notification when Event is set.
+type wsMessage struct {
+	Id     string                `json:"id,omitempty"`
+	Error  string                `json:"error,omitempty"`
+	TodoId string                `json:"todo_id,omitempty"`
+	Todo   *types.Todo           `json:"todo,omitempty"`
+	Todos  map[string]types.Todo `json:"todos,omitempty"`
+	Event  *types.TodoEvent      `json:"event,omitempty"`
+}
+
+// ServeWebSocket accepts JSON commands over a websocket and pushes every
+// change to the user's todos back. Each connection has a bounded outbox,
+// and one that can't keep up is closed rather than holding up the actor or
+// other clients. Like the rest of the API it needs a Bearer token when the
+// server has users; the web pages' session cookie isn't accepted, so other
+// sites can't open a connection as a signed in browser.
+func (s *TodoServer) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	logEndpointCall(r, "ServeWebSocket", nil)
+
+	// Subscribe before upgrading so no change made during the handshake is missed
+	sub, _, _ := s.actor.Events().Subscribe(0, wsOutboxSize)
+	defer sub.Close()
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Websocket upgrade failed", slog.String("error", err.Error()))
+		return
+	}
+
+	// The request context isn't cancelled for hijacked connections, so keep
+	// its values but manage the lifetime here
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+
+	out := make(chan wsMessage, wsOutboxSize)
+	send := func(m wsMessage) bool {
+		select {
+		case out <- m:
+			return true
+		default:
+			slog.WarnContext(ctx, "Closing websocket that isn't keeping up")
+			conn.Close(wsCloseTryLater, "too slow")
+			cancel()
+			return false
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					conn.Close(wsCloseTryLater, "too slow")
+					cancel()
+					return
+				}
+				if !e.Todo.Visible(ctx) {
+					continue
+				}
+				if !send(wsMessage{Event: &e}) {
+					return
+				}
+			}
+		}
+	}()
+
+	go s.writeWebSocket(ctx, cancel, conn, out)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			slog.InfoContext(ctx, "Websocket closed", slog.String("reason", err.Error()))
+			cancel()
+			conn.Close(wsCloseNormal, "")
+			return
+		}
+
+		var cmd wsCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			if !send(wsMessage{Error: "invalid command: " + err.Error()}) {
+				return
+			}
+			continue
+		}
+
+		if !send(s.handleWebSocketCommand(ctx, cmd)) {
+			return
+		}
+	}
+}
+
+func (s *TodoServer) writeWebSocket(ctx context.Context, cancel context.CancelFunc, conn *wsConn, out <-chan wsMessage) {
+	ping := time.NewTicker(s.heartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-out:
+			data, _ := json.Marshal(m)
+			if err := conn.WriteMessage(data); err != nil {
+				cancel()
+				conn.Close(wsCloseNormal, "")
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				cancel()
+				conn.Close(wsCloseNormal, "")
+				return
+			}
+		}
+	}
+}
+
+// handleWebSocketCommand maps a command onto the actor's requests. Response
+// channels are buffered so the actor never waits on a connection that has
+// gone away.
+func (s *TodoServer) handleWebSocketCommand(ctx context.Context, cmd wsCommand) wsMessage {
+	ctx, cancel := context.WithTimeout(ctx, wsCommandTimeout)
+	defer cancel()
+
+	reply := wsMessage{Id: cmd.Id}
+	timedOut := func() wsMessage {
+		reply.Error = "request canceled"
+		return reply
+	}
+
+	switch cmd.Op {
+	case "get":
+		resp := make(chan types.GetTodoResponse, 1)
+		s.actor.Send(types.GetTodoRequest{Ctx: ctx, Id: cmd.TodoId, Resp: resp})
+		select {
+		case res := <-resp:
+			if res.Err != nil {
+				reply.Error = res.Err.Error()
+				return reply
+			}
+			reply.TodoId, reply.Todo = cmd.TodoId, &res.Todo
+		case <-ctx.Done():
+			return timedOut()
+		}
+
+	case "list":
+		var todos map[string]types.Todo
+		switch {
+		case cmd.Status != "":
+			status, err := types.ParseStatus(string(cmd.Status))
+			if err != nil {
+				reply.Error = err.Error()
+				return reply
+			}
+			resp := make(chan types.GetTodosByStatusResponse, 1)
+			s.actor.Send(types.GetTodosByStatusRequest{Ctx: ctx, Status: status, Resp: resp})
+			select {
+			case res := <-resp:
+				todos = res.Todos
+			case <-ctx.Done():
+				return timedOut()
+			}
+		case cmd.Overdue:
+			resp := make(chan types.GetOverDueTodosResponse, 1)
+			s.actor.Send(types.GetOverDueTodosRequest{Ctx: ctx, Resp: resp})
+			select {
+			case res := <-resp:
+				todos = res.Todos
+			case <-ctx.Done():
+				return timedOut()
+			}
+		default:
+			resp := make(chan types.GetAllTodosResponse, 1)
+			s.actor.Send(types.GetAllTodosRequest{Ctx: ctx, Resp: resp})
+			select {
+			case res := <-resp:
+				if res.Err != nil {
+					reply.Error = res.Err.Error()
+					return reply
+				}
+				todos = res.Todos
+			case <-ctx.Done():
+				return timedOut()
+			}
+		}
+		reply.Todos = todos
+
+	case "add":
+		if cmd.Todo == nil {
+			reply.Error = "add needs a todo"
+			return reply
+		}
+		todo := *cmd.Todo
+		// The same checks as AddTodo makes
+		if err := cmp.Or(todo.ValidateNew(), s.checkUser("assignee", todo.Assignee)); err != nil {
+			reply.Error = err.Error()
+			return reply
+		}
+		todo.Init(time.Now())
+
+		resp := make(chan types.AddTodoResponse, 1)
+		s.actor.Send(types.AddTodoRequest{Ctx: ctx, Todo: todo, Resp: resp})
+		select {
+		case res := <-resp:
+			if res.Err != nil {
+				reply.Error = res.Err.Error()
+				return reply
+			}
+			reply.TodoId = res.Id
+		case <-ctx.Done():
+			return timedOut()
+		}
+
+	case "update_status":
+		status, err := types.ParseStatus(string(cmd.Status))
+		if err != nil {
+			reply.Error = err.Error()
+			return reply
+		}
+		// As authorize does over HTTP, though the stores check it too
+		todo, err := s.wsGetTodo(ctx, cmd.TodoId)
+		if err == nil {
+			err = todo.Allow(ctx, types.RoleEditor)
+		}
+		if err != nil {
+			reply.Error = err.Error()
+			return reply
+		}
+
+		resp := make(chan types.UpdateTodoStatusResponse, 1)
+		s.actor.Send(types.UpdateTodoStatusRequest{Ctx: ctx, Id: cmd.TodoId, Status: status, Resp: resp})
+		select {
+		case res := <-resp:
+			if res.Err != nil {
+				reply.Error = res.Err.Error()
+				return reply
+			}
+			reply.TodoId = cmd.TodoId
+		case <-ctx.Done():
+			return timedOut()
+		}
+
+	default:
+		reply.Error = "unknown op " + cmd.Op
+	}
+
+	return reply
+}
+
+// wsGetTodo fetches a todo from the actor for a command.
+func (s *TodoServer) wsGetTodo(ctx context.Context, id string) (types.Todo, error) {
+	resp := make(chan types.GetTodoResponse, 1)
+	s.actor.Send(types.GetTodoRequest{Ctx: ctx, Id: id, Resp: resp})
+	select {
+	case res := <-resp:
+		return res.Todo, res.Err
+	case <-ctx.Done():
+		return types.Todo{}, errors.New("request canceled")
+	}
+}
@@ -0,0 +1,140 @@
+package todoapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/users"
+)
+
+func TestWebSocket(t *testing.T) {
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, err := dialWebSocket(strings.Replace(ts.URL, "http://", "ws://", 1)+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("unexpected error connecting: %v", err)
+	}
+	defer conn.Close(wsCloseNormal, "")
+
+	t.Run("Commands are answered and changes are pushed", func(t *testing.T) {
+		sendCommand(t, conn, wsCommand{Id: "1", Op: "add", Todo: &types.Todo{Description: "Socket todo"}})
+
+		var reply, event *wsMessage
+		for reply == nil || event == nil {
+			m := readMessage(t, conn)
+			if m.Event != nil {
+				event = &m
+			} else {
+				reply = &m
+			}
+		}
+
+		if reply.Id != "1" || reply.Error != "" || reply.TodoId == "" {
+			t.Fatalf("got reply %+v want the new todo's id", reply)
+		}
+		if event.Event.Type != types.EventAdded || event.Event.TodoId != reply.TodoId {
+			t.Errorf("got event %+v want the todo being added", event.Event)
+		}
+
+		sendCommand(t, conn, wsCommand{Id: "2", Op: "get", TodoId: reply.TodoId})
+		got := readMessage(t, conn)
+		if got.Id != "2" || got.Todo == nil || got.Todo.Description != "Socket todo" {
+			t.Errorf("got reply %+v want the added todo", got)
+		}
+	})
+
+	t.Run("Errors are reported against the command", func(t *testing.T) {
+		sendCommand(t, conn, wsCommand{Id: "3", Op: "get", TodoId: "non-existent-id"})
+		got := readMessage(t, conn)
+		if got.Id != "3" || got.Error == "" {
+			t.Errorf("got reply %+v want an error", got)
+		}
+	})
+}
+
+func sendCommand(t testing.TB, conn *wsConn, cmd wsCommand) {
+	t.Helper()
+	data, _ := json.Marshal(cmd)
+	if err := conn.WriteMessage(data); err != nil {
+		t.Fatalf("unexpected error sending command: %v", err)
+	}
+}
+
+func readMessage(t testing.TB, conn *wsConn) wsMessage {
+	t.Helper()
+	conn.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error reading message: %v", err)
+	}
+	var m wsMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unable to parse message %q, '%v'", data, err)
+	}
+	return m
+}
+
+func TestWebSocketRoles(t *testing.T) {
+	userStore, _ := users.NewStore("")
+	alice, _ := userStore.AddUser("alice", false)
+	bob, _ := userStore.AddUser("bob", false)
+	_, aliceToken, _ := userStore.CreateToken(alice.Id, "")
+	_, bobToken, _ := userStore.CreateToken(bob.Id, "")
+
+	server := NewTodoServer(stores.NewTodoStoreActor(stores.NewInMemoryTodoStore()), WithUsers(userStore))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	aliceClient := NewTodoAPIClient(ts.URL+APIPrefix, WithToken(aliceToken))
+
+	id, err := aliceClient.AddTodo(types.NewTodo("Shared todo", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aliceClient.ShareTodo(id, "bob", types.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := dialWebSocket(strings.Replace(ts.URL, "http://", "ws://", 1)+"/api/ws", http.Header{"Authorization": {"Bearer " + bobToken}})
+	if err != nil {
+		t.Fatalf("unexpected error connecting: %v", err)
+	}
+	defer conn.Close(wsCloseNormal, "")
+
+	t.Run("Viewers can't change a todo's status", func(t *testing.T) {
+		sendCommand(t, conn, wsCommand{Id: "1", Op: "update_status", TodoId: id, Status: types.Completed})
+		got := readMessage(t, conn)
+		if got.Id != "1" || !strings.Contains(got.Error, "editor") {
+			t.Errorf("got reply %+v want it refused for needing an editor", got)
+		}
+		todo, _ := aliceClient.GetTodo(id)
+		if todo.Status == types.Completed {
+			t.Errorf("got the todo completed by a viewer")
+		}
+	})
+
+	t.Run("Commands are validated like HTTP requests", func(t *testing.T) {
+		sendCommand(t, conn, wsCommand{Id: "2", Op: "add", Todo: &types.Todo{Description: "Assigned", Assignee: "nobody"}})
+		if got := readMessage(t, conn); got.Id != "2" || !strings.Contains(got.Error, "isn't a user") {
+			t.Errorf("got reply %+v want an unknown assignee refused", got)
+		}
+		sendCommand(t, conn, wsCommand{Id: "3", Op: "list", Status: "bogus"})
+		if got := readMessage(t, conn); got.Id != "3" || !strings.Contains(got.Error, "unknown status") {
+			t.Errorf("got reply %+v want an unknown status refused", got)
+		}
+	})
+
+	t.Run("Statuses can be given as slugs", func(t *testing.T) {
+		sendCommand(t, conn, wsCommand{Id: "4", Op: "list", Status: "not-started"})
+		if got := readMessage(t, conn); got.Error != "" || len(got.Todos) != 1 {
+			t.Errorf("got reply %+v want the shared todo", got)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+// Package sessions keeps the sessions of browsers using the web pages. They
+// only live in memory, so restarting the server signs everyone out.
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLifetime is how long a session lasts after it's created.
+	DefaultLifetime = 24 * time.Hour
+	// MaxSessions is how many sessions a store keeps before it drops the
+	// one closest to expiring to make room for another.
+	MaxSessions = 100_000
+)
+
+// Flash kinds
+const (
+	FlashInfo  = "info"
+	FlashError = "error"
+)
+
+// Flash is a message for the next page the browser is shown, such as the
+// outcome of the form it submitted.
+type Flash struct {
+	Kind    string
+	Message string
+}
+
+// Session is a browser, signed in as UserId or not yet signed in. Forms
+// have to be sent with its CSRFToken, so other sites can't submit them. A
+// signed out browser's session isn't stored until it needs to be, see
+// SignedOut.
+type Session struct {
+	Id        string
+	UserId    string
+	CSRFToken string
+	Expires   time.Time
+	flashes   []Flash
+}
+
+// Store keeps sessions until they expire.
+type Store struct {
+	lock      sync.Mutex
+	lifetime  time.Duration
+	max       int
+	sessions  map[string]*Session
+	lastPrune time.Time
+	// key signs the CSRF tokens of sessions that aren't stored
+	key []byte
+	now func() time.Time
+}
+
+// NewStore keeps sessions for lifetime, or DefaultLifetime if it's 0.
+func NewStore(lifetime time.Duration) *Store {
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return &Store{lifetime: lifetime, max: MaxSessions, sessions: map[string]*Session{}, key: key, now: time.Now}
+}
+
+// Create starts a session for the user with the id, or a signed out one if
+// it's empty. Signing in should always create a new session, rather than
+// change the one the browser had, so an id someone else planted in the
+// browser is no use to them.
+func (s *Store) Create(userId string) Session {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pruneLocked()
+
+	session := &Session{Id: newId(), UserId: userId, CSRFToken: newId(), Expires: s.now().Add(s.lifetime)}
+	s.sessions[session.Id] = session
+	return *session
+}
+
+// SignedOut is the session of a browser that hasn't signed in, or been
+// given a session to keep flashes in. It isn't stored, so has no id, and
+// its CSRF token is signed from nonce, which the browser keeps in a cookie,
+// so crawlers asking for the login page don't fill the store.
+func (s *Store) SignedOut(nonce string) Session {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(nonce))
+	return Session{CSRFToken: base64.RawURLEncoding.EncodeToString(mac.Sum(nil))}
+}
+
+// NewNonce returns a nonce for SignedOut.
+func NewNonce() string {
+	return newId()
+}
+
+// Get returns the session with the id, if it hasn't expired.
+func (s *Store) Get(id string) (Session, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !s.now().Before(session.Expires) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	return *session, true
+}
+
+// Delete ends the session, such as when signing out.
+func (s *Store) Delete(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sessions, id)
+}
+
+// AddFlash keeps a message for the session's next page.
+func (s *Store) AddFlash(id string, f Flash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if session, ok := s.sessions[id]; ok {
+		session.flashes = append(session.flashes, f)
+	}
+}
+
+// TakeFlashes returns the session's messages, oldest first, and forgets
+// them so they're only shown once.
+func (s *Store) TakeFlashes(id string) []Flash {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	flashes := session.flashes
+	session.flashes = nil
+	return flashes
+}
+
+// CheckCSRF reports whether token is the session's CSRF token.
+func (s Session) CheckCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken)) == 1
+}
+
+// pruneLocked forgets expired sessions, at most once a minute, and makes
+// room for another if the store is full.
+func (s *Store) pruneLocked() {
+	now := s.now()
+	if now.Sub(s.lastPrune) > time.Minute {
+		for id, session := range s.sessions {
+			if !now.Before(session.Expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	if len(s.sessions) < s.max {
+		return
+	}
+	var oldest *Session
+	for _, session := range s.sessions {
+		if oldest == nil || session.Expires.Before(oldest.Expires) {
+			oldest = session
+		}
+	}
+	delete(s.sessions, oldest.Id)
+}
+
+// newId returns 256 random bits, for session ids and CSRF tokens.
+func newId() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)
+	store := NewStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	session := store.Create("alice")
+
+	t.Run("A session can be found by its id", func(t *testing.T) {
+		got, ok := store.Get(session.Id)
+		if !ok || got.UserId != "alice" {
+			t.Errorf("got %+v want alice's session", got)
+		}
+		if _, ok := store.Get(session.CSRFToken); ok {
+			t.Errorf("got a session for its CSRF token")
+		}
+	})
+
+	t.Run("Only the session's CSRF token is accepted", func(t *testing.T) {
+		other := store.Create("alice")
+		if !session.CheckCSRF(session.CSRFToken) {
+			t.Errorf("got the session's own token rejected")
+		}
+		if session.CheckCSRF(other.CSRFToken) || session.CheckCSRF("") {
+			t.Errorf("got another session's token, or none, accepted")
+		}
+	})
+
+	t.Run("Flashes are only taken once", func(t *testing.T) {
+		store.AddFlash(session.Id, Flash{FlashInfo, "Added"})
+		store.AddFlash(session.Id, Flash{FlashError, "Oops"})
+		if got := store.TakeFlashes(session.Id); len(got) != 2 || got[0].Message != "Added" {
+			t.Errorf("got %+v want both flashes in order", got)
+		}
+		if got := store.TakeFlashes(session.Id); len(got) != 0 {
+			t.Errorf("got %+v want none", got)
+		}
+	})
+
+	t.Run("Sessions expire", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		if _, ok := store.Get(session.Id); ok {
+			t.Errorf("got the session after it expired")
+		}
+	})
+
+	t.Run("Deleted sessions are gone", func(t *testing.T) {
+		s := store.Create("")
+		store.Delete(s.Id)
+		if _, ok := store.Get(s.Id); ok {
+			t.Errorf("got the session after deleting it")
+		}
+	})
+
+	t.Run("The oldest session makes room when the store is full", func(t *testing.T) {
+		full := NewStore(time.Hour)
+		full.max = 2
+		first := full.Create("alice")
+		full.now = func() time.Time { return time.Now().Add(time.Second) }
+		second := full.Create("bob")
+		third := full.Create("carol")
+		if _, ok := full.Get(first.Id); ok {
+			t.Errorf("got the oldest session kept")
+		}
+		for _, session := range []Session{second, third} {
+			if _, ok := full.Get(session.Id); !ok {
+				t.Errorf("got %s's session dropped", session.UserId)
+			}
+		}
+	})
+
+	t.Run("Signed out sessions have a CSRF token for their nonce", func(t *testing.T) {
+		nonce := NewNonce()
+		session := store.SignedOut(nonce)
+		if session.Id != "" || !session.CheckCSRF(store.SignedOut(nonce).CSRFToken) {
+			t.Errorf("got %+v want an unstored session with the same token each time", session)
+		}
+		if session.CheckCSRF(store.SignedOut(NewNonce()).CSRFToken) || session.CheckCSRF(NewStore(0).SignedOut(nonce).CSRFToken) {
+			t.Errorf("got a token for another nonce, or from another store, accepted")
+		}
+	})
+}
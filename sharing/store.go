@@ -0,0 +1,129 @@
+package sharing
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/types"
+)
+
+var ErrNotShared = types.NotFoundf("the list isn't shared with that user")
+
+// Store keeps who users have shared their lists with, optionally persisting
+// them to a file. Todos shared on their own keep their members themselves.
+type Store struct {
+	lock  sync.Mutex
+	path  string
+	lists map[types.ListRef]map[string]types.Role
+}
+
+type sharedList struct {
+	types.ListRef
+	Members map[string]types.Role `json:"members"`
+}
+
+type state struct {
+	Lists []sharedList `json:"lists"`
+}
+
+// NewStore loads shared lists from path, which is created on the first
+// change. An empty path keeps them in memory.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, lists: map[types.ListRef]map[string]types.Role{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem reading shared lists, %v", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("problem parsing shared lists, %v", err)
+	}
+	for _, l := range st.Lists {
+		s.lists[l.ListRef] = l.Members
+	}
+	return s, nil
+}
+
+// Members returns the users the list is shared with, by id, and their
+// roles. It's empty for a list that isn't shared.
+func (s *Store) Members(list types.ListRef) map[string]types.Role {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return maps.Clone(s.lists[list])
+}
+
+// Share gives the user the role on every todo in the list, replacing any
+// role they had.
+func (s *Store) Share(list types.ListRef, user string, role types.Role) error {
+	role, err := types.ParseRole(string(role))
+	if err != nil {
+		return types.Invalid("role", "%v", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.lists[list] == nil {
+		s.lists[list] = map[string]types.Role{}
+	}
+	s.lists[list][user] = role
+	return s.saveLocked()
+}
+
+// Unshare stops sharing the list with the user.
+func (s *Store) Unshare(list types.ListRef, user string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.lists[list][user]; !ok {
+		return ErrNotShared
+	}
+	delete(s.lists[list], user)
+	if len(s.lists[list]) == 0 {
+		delete(s.lists, list)
+	}
+	return s.saveLocked()
+}
+
+// SharedWith returns the lists shared with the user and their role on each.
+func (s *Store) SharedWith(user string) map[types.ListRef]types.Role {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	shared := map[types.ListRef]types.Role{}
+	for list, members := range s.lists {
+		if role, ok := members[user]; ok {
+			shared[list] = role
+		}
+	}
+	return shared
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	st := state{Lists: []sharedList{}}
+	for list, members := range s.lists {
+		st.Lists = append(st.Lists, sharedList{list, members})
+	}
+	slices.SortFunc(st.Lists, func(a, b sharedList) int {
+		return cmp.Or(cmp.Compare(a.Owner, b.Owner), cmp.Compare(a.List, b.List))
+	})
+	if err := atomicfile.WriteJSON(s.path, st); err != nil {
+		return fmt.Errorf("problem saving shared lists, %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package sharing
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shares.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	work := types.ListRef{Owner: "alice", List: "work"}
+	home := types.ListRef{Owner: "alice", List: "home"}
+
+	if err := store.Share(work, "bob", types.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	store.Share(home, "bob", types.RoleViewer)
+	store.Share(work, "carol", types.RoleViewer)
+
+	t.Run("Lists shared with a user have their role", func(t *testing.T) {
+		got := store.SharedWith("bob")
+		if len(got) != 2 || got[work] != types.RoleEditor || got[home] != types.RoleViewer {
+			t.Errorf("got %v want work as an editor and home as a viewer", got)
+		}
+	})
+
+	t.Run("Sharing again replaces the role", func(t *testing.T) {
+		store.Share(work, "carol", types.RoleOwner)
+		if got := store.Members(work)["carol"]; got != types.RoleOwner {
+			t.Errorf("got %q want owner", got)
+		}
+	})
+
+	t.Run("Unknown roles are invalid", func(t *testing.T) {
+		if err := store.Share(work, "dan", "admin"); !errors.Is(err, types.ErrInvalid) {
+			t.Errorf("got %v want ErrInvalid", err)
+		}
+	})
+
+	t.Run("Unsharing removes the member", func(t *testing.T) {
+		if err := store.Unshare(home, "bob"); err != nil {
+			t.Fatal(err)
+		}
+		if got := store.SharedWith("bob"); len(got) != 1 {
+			t.Errorf("got %v want only work", got)
+		}
+		if err := store.Unshare(home, "bob"); !errors.Is(err, ErrNotShared) {
+			t.Errorf("got %v want %v", err, ErrNotShared)
+		}
+	})
+
+	t.Run("Shares are kept between restarts", func(t *testing.T) {
+		reloaded, err := NewStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := reloaded.Members(work)
+		if len(got) != 2 || got["bob"] != types.RoleEditor || got["carol"] != types.RoleOwner {
+			t.Errorf("got %v want bob and carol", got)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package stores
+
+import (
+	"sync"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// DefaultReplaySize is how many recent events the actor's broker keeps for
+// subscribers resuming from an earlier event.
+const DefaultReplaySize = 256
+
+// EventBroker fans events out to subscribers and keeps a bounded buffer of
+// recent events to replay. Publishing never blocks: a subscriber whose
+// buffer is full is dropped, and can resubscribe from the last event it saw.
+type EventBroker struct {
+	lock   sync.Mutex
+	nextId uint64
+	replay []types.TodoEvent
+	size   int
+	subs   map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	// C receives the subscriber's events and is closed when the
+	// subscription is closed or dropped for falling behind.
+	C      <-chan types.TodoEvent
+	c      chan types.TodoEvent
+	broker *EventBroker
+}
+
+func NewEventBroker(replaySize int) *EventBroker {
+	return &EventBroker{
+		nextId: 1,
+		size:   replaySize,
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the event its Id and sends it to every subscriber.
+func (b *EventBroker) Publish(e types.TodoEvent) types.TodoEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	e.Id = b.nextId
+	b.nextId++
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.replay = append(b.replay, e)
+	if len(b.replay) > b.size {
+		b.replay = b.replay[len(b.replay)-b.size:]
+	}
+
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			b.remove(s)
+		}
+	}
+	return e
+}
+
+// Subscribe registers a subscriber with room for buffer undelivered events.
+// Events after lastId that are still in the replay buffer are returned to be
+// sent first; complete is false if some of them have already been discarded,
+// or if lastId is one the broker hasn't published, such as an id from before
+// the server restarted.
+func (b *EventBroker) Subscribe(lastId uint64, buffer int) (sub *Subscription, missed []types.TodoEvent, complete bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	complete = true
+	if lastId > 0 {
+		for _, e := range b.replay {
+			if e.Id > lastId {
+				missed = append(missed, e)
+			}
+		}
+		oldest := b.nextId
+		if len(b.replay) > 0 {
+			oldest = b.replay[0].Id
+		}
+		complete = lastId+1 >= oldest && lastId < b.nextId
+	}
+
+	c := make(chan types.TodoEvent, buffer)
+	sub = &Subscription{C: c, c: c, broker: b}
+	b.subs[sub] = struct{}{}
+	return sub, missed, complete
+}
+
+// LastId is the Id of the most recently published event.
+func (b *EventBroker) LastId() uint64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.nextId - 1
+}
+
+func (s *Subscription) Close() {
+	s.broker.lock.Lock()
+	defer s.broker.lock.Unlock()
+	s.broker.remove(s)
+}
+
+func (b *EventBroker) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
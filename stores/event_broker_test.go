@@ -0,0 +1,73 @@
+package stores
+
+import (
+	"testing"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestEventBroker(t *testing.T) {
+	t.Run("Subscribers receive published events in order", func(t *testing.T) {
+		b := NewEventBroker(10)
+		sub, _, _ := b.Subscribe(0, 10)
+		defer sub.Close()
+
+		b.Publish(types.TodoEvent{Type: types.EventAdded, TodoId: "1"})
+		b.Publish(types.TodoEvent{Type: types.EventUpdated, TodoId: "1"})
+
+		if e := <-sub.C; e.Id != 1 || e.Type != types.EventAdded {
+			t.Errorf("got event %v want the add first", e)
+		}
+		if e := <-sub.C; e.Id != 2 || e.Type != types.EventUpdated {
+			t.Errorf("got event %v want the update second", e)
+		}
+	})
+
+	t.Run("Resuming replays the events missed", func(t *testing.T) {
+		b := NewEventBroker(2)
+		for range 3 {
+			b.Publish(types.TodoEvent{Type: types.EventAdded})
+		}
+
+		_, missed, complete := b.Subscribe(1, 10)
+		if len(missed) != 2 || missed[0].Id != 2 || !complete {
+			t.Errorf("got %d missed events, complete %v, want events 2 and 3", len(missed), complete)
+		}
+
+		// Event 1 has been pushed out of the replay buffer
+		_, _, complete = b.Subscribe(0, 10)
+		if !complete {
+			t.Errorf("expected a new subscriber to be complete")
+		}
+		b.Publish(types.TodoEvent{Type: types.EventAdded})
+		if _, _, complete = b.Subscribe(1, 10); complete {
+			t.Errorf("expected resuming from a discarded event to be incomplete")
+		}
+	})
+
+	t.Run("Resuming from an event that wasn't published is incomplete", func(t *testing.T) {
+		b := NewEventBroker(10)
+		b.Publish(types.TodoEvent{Type: types.EventAdded})
+
+		if _, _, complete := b.Subscribe(1, 10); !complete {
+			t.Errorf("expected resuming from the latest event to be complete")
+		}
+		// As if the server restarted after the subscriber saw event 5
+		if _, missed, complete := b.Subscribe(5, 10); complete || len(missed) != 0 {
+			t.Errorf("got %d missed events, complete %v, want an incomplete subscription", len(missed), complete)
+		}
+	})
+
+	t.Run("Slow subscribers are dropped instead of blocking", func(t *testing.T) {
+		b := NewEventBroker(10)
+		sub, _, _ := b.Subscribe(0, 1)
+
+		b.Publish(types.TodoEvent{Type: types.EventAdded})
+		b.Publish(types.TodoEvent{Type: types.EventAdded})
+
+		<-sub.C
+		if _, ok := <-sub.C; ok {
+			t.Errorf("expected the subscription to be closed")
+		}
+	})
+}
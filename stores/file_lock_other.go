@@ -0,0 +1,11 @@
+//go:build !unix
+
+package stores
+
+import "os"
+
+// lockFile is a no-op on platforms without flock, so callers there must make
+// sure only one process opens the file.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -2,7 +2,6 @@ package stores
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"sync"
 
@@ -29,8 +28,8 @@ func (i *InMemoryTodoStore) GetTodo(ctx context.Context, id string) (types.Todo,
 	defer i.lock.RUnlock()
 
 	todo, ok := i.store[id]
-	if !ok {
-		return types.Todo{}, fmt.Errorf("no todo with id %s found", id)
+	if !ok || !todo.Visible(ctx) {
+		return types.Todo{}, types.NotFoundf("no todo with id %s found", id)
 	}
 	return todo, nil
 }
@@ -43,6 +42,7 @@ func (i *InMemoryTodoStore) AddTodo(ctx context.Context, todo types.Todo) (strin
 
 	id := uuid.NewString()
 
+	todo.Own(ctx)
 	i.store[id] = todo
 
 	return id, nil
@@ -55,8 +55,13 @@ func (i *InMemoryTodoStore) UpdateTodoStatus(ctx context.Context, id string, sta
 	defer i.lock.Unlock()
 
 	todo, ok := i.store[id]
-	if !ok {
-		return fmt.Errorf("no todo with ID %s was found", id)
+	if !ok || !todo.Visible(ctx) {
+		return types.NotFoundf("no todo with ID %s was found", id)
+	}
+	// Checked here, rather than only by the server, so every way of
+	// changing a todo needs the same role
+	if err := todo.Allow(ctx, types.RoleEditor); err != nil {
+		return err
 	}
 	todo.SetStatus(status)
 	i.store[id] = todo // Have to assign the value back since it's retrieved by value (not storing pointers)
@@ -71,7 +76,7 @@ func (i *InMemoryTodoStore) GetTodosByStatus(ctx context.Context, status types.S
 
 	results := map[string]types.Todo{}
 	for key, value := range i.store {
-		if value.Status == status {
+		if value.Status == status && value.Visible(ctx) {
 			results[key] = value
 		}
 	}
@@ -86,7 +91,7 @@ func (i *InMemoryTodoStore) GetOverdueTodos(ctx context.Context) map[string]type
 
 	results := map[string]types.Todo{}
 	for key, t := range i.store {
-		if t.IsOverdue() {
+		if t.IsOverdue() && t.Visible(ctx) {
 			results[key] = t
 		}
 	}
@@ -101,9 +106,32 @@ func (i *InMemoryTodoStore) GetAllTodos(ctx context.Context) map[string]types.To
 
 	results := map[string]types.Todo{}
 	for key, t := range i.store {
-		if t.Status != types.Completed {
+		if t.Status != types.Completed && t.Visible(ctx) {
 			results[key] = t
 		}
 	}
 	return results
 }
+
+func (i *InMemoryTodoStore) QueryTodos(ctx context.Context, q types.TodoQuery) (types.TodoPage, error) {
+	slog.InfoContext(ctx, "InMemoryTodoStore: QueryTodos called")
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return types.QueryTodos(types.VisibleTodos(ctx, i.store), q)
+}
+
+func (i *InMemoryTodoStore) ApplyBulk(ctx context.Context, ops []types.BulkOp) ([]types.BulkResult, error) {
+	slog.InfoContext(ctx, "InMemoryTodoStore: ApplyBulk called", "ops", len(ops))
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	todos, results, err := types.ApplyScopedBulk(ctx, i.store, ops, uuid.NewString)
+	if err != nil {
+		return results, err
+	}
+	i.store = todos
+	return results, nil
+}
@@ -2,6 +2,7 @@ package stores
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"grantjames.github.io/todo-app/types"
@@ -85,3 +86,64 @@ func TestInMemoryStore(t *testing.T) {
 		}
 	})
 }
+
+func TestInMemoryStoreScopedToOwner(t *testing.T) {
+	store := CreateTestStore()
+	alice := types.WithOwner(ctx, "alice")
+	bob := types.WithOwner(ctx, "bob")
+
+	aliceId, _ := store.AddTodo(alice, types.NewTodo("Alice's todo", nil))
+
+	t.Run("Added todos belong to the owner", func(t *testing.T) {
+		todo, err := store.GetTodo(alice, aliceId)
+		if err != nil || todo.Owner != "alice" {
+			t.Errorf("got %+v and error %v want alice's todo", todo, err)
+		}
+	})
+
+	t.Run("Other owners can't see or change them", func(t *testing.T) {
+		if _, err := store.GetTodo(bob, aliceId); err == nil {
+			t.Errorf("expected bob not to find alice's todo")
+		}
+		if err := store.UpdateTodoStatus(bob, aliceId, types.Completed); err == nil {
+			t.Errorf("expected bob not to be able to change alice's todo")
+		}
+		if todos := store.GetAllTodos(bob); len(todos) != 0 {
+			t.Errorf("got %d todos for bob want 0", len(todos))
+		}
+		page, _ := store.QueryTodos(bob, types.TodoQuery{})
+		if len(page.Todos) != 0 {
+			t.Errorf("got %d todos for bob want 0", len(page.Todos))
+		}
+	})
+
+	t.Run("Bulk changes only apply to the owner's todos", func(t *testing.T) {
+		_, err := store.ApplyBulk(bob, []types.BulkOp{{Op: types.BulkDelete, Id: aliceId}})
+		if err == nil {
+			t.Errorf("expected bob not to be able to delete alice's todo")
+		}
+		results, err := store.ApplyBulk(bob, []types.BulkOp{{Op: types.BulkCreate, Todo: &types.Todo{Description: "Bob's todo"}}})
+		if err != nil || results[0].Todo.Owner != "bob" {
+			t.Fatalf("got %+v and error %v want bob's todo created", results, err)
+		}
+		if todos := store.GetAllTodos(alice); len(todos) != 1 {
+			t.Errorf("got %d todos for alice want 1", len(todos))
+		}
+	})
+
+	t.Run("Viewers can't change a todo's status", func(t *testing.T) {
+		shared := types.NewTodo("Shared todo", nil)
+		shared.Members = map[string]types.Role{"bob": types.RoleViewer}
+		id, _ := store.AddTodo(alice, shared)
+		if err := store.UpdateTodoStatus(bob, id, types.Completed); !errors.Is(err, types.ErrForbidden) {
+			t.Errorf("got %v want ErrForbidden", err)
+		}
+		store.ApplyBulk(alice, []types.BulkOp{{Op: types.BulkDelete, Id: id}})
+	})
+
+	t.Run("A context without an owner sees every todo", func(t *testing.T) {
+		if todos := store.GetAllTodos(ctx); len(todos) != 4 {
+			t.Errorf("got %d todos want 4", len(todos))
+		}
+	})
+}
@@ -3,6 +3,7 @@ package stores
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,9 +13,13 @@ import (
 	"grantjames.github.io/todo-app/types"
 )
 
+// ErrFileLocked is returned when another process already has the file open.
+var ErrFileLocked = errors.New("file is locked by another process")
+
 type JSONFileTodoStore struct {
 	database *json.Encoder
 	todos    map[string]types.Todo
+	file     *os.File
 }
 
 func initialiseTodosDBFile(file *os.File) error {
@@ -54,12 +59,44 @@ func NewJSONFileTodoStore(file *os.File) (*JSONFileTodoStore, error) {
 	}, nil
 }
 
+// OpenJSONFileTodoStore opens, or creates, the JSON file at path and takes
+// an exclusive lock on it so the CLI and a running server can never both
+// write to the same file. Call Close to release the lock.
+func OpenJSONFileTodoStore(path string) (*JSONFileTodoStore, error) {
+	db, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("problem opening %s %v", path, err)
+	}
+
+	if err := lockFile(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("problem locking %s, %w", path, err)
+	}
+
+	store, err := NewJSONFileTodoStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	store.file = db
+
+	return store, nil
+}
+
+// Close releases the file opened by OpenJSONFileTodoStore.
+func (i *JSONFileTodoStore) Close() error {
+	if i.file == nil {
+		return nil
+	}
+	return i.file.Close()
+}
+
 func (i *JSONFileTodoStore) GetTodo(ctx context.Context, id string) (types.Todo, error) {
 	slog.InfoContext(ctx, "JSONFileTodoStore: GetTodo called", "todo_id", id)
 
 	todo, ok := i.todos[id]
-	if !ok {
-		return types.Todo{}, fmt.Errorf("no todo with id %s found", id)
+	if !ok || !todo.Visible(ctx) {
+		return types.Todo{}, types.NotFoundf("no todo with id %s found", id)
 	}
 	return todo, nil
 }
@@ -69,6 +106,7 @@ func (i *JSONFileTodoStore) AddTodo(ctx context.Context, todo types.Todo) (strin
 
 	id := uuid.NewString()
 
+	todo.Own(ctx)
 	i.todos[id] = todo
 
 	i.database.Encode(i.todos)
@@ -79,8 +117,13 @@ func (i *JSONFileTodoStore) UpdateTodoStatus(ctx context.Context, id string, sta
 	slog.InfoContext(ctx, "JSONFileTodoStore: UpdateTodoStatus called", "todo_id", id, "status", status)
 
 	todo, ok := i.todos[id]
-	if !ok {
-		return fmt.Errorf("no todo with ID %s was found", id)
+	if !ok || !todo.Visible(ctx) {
+		return types.NotFoundf("no todo with ID %s was found", id)
+	}
+	// Checked here, rather than only by the server, so every way of
+	// changing a todo needs the same role
+	if err := todo.Allow(ctx, types.RoleEditor); err != nil {
+		return err
 	}
 	todo.SetStatus(status)
 	i.todos[id] = todo // Have to assign the value back since it's retrieved by value (not storing pointers)
@@ -94,7 +137,7 @@ func (i *JSONFileTodoStore) GetTodosByStatus(ctx context.Context, status types.S
 
 	results := map[string]types.Todo{}
 	for key, value := range i.todos {
-		if value.Status == status {
+		if value.Status == status && value.Visible(ctx) {
 			results[key] = value
 		}
 	}
@@ -106,7 +149,7 @@ func (i *JSONFileTodoStore) GetOverdueTodos(ctx context.Context) map[string]type
 
 	results := map[string]types.Todo{}
 	for key, t := range i.todos {
-		if t.IsOverdue() {
+		if t.IsOverdue() && t.Visible(ctx) {
 			results[key] = t
 		}
 	}
@@ -118,9 +161,30 @@ func (i *JSONFileTodoStore) GetAllTodos(ctx context.Context) map[string]types.To
 
 	results := map[string]types.Todo{}
 	for key, t := range i.todos {
-		if t.Status != types.Completed {
+		if t.Status != types.Completed && t.Visible(ctx) {
 			results[key] = t
 		}
 	}
 	return results
 }
+
+func (i *JSONFileTodoStore) QueryTodos(ctx context.Context, q types.TodoQuery) (types.TodoPage, error) {
+	slog.InfoContext(ctx, "JSONFileTodoStore: QueryTodos called")
+
+	return types.QueryTodos(types.VisibleTodos(ctx, i.todos), q)
+}
+
+// ApplyBulk writes the file once for the whole batch, and not at all if any
+// op fails.
+func (i *JSONFileTodoStore) ApplyBulk(ctx context.Context, ops []types.BulkOp) ([]types.BulkResult, error) {
+	slog.InfoContext(ctx, "JSONFileTodoStore: ApplyBulk called", "ops", len(ops))
+
+	todos, results, err := types.ApplyScopedBulk(ctx, i.todos, ops, uuid.NewString)
+	if err != nil {
+		return results, err
+	}
+	i.todos = todos
+
+	i.database.Encode(i.todos)
+	return results, nil
+}
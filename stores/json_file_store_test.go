@@ -0,0 +1,84 @@
+package stores
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestOpenJSONFileTodoStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	t.Run("Todos are persisted between opens", func(t *testing.T) {
+		store, err := OpenJSONFileTodoStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error opening store: %v", err)
+		}
+		id, _ := store.AddTodo(ctx, types.NewTodo("Persisted todo", nil))
+		store.Close()
+
+		store, err = OpenJSONFileTodoStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error reopening store: %v", err)
+		}
+		defer store.Close()
+
+		todo, err := store.GetTodo(ctx, id)
+		if err != nil {
+			t.Fatalf("Expected to retrieve todo with ID %s, got error: %v", id, err)
+		}
+		if todo.Description != "Persisted todo" {
+			t.Errorf("Expected description 'Persisted todo', got '%s'", todo.Description)
+		}
+	})
+
+	t.Run("A file can only be opened by one store at a time", func(t *testing.T) {
+		store, err := OpenJSONFileTodoStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error opening store: %v", err)
+		}
+		defer store.Close()
+
+		_, err = OpenJSONFileTodoStore(path)
+		if !errors.Is(err, ErrFileLocked) {
+			t.Errorf("got error %v want ErrFileLocked", err)
+		}
+	})
+}
+
+func TestJSONFileTodoStoreBulk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	store, err := OpenJSONFileTodoStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening store: %v", err)
+	}
+	id, _ := store.AddTodo(ctx, types.NewTodo("Existing todo", nil))
+
+	_, err = store.ApplyBulk(ctx, []types.BulkOp{
+		{Op: types.BulkUpdate, Id: id, Status: types.Completed},
+		{Op: types.BulkDelete, Id: "missing"},
+	})
+	if !errors.Is(err, types.ErrBulkFailed) {
+		t.Fatalf("got error %v want %v", err, types.ErrBulkFailed)
+	}
+	results, err := store.ApplyBulk(ctx, []types.BulkOp{
+		{Op: types.BulkCreate, Todo: &types.Todo{Description: "Created in bulk"}},
+		{Op: types.BulkDelete, Id: id},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Close()
+
+	store, err = OpenJSONFileTodoStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error reopening store: %v", err)
+	}
+	defer store.Close()
+	todos := types.AllTodos(ctx, store)
+	if _, ok := todos[results[0].Id]; !ok || len(todos) != 1 {
+		t.Errorf("got %v want only the todo created in bulk", todos)
+	}
+}
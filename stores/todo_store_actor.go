@@ -3,22 +3,41 @@ package stores
 import (
 	"context"
 	"log/slog"
+	"slices"
+	"time"
 
+	"grantjames.github.io/todo-app/search"
 	"grantjames.github.io/todo-app/types"
 )
 
 type TodoStoreActor struct {
-	cmds  chan types.Cmd
-	store types.TodoStore
+	cmds   chan types.Cmd
+	store  types.TodoStore
+	events *EventBroker
+	index  *search.Index
 }
 
+// NewTodoStoreActor builds the search index from what's already in store,
+// then keeps it up to date with every change made through the actor.
 func NewTodoStoreActor(store types.TodoStore) *TodoStoreActor {
+	index := search.NewIndex()
+	for id, todo := range types.AllTodos(context.Background(), store) {
+		index.Put(id, todo)
+	}
+
 	return &TodoStoreActor{
-		cmds:  make(chan types.Cmd, 1),
-		store: store,
+		cmds:   make(chan types.Cmd, 1),
+		store:  store,
+		events: NewEventBroker(DefaultReplaySize),
+		index:  index,
 	}
 }
 
+// Events is the broker every change made through the actor is published to.
+func (a *TodoStoreActor) Events() *EventBroker {
+	return a.events
+}
+
 func (a *TodoStoreActor) Run(ctx context.Context) {
 	for {
 		select {
@@ -45,6 +64,7 @@ func (a *TodoStoreActor) Run(ctx context.Context) {
 				slog.InfoContext(ctx, "Actor received AddTodoRequest")
 				id, err := a.store.AddTodo(m.Ctx, m.Todo)
 				if err == nil {
+					a.publish(m.Ctx, types.EventAdded, id)
 					m.Resp <- types.AddTodoResponse{Id: id}
 				} else {
 					m.Resp <- types.AddTodoResponse{Err: err}
@@ -53,7 +73,28 @@ func (a *TodoStoreActor) Run(ctx context.Context) {
 			case types.UpdateTodoStatusRequest:
 				slog.InfoContext(ctx, "Actor received UpdateTodoStatusRequest")
 				err := a.store.UpdateTodoStatus(m.Ctx, m.Id, m.Status)
-				m.Resp <- types.UpdateTodoStatusResponse{Err: err}
+				var todo types.Todo
+				if err == nil {
+					todo = a.publish(m.Ctx, types.EventUpdated, m.Id)
+				}
+				m.Resp <- types.UpdateTodoStatusResponse{Todo: todo, Err: err}
+
+			case types.BulkRequest:
+				slog.InfoContext(ctx, "Actor received BulkRequest", slog.Int("ops", len(m.Ops)))
+				results, err := a.store.ApplyBulk(m.Ctx, m.Ops)
+				if err == nil {
+					for _, r := range results {
+						switch r.Op {
+						case types.BulkCreate:
+							a.publish(m.Ctx, types.EventAdded, r.Id)
+						case types.BulkUpdate:
+							a.publish(m.Ctx, types.EventUpdated, r.Id)
+						case types.BulkDelete:
+							a.publishDeleted(r.Id, *r.Todo)
+						}
+					}
+				}
+				m.Resp <- types.BulkResponse{Results: results, Err: err}
 
 			case types.GetOverDueTodosRequest:
 				slog.InfoContext(ctx, "Actor received GetOverDueTodosRequest")
@@ -64,11 +105,59 @@ func (a *TodoStoreActor) Run(ctx context.Context) {
 				slog.InfoContext(ctx, "Actor received GetOverDueTodosRequest")
 				todos := a.store.GetTodosByStatus(m.Ctx, m.Status)
 				m.Resp <- types.GetTodosByStatusResponse{Todos: todos}
+
+			case types.QueryTodosRequest:
+				slog.InfoContext(ctx, "Actor received QueryTodosRequest")
+				page, err := a.store.QueryTodos(m.Ctx, m.Query)
+				m.Resp <- types.QueryTodosResponse{Page: page, Err: err}
+
+			case types.SearchTodosRequest:
+				slog.InfoContext(ctx, "Actor received SearchTodosRequest", slog.String("query", m.Query))
+				m.Resp <- types.SearchTodosResponse{Results: a.search(m.Ctx, m.Query, m.Limit)}
+
+			case types.GetStatsRequest:
+				slog.InfoContext(ctx, "Actor received GetStatsRequest")
+				todos := types.AllTodos(m.Ctx, a.store)
+				stats := types.ComputeStats(todos, m.Since, m.Until, time.Now(), types.StatsOldestOpen)
+				m.Resp <- types.GetStatsResponse{Stats: stats}
 			}
 		}
 	}
 }
 
+// search searches the index for up to limit of the todos that can be seen
+// from ctx.
+func (a *TodoStoreActor) search(ctx context.Context, query string, limit int) []types.SearchResult {
+	if _, ok := types.AccessFrom(ctx); !ok {
+		return a.index.Search(query, limit)
+	}
+	results := slices.DeleteFunc(a.index.Search(query, 0), func(r types.SearchResult) bool { return !r.Todo.Visible(ctx) })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// publish reads the todo's new state back from the store, reindexes it and
+// broadcasts it, returning the todo it read.
+func (a *TodoStoreActor) publish(ctx context.Context, eventType types.EventType, id string) types.Todo {
+	todo, err := a.store.GetTodo(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read todo for event", slog.String("todo_id", id), slog.String("error", err.Error()))
+		return todo
+	}
+	a.index.Put(id, todo)
+	a.events.Publish(types.TodoEvent{Type: eventType, TodoId: id, Todo: todo, Version: todo.Version})
+	return todo
+}
+
+// publishDeleted drops a deleted todo from the index and broadcasts it as it
+// was before it was deleted.
+func (a *TodoStoreActor) publishDeleted(id string, todo types.Todo) {
+	a.index.Remove(id)
+	a.events.Publish(types.TodoEvent{Type: types.EventDeleted, TodoId: id, Todo: todo, Version: todo.Version})
+}
+
 func (a *TodoStoreActor) Send(cmd types.Cmd) {
 	a.cmds <- cmd
 }
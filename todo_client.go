@@ -0,0 +1,25 @@
+package todoapp
+
+import (
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+	"grantjames.github.io/todo-app/views"
+)
+
+// TodoClient is what the CLI uses to read and change todos. TodoAPIClient
+// implements it over HTTP, other implementations can wrap or replace it.
+type TodoClient interface {
+	GetTodo(id string) (*types.Todo, error)
+	AddTodo(todo types.Todo) (string, error)
+	UpdateTodoStatus(id string, status types.Status) error
+	GetTodosByStatus(status types.Status) (map[string]types.Todo, error)
+	GetOverdueTodos() (map[string]types.Todo, error)
+	GetAllTodos() (map[string]types.Todo, error)
+	QueryTodos(q types.TodoQuery) (*types.TodoPage, error)
+	GetStats(since, until time.Time) (*types.TodoStats, error)
+	Search(query string, limit int) ([]types.SearchResult, error)
+	GetViews() ([]views.View, error)
+	GetViewTodos(name string) ([]types.TodoItem, error)
+	Bulk(ops []types.BulkOp) ([]types.BulkResult, error)
+}
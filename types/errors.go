@@ -0,0 +1,77 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors from stores, and from clients given an error response by the
+// server, match one of these with errors.Is so callers can tell them apart
+// without looking at the message.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid")
+	// ErrForbidden is for a todo the user can see but doesn't have the
+	// role to change
+	ErrForbidden = errors.New("forbidden")
+)
+
+// kindError has a message of its own but matches kind.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e kindError) Error() string { return e.msg }
+func (e kindError) Unwrap() error { return e.kind }
+
+// NotFoundf is an error matching ErrNotFound with a formatted message.
+func NotFoundf(format string, args ...any) error {
+	return kindError{ErrNotFound, fmt.Sprintf(format, args...)}
+}
+
+// Conflictf is an error matching ErrConflict with a formatted message.
+func Conflictf(format string, args ...any) error {
+	return kindError{ErrConflict, fmt.Sprintf(format, args...)}
+}
+
+// Invalidf is an error matching ErrInvalid with a formatted message.
+func Invalidf(format string, args ...any) error {
+	return kindError{ErrInvalid, fmt.Sprintf(format, args...)}
+}
+
+// Forbiddenf is an error matching ErrForbidden with a formatted message.
+func Forbiddenf(format string, args ...any) error {
+	return kindError{ErrForbidden, fmt.Sprintf(format, args...)}
+}
+
+// FieldError is what's wrong with one field of a request.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// ValidationError lists the invalid fields of a request. It matches
+// ErrInvalid.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Invalid is a ValidationError for a single field.
+func Invalid(field, format string, args ...any) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Detail: fmt.Sprintf(format, args...)}}}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Detail
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
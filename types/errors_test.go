@@ -0,0 +1,35 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("Formatted errors match their kind and keep their message", func(t *testing.T) {
+		err := fmt.Errorf("loading, %w", NotFoundf("no todo with id %s found", "1"))
+		if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalid) {
+			t.Errorf("got %v want it to match only ErrNotFound", err)
+		}
+		if err.Error() != "loading, no todo with id 1 found" {
+			t.Errorf("got message %q", err.Error())
+		}
+	})
+
+	t.Run("Validation errors match ErrInvalid and list their fields", func(t *testing.T) {
+		err := error(&ValidationError{Fields: []FieldError{{"status", "unknown"}, {"due", "not a date"}}})
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("got %v want it to match ErrInvalid", err)
+		}
+		if err.Error() != "status: unknown; due: not a date" {
+			t.Errorf("got message %q", err.Error())
+		}
+	})
+
+	t.Run("Unknown statuses are invalid", func(t *testing.T) {
+		if _, err := ParseStatus("sleeping"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("got %v want ErrInvalid", err)
+		}
+	})
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"maps"
+	"strings"
 	"time"
 )
 
@@ -13,25 +15,80 @@ const (
 	Completed  Status = "Completed"
 )
 
+// Statuses lists every status in the order a todo moves through them.
+var Statuses = []Status{NotStarted, Started, Completed}
+
+// Slug is the status in a form that's easy to type on the command line,
+// e.g. "not-started".
+func (s Status) Slug() string {
+	return strings.ReplaceAll(strings.ToLower(string(s)), " ", "-")
+}
+
+// ParseStatus accepts a status or its slug, ignoring case.
+func ParseStatus(s string) (Status, error) {
+	for _, status := range Statuses {
+		if strings.EqualFold(s, string(status)) || strings.EqualFold(s, status.Slug()) {
+			return status, nil
+		}
+	}
+	return "", Invalidf("unknown status %q", s)
+}
+
 type Todo struct {
 	Description string     `json:"description"`
 	Status      Status     `json:"status"`
 	Due         *time.Time `json:"due"`
 	Updated     time.Time  `json:"updated"`
+	List        string     `json:"list,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	// Priority is from 1, the lowest, to MaxPriority, or 0 if it isn't set
+	Priority int       `json:"priority,omitempty"`
+	Created  time.Time `json:"created,omitzero"`
+	// When the todo last moved into each status
+	StatusChanged map[Status]time.Time `json:"status_changed,omitempty"`
+	// Incremented every time the todo changes
+	Version int `json:"version,omitempty"`
+	// The id of the user the todo belongs to, if the server has users
+	Owner string `json:"owner,omitempty"`
+	// The id of the user the todo is assigned to
+	Assignee string `json:"assignee,omitempty"`
+	// The users the todo is shared with, by id, and their roles on it
+	Members map[string]Role `json:"members,omitempty"`
 }
 
 func NewTodo(desc string, due *time.Time) Todo {
+	now := time.Now()
 	return Todo{
-		Description: desc,
-		Due:         due,
-		Status:      NotStarted,
-		Updated:     time.Now(),
+		Description:   desc,
+		Due:           due,
+		Status:        NotStarted,
+		Updated:       now,
+		Created:       now,
+		StatusChanged: map[Status]time.Time{NotStarted: now},
+		Version:       1,
 	}
 }
 
 func (t *Todo) SetStatus(s Status) {
+	now := time.Now()
 	t.Status = s
-	t.Updated = time.Now()
+	t.Updated = now
+	t.Version++
+
+	// Todos are passed around by value, so copy the map rather than
+	// changing one that other copies share
+	changed := maps.Clone(t.StatusChanged)
+	if changed == nil {
+		changed = map[Status]time.Time{}
+	}
+	changed[s] = now
+	t.StatusChanged = changed
+}
+
+// StatusTime returns when the todo last moved into status s, if known.
+func (t *Todo) StatusTime(s Status) (time.Time, bool) {
+	at, ok := t.StatusChanged[s]
+	return at, ok
 }
 
 func (t *Todo) IsOverdue() bool {
@@ -44,9 +101,13 @@ func (t *Todo) String() string {
 	if t.Due != nil {
 		due = t.Due.Format("02/01/2006")
 	}
+	tags := ""
+	if len(t.Tags) > 0 {
+		tags = fmt.Sprintf("\n  Tags: %s", strings.Join(t.Tags, ", "))
+	}
 	return fmt.Sprintf(`%s
   Status: %s
-  Due: %s
+  Due: %s%s
   Updated: %s
-	`, t.Description, t.Status, due, t.Updated.Format("02/01/2006 at 15:04:05"))
+	`, t.Description, t.Status, due, tags, t.Updated.Format("02/01/2006 at 15:04:05"))
 }
@@ -0,0 +1,191 @@
+package types
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Role is what a user can do with a todo, or every todo in a list, that's
+// shared with them.
+type Role string
+
+const (
+	// RoleViewer can see the todo
+	RoleViewer Role = "viewer"
+	// RoleEditor can also change it
+	RoleEditor Role = "editor"
+	// RoleOwner can also delete, assign and share it
+	RoleOwner Role = "owner"
+)
+
+// Roles lists every role, each allowing everything the ones before it do.
+var Roles = []Role{RoleViewer, RoleEditor, RoleOwner}
+
+// ParseRole accepts a role, ignoring case.
+func ParseRole(s string) (Role, error) {
+	for _, role := range Roles {
+		if strings.EqualFold(s, string(role)) {
+			return role, nil
+		}
+	}
+	return "", Invalidf("unknown role %q, expected viewer, editor or owner", s)
+}
+
+// Allows reports whether the role can do everything need can. No role
+// allows nothing.
+func (r Role) Allows(need Role) bool {
+	return r != "" && slices.Index(Roles, r) >= slices.Index(Roles, need)
+}
+
+// ListRef is one user's list. List names are only unique to their owner.
+type ListRef struct {
+	// The id of the user the list's todos belong to
+	Owner string `json:"owner"`
+	List  string `json:"list"`
+}
+
+// Member is a user a todo or list is shared with.
+type Member struct {
+	UserId string `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+	Role   Role   `json:"role"`
+}
+
+// Access is who the stores are scoped to: a user, and the lists other users
+// have shared with them.
+type Access struct {
+	User  string
+	Lists map[ListRef]Role
+}
+
+type accessKey struct{}
+
+// WithAccess scopes the stores to the todos a has a role on. Given the
+// returned context they only see those todos, and give the todos they add
+// to a's user. A context without access sees every todo.
+func WithAccess(ctx context.Context, a Access) context.Context {
+	return context.WithValue(ctx, accessKey{}, a)
+}
+
+// WithOwner scopes the stores to the todos of the user with the id owner,
+// and those shared with them on the todo itself.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return WithAccess(ctx, Access{User: owner})
+}
+
+// AccessFrom returns the access ctx is scoped to, if it is. A nil ctx isn't.
+func AccessFrom(ctx context.Context) (Access, bool) {
+	if ctx == nil {
+		return Access{}, false
+	}
+	a, ok := ctx.Value(accessKey{}).(Access)
+	return a, ok
+}
+
+// OwnerFrom returns the id of the user ctx is scoped to, if it is.
+func OwnerFrom(ctx context.Context) (string, bool) {
+	a, ok := AccessFrom(ctx)
+	return a.User, ok
+}
+
+// RoleOf returns a's role on the todo, if it has one. Users own their own
+// todos, can edit the todos assigned to them, and otherwise have the role
+// the todo or its list was shared with them as, whichever allows the most.
+func (t Todo) RoleOf(a Access) (Role, bool) {
+	if t.Owner == a.User {
+		return RoleOwner, true
+	}
+	best := slices.Index(Roles, t.Members[a.User])
+	if t.List != "" {
+		best = max(best, slices.Index(Roles, a.Lists[ListRef{t.Owner, t.List}]))
+	}
+	if t.Assignee == a.User {
+		best = max(best, slices.Index(Roles, RoleEditor))
+	}
+	if best < 0 {
+		return "", false
+	}
+	return Roles[best], true
+}
+
+// Role returns the role ctx has on the todo. A context without access owns
+// every todo.
+func (t Todo) Role(ctx context.Context) (Role, bool) {
+	a, ok := AccessFrom(ctx)
+	if !ok {
+		return RoleOwner, true
+	}
+	return t.RoleOf(a)
+}
+
+// Visible reports whether the todo can be seen from ctx.
+func (t Todo) Visible(ctx context.Context) bool {
+	_, ok := t.Role(ctx)
+	return ok
+}
+
+// Allow returns an error matching ErrForbidden unless ctx has at least the
+// role need on the todo.
+func (t Todo) Allow(ctx context.Context, need Role) error {
+	role, _ := t.Role(ctx)
+	if !role.Allows(need) {
+		return Forbiddenf("this needs the %s role on the todo, not %s", need, role)
+	}
+	return nil
+}
+
+// VisibleTodos returns the todos that can be seen from ctx, which is todos
+// itself if ctx isn't scoped.
+func VisibleTodos(ctx context.Context, todos map[string]Todo) map[string]Todo {
+	if _, ok := AccessFrom(ctx); !ok {
+		return todos
+	}
+	visible := map[string]Todo{}
+	for id, t := range todos {
+		if t.Visible(ctx) {
+			visible[id] = t
+		}
+	}
+	return visible
+}
+
+// Own gives a todo being added to the user ctx is scoped to.
+func (t *Todo) Own(ctx context.Context) {
+	if owner, ok := OwnerFrom(ctx); ok {
+		t.Owner = owner
+	}
+}
+
+// ApplyScopedBulk is ApplyBulk on the todos that can be seen from ctx,
+// giving any it creates to ctx's user. Each update or delete also needs the
+// role it's for, see BulkOp.Needs. It returns all of todos with the changes
+// applied.
+func ApplyScopedBulk(ctx context.Context, todos map[string]Todo, ops []BulkOp, newId func() string) (map[string]Todo, []BulkResult, error) {
+	if _, ok := AccessFrom(ctx); !ok {
+		return ApplyBulk(todos, ops, newId)
+	}
+
+	visible := VisibleTodos(ctx, todos)
+	allow := func(op BulkOp, t Todo) error { return t.Allow(ctx, op.Needs(t)) }
+	changed, results, err := applyBulk(visible, ops, newId, allow)
+	if err != nil {
+		return nil, results, err
+	}
+	for i, r := range results {
+		if r.Op == BulkCreate {
+			t := changed[r.Id]
+			t.Own(ctx)
+			changed[r.Id] = t
+			results[i].Todo = &t
+		}
+	}
+
+	all := maps.Clone(todos)
+	for id := range visible {
+		delete(all, id)
+	}
+	maps.Copy(all, changed)
+	return all, results, nil
+}
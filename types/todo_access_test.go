@@ -0,0 +1,46 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	todo := Todo{Description: "Plan the offsite", List: "work", Owner: "alice", Assignee: "carol",
+		Members: map[string]Role{"bob": RoleViewer}}
+	work := ListRef{Owner: "alice", List: "work"}
+
+	cases := []struct {
+		name   string
+		access Access
+		want   Role
+	}{
+		{"The owner", Access{User: "alice"}, RoleOwner},
+		{"A member of the todo", Access{User: "bob"}, RoleViewer},
+		{"A member of the list with more than on the todo", Access{User: "bob", Lists: map[ListRef]Role{work: RoleOwner}}, RoleOwner},
+		{"The assignee", Access{User: "carol"}, RoleEditor},
+		{"Someone else's list of the same name", Access{User: "dan", Lists: map[ListRef]Role{{"erin", "work"}: RoleEditor}}, ""},
+		{"A stranger", Access{User: "dan"}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := todo.RoleOf(c.access)
+			if got != c.want || ok != (c.want != "") {
+				t.Errorf("got %q, %v want %q", got, ok, c.want)
+			}
+		})
+	}
+
+	t.Run("Changes need a role allowing them", func(t *testing.T) {
+		ctx := WithOwner(t.Context(), "bob")
+		if err := todo.Allow(ctx, RoleViewer); err != nil {
+			t.Errorf("got %v want a viewer to view", err)
+		}
+		if err := todo.Allow(ctx, RoleEditor); !errors.Is(err, ErrForbidden) {
+			t.Errorf("got %v want ErrForbidden", err)
+		}
+		if err := todo.Allow(t.Context(), RoleOwner); err != nil {
+			t.Errorf("got %v want an unscoped context to own every todo", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"context"
+	"time"
 )
 
 type Cmd interface{ isCmd() }
@@ -54,7 +55,9 @@ type UpdateTodoStatusRequest struct {
 func (UpdateTodoStatusRequest) isCmd() {}
 
 type UpdateTodoStatusResponse struct {
-	Err error
+	// Todo is the todo after the change
+	Todo Todo
+	Err  error
 }
 
 type GetTodosByStatusRequest struct {
@@ -79,3 +82,57 @@ func (GetOverDueTodosRequest) isCmd() {}
 type GetOverDueTodosResponse struct {
 	Todos map[string]Todo
 }
+
+type GetStatsRequest struct {
+	Ctx   context.Context
+	Since time.Time
+	Until time.Time
+	Resp  chan GetStatsResponse
+}
+
+func (GetStatsRequest) isCmd() {}
+
+type GetStatsResponse struct {
+	Stats TodoStats
+}
+
+type QueryTodosRequest struct {
+	Ctx   context.Context
+	Query TodoQuery
+	Resp  chan QueryTodosResponse
+}
+
+func (QueryTodosRequest) isCmd() {}
+
+type QueryTodosResponse struct {
+	Page TodoPage
+	Err  error
+}
+
+type SearchTodosRequest struct {
+	Ctx   context.Context
+	Query string
+	Limit int
+	Resp  chan SearchTodosResponse
+}
+
+func (SearchTodosRequest) isCmd() {}
+
+type SearchTodosResponse struct {
+	Results []SearchResult
+}
+
+// BulkRequest applies a batch of changes as one message, so no other request
+// sees it part way through.
+type BulkRequest struct {
+	Ctx  context.Context
+	Ops  []BulkOp
+	Resp chan BulkResponse
+}
+
+func (BulkRequest) isCmd() {}
+
+type BulkResponse struct {
+	Results []BulkResult
+	Err     error
+}
@@ -0,0 +1,251 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// MaxBulkOps limits how many operations one bulk request can contain.
+const MaxBulkOps = 1000
+
+type BulkOpType string
+
+const (
+	BulkCreate BulkOpType = "create"
+	BulkUpdate BulkOpType = "update"
+	BulkDelete BulkOpType = "delete"
+)
+
+// ErrBulkFailed is returned when an operation in a batch fails, in which
+// case none of them were applied.
+var ErrBulkFailed = errors.New("an operation failed so none were applied")
+
+// BulkNotApplied is the error given for ops that would have succeeded in a
+// batch that failed.
+const BulkNotApplied = "not applied"
+
+// BulkOp is one change in a batch. Creates take Todo, with only the fields
+// clients can set, updates take Id and whichever of the other fields are
+// changing, deletes take Id.
+type BulkOp struct {
+	Op          BulkOpType `json:"op"`
+	Id          string     `json:"id,omitempty"`
+	Todo        *Todo      `json:"todo,omitempty"`
+	Status      Status     `json:"status,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	List        *string    `json:"list,omitempty"`
+	Tags        *[]string  `json:"tags,omitempty"`
+	Priority    *int       `json:"priority,omitempty"`
+	// Assignee is the id of the user to assign the todo to, or empty to
+	// unassign it
+	Assignee *string `json:"assignee,omitempty"`
+	// Members are the users whose role on the todo is changing, by id. An
+	// empty role stops sharing the todo with them.
+	Members map[string]Role `json:"members,omitempty"`
+}
+
+// BulkResult is the outcome of the operation at the same index in a batch.
+// Todo is the todo after the change, or before it for a delete.
+type BulkResult struct {
+	Op    BulkOpType `json:"op"`
+	Id    string     `json:"id,omitempty"`
+	Ok    bool       `json:"ok"`
+	Todo  *Todo      `json:"todo,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// ApplyBulk applies ops in order to a copy of todos. If every op succeeds
+// it returns the changed copy, otherwise it returns ErrBulkFailed and todos
+// should be left as they were. Results are returned either way, with an
+// error on each op that failed. Later ops see the effect of earlier ones,
+// e.g. a todo can be updated then deleted, but the ids of created todos
+// aren't known until the batch is applied so no other op can refer to them.
+func ApplyBulk(todos map[string]Todo, ops []BulkOp, newId func() string) (map[string]Todo, []BulkResult, error) {
+	return applyBulk(todos, ops, newId, nil)
+}
+
+// applyBulk is ApplyBulk, which also fails any update or delete that allow
+// returns an error for, if it isn't nil. It's given the todo as it is
+// before the op.
+func applyBulk(todos map[string]Todo, ops []BulkOp, newId func() string, allow func(BulkOp, Todo) error) (map[string]Todo, []BulkResult, error) {
+	if len(ops) > MaxBulkOps {
+		return nil, nil, fmt.Errorf("a batch can have at most %d operations", MaxBulkOps)
+	}
+
+	changed := maps.Clone(todos)
+	results := make([]BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		result, err := applyBulkOp(changed, op, newId, allow)
+		if err != nil {
+			result.Error = err.Error()
+			failed = true
+		} else {
+			result.Ok = true
+		}
+		results[i] = result
+	}
+
+	if failed {
+		// Nothing was applied, so don't report anything as done
+		for i := range results {
+			results[i].Ok = false
+			if results[i].Error == "" {
+				results[i].Error = BulkNotApplied
+			}
+		}
+		return nil, results, ErrBulkFailed
+	}
+	return changed, results, nil
+}
+
+// Needs is the role on t the op needs. Deleting, assigning and sharing a
+// todo need its owner, as does moving it to another list, since that
+// changes who it's shared with. Other updates need an editor.
+func (op BulkOp) Needs(t Todo) Role {
+	if op.Op == BulkDelete || op.Assignee != nil || op.Members != nil || op.List != nil && *op.List != t.List {
+		return RoleOwner
+	}
+	return RoleEditor
+}
+
+// Validate checks op on its own, without the todos it would apply to.
+func (op BulkOp) Validate() error {
+	switch op.Op {
+	case BulkCreate:
+		if op.Todo == nil {
+			return Invalidf("a create needs a todo with a description")
+		}
+		return op.Todo.ValidateNew()
+
+	case BulkUpdate:
+		if op.Id == "" {
+			return Invalid("id", "is required")
+		}
+		if op.Status != "" {
+			if _, err := ParseStatus(string(op.Status)); err != nil {
+				return err
+			}
+		}
+		if op.Description != nil && *op.Description == "" {
+			return Invalidf("description can't be empty")
+		}
+		var v validator
+		if op.Description != nil {
+			v.description(*op.Description)
+		}
+		v.due(op.Due, time.Now())
+		if op.List != nil {
+			v.list(*op.List)
+		}
+		if op.Tags != nil {
+			v.tags(*op.Tags)
+		}
+		if op.Priority != nil {
+			v.priority(*op.Priority)
+		}
+		for user, role := range op.Members {
+			if _, err := ParseRole(string(role)); role != "" && err != nil {
+				v.add("members", "%v for %s", err, user)
+			}
+		}
+		return v.err()
+
+	case BulkDelete:
+		if op.Id == "" {
+			return Invalid("id", "is required")
+		}
+		return nil
+	}
+	return Invalidf("unknown op %q, expected create, update or delete", op.Op)
+}
+
+func applyBulkOp(todos map[string]Todo, op BulkOp, newId func() string, allow func(BulkOp, Todo) error) (BulkResult, error) {
+	result := BulkResult{Op: op.Op, Id: op.Id}
+	if err := op.Validate(); err != nil {
+		return result, err
+	}
+
+	switch op.Op {
+	case BulkCreate:
+		result.Id = newId()
+		todo := *op.Todo
+		todo.Init(time.Now())
+		todos[result.Id] = todo
+		result.Todo = &todo
+
+	case BulkUpdate:
+		todo, ok := todos[op.Id]
+		if !ok {
+			return result, NotFoundf("no todo with id %s found", op.Id)
+		}
+		if allow != nil {
+			if err := allow(op, todo); err != nil {
+				return result, err
+			}
+		}
+		if op.Status != "" {
+			status, _ := ParseStatus(string(op.Status))
+			todo.SetStatus(status)
+		} else {
+			todo.Updated = time.Now()
+			todo.Version++
+		}
+		if op.Description != nil {
+			todo.Description = *op.Description
+		}
+		if op.Due != nil {
+			todo.Due = op.Due
+		}
+		if op.List != nil {
+			todo.List = *op.List
+		}
+		if op.Tags != nil {
+			todo.Tags = *op.Tags
+		}
+		if op.Priority != nil {
+			todo.Priority = *op.Priority
+		}
+		if op.Assignee != nil {
+			todo.Assignee = *op.Assignee
+		}
+		if op.Members != nil {
+			// Like StatusChanged, copy the map rather than change one other
+			// copies share
+			members := maps.Clone(todo.Members)
+			if members == nil {
+				members = map[string]Role{}
+			}
+			for user, role := range op.Members {
+				if role == "" {
+					delete(members, user)
+				} else {
+					members[user], _ = ParseRole(string(role))
+				}
+			}
+			todo.Members = members
+			if len(members) == 0 {
+				todo.Members = nil
+			}
+		}
+		todos[op.Id] = todo
+		result.Todo = &todo
+
+	case BulkDelete:
+		todo, ok := todos[op.Id]
+		if !ok {
+			return result, NotFoundf("no todo with id %s found", op.Id)
+		}
+		if allow != nil {
+			if err := allow(op, todo); err != nil {
+				return result, err
+			}
+		}
+		delete(todos, op.Id)
+		result.Todo = &todo
+	}
+	return result, nil
+}
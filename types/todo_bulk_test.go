@@ -0,0 +1,61 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestApplyBulk(t *testing.T) {
+	todos := map[string]Todo{
+		"a": NewTodo("First", nil),
+		"b": NewTodo("Second", nil),
+	}
+	n := 0
+	newId := func() string {
+		n++
+		return fmt.Sprintf("new-%d", n)
+	}
+
+	t.Run("Applies every op in order", func(t *testing.T) {
+		list := "sprint"
+		changed, results, err := ApplyBulk(todos, []BulkOp{
+			{Op: BulkCreate, Todo: &Todo{Description: "Third"}},
+			{Op: BulkUpdate, Id: "a", Status: "completed", List: &list},
+			{Op: BulkDelete, Id: "b"},
+		}, newId)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 3 || !results[0].Ok || results[0].Id != "new-1" {
+			t.Errorf("got %+v want three ok results starting with the created id", results)
+		}
+		if a := changed["a"]; a.Status != Completed || a.List != "sprint" || a.Version != 2 {
+			t.Errorf("got %+v want a completed todo in sprint at version 2", a)
+		}
+		if _, ok := changed["b"]; ok || len(changed) != 2 {
+			t.Errorf("got %v want b deleted and the new todo added", changed)
+		}
+		if todos["a"].Status != NotStarted || len(todos) != 2 {
+			t.Errorf("the original todos were changed")
+		}
+	})
+
+	t.Run("Applies nothing if any op fails", func(t *testing.T) {
+		changed, results, err := ApplyBulk(todos, []BulkOp{
+			{Op: BulkDelete, Id: "a"},
+			{Op: BulkUpdate, Id: "a", Status: Started},
+			{Op: BulkUpdate, Id: "b", Status: "later"},
+			{Op: "archive", Id: "b"},
+		}, newId)
+		if !errors.Is(err, ErrBulkFailed) || changed != nil {
+			t.Fatalf("got %v and error %v want %v", changed, err, ErrBulkFailed)
+		}
+		want := []string{BulkNotApplied, "no todo with id a found", `unknown status "later"`, `unknown op "archive", expected create, update or delete`}
+		for i, r := range results {
+			if r.Ok || r.Error != want[i] {
+				t.Errorf("op %d: got %+v want error %q", i, r, want[i])
+			}
+		}
+	})
+}
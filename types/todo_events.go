@@ -0,0 +1,57 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventAdded   EventType = "added"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+	// EventReset is sent to subscribers resuming from an event that's no
+	// longer available to replay, so they know to fetch todos afresh.
+	EventReset EventType = "reset"
+)
+
+// TodoEvent describes a change made to a todo by the actor. Id increases by
+// one for every event so clients can resume from the last one they saw.
+type TodoEvent struct {
+	Id     uint64    `json:"id"`
+	Type   EventType `json:"type"`
+	TodoId string    `json:"todo_id"`
+	// The todo after the change, or as it was before it was deleted
+	Todo    Todo      `json:"todo"`
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+}
+
+// EventFilter narrows a stream of events. Empty fields match everything.
+type EventFilter struct {
+	List   string
+	Tag    string
+	Status Status
+	// Access is set by the server to who's streaming the events, who only
+	// get those for todos they have a role on
+	Access *Access
+}
+
+func (f EventFilter) Matches(e TodoEvent) bool {
+	if f.Access != nil {
+		if _, ok := e.Todo.RoleOf(*f.Access); !ok {
+			return false
+		}
+	}
+	if f.List != "" && e.Todo.List != f.List {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(e.Todo.Tags, f.Tag) {
+		return false
+	}
+	if f.Status != "" && e.Todo.Status != f.Status {
+		return false
+	}
+	return true
+}
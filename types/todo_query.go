@@ -0,0 +1,243 @@
+package types
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SortFields are the fields todos can be sorted by.
+var SortFields = []string{"due", "updated", "created", "description", "status", "list", "priority"}
+
+// DefaultSort is the order used when a query doesn't give one.
+var DefaultSort = []SortKey{{Field: "created"}}
+
+var ErrInvalidCursor = Invalidf("invalid cursor")
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma separated list of fields, each descending if
+// prefixed with "-", such as "due,-updated".
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	for field := range strings.SplitSeq(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if !slices.Contains(SortFields, key.Field) {
+			return nil, Invalid("sort", "unknown sort field %q, expected one of %s", key.Field, strings.Join(SortFields, ", "))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func FormatSort(keys []SortKey) string {
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = k.Field
+		if k.Desc {
+			fields[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// TodoFilter is a predicate over todos, such as a parsed filter expression.
+// String gives the filter's text so it can be sent to the server.
+type TodoFilter interface {
+	Match(t Todo) bool
+	String() string
+}
+
+// TodoQuery selects a page of todos. With no Status, Overdue or Filter it
+// matches what GetAllTodos returns, unless All includes completed todos.
+type TodoQuery struct {
+	Status  Status
+	Overdue bool
+	All     bool
+	Filter  TodoFilter
+	Sort    []SortKey
+	// Assignee only matches todos assigned to the user with this id
+	Assignee string
+	// Limit is the most todos to return, or every todo if 0
+	Limit int
+	// Cursor continues from the page that returned it
+	Cursor string
+}
+
+func (q TodoQuery) Matches(t Todo) bool {
+	switch {
+	case q.Status != "" && t.Status != q.Status:
+		return false
+	case q.Overdue && !t.IsOverdue():
+		return false
+	case q.Assignee != "" && t.Assignee != q.Assignee:
+		return false
+	case q.Filter != nil && !q.Filter.Match(t):
+		return false
+	case q.Status == "" && !q.Overdue && q.Filter == nil && !q.All && t.Status == Completed:
+		return false
+	}
+	return true
+}
+
+// TodoItem is a todo along with its id, for responses that are ordered
+// lists rather than maps keyed by id.
+type TodoItem struct {
+	Id string `json:"id"`
+	Todo
+}
+
+type TodoPage struct {
+	Todos []TodoItem `json:"todos"`
+	// NextCursor fetches the following page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is where a page ended: the sort values and id of its last todo.
+type cursor struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	Id     string    `json:"id"`
+}
+
+// QueryTodos applies q to todos. Stores without a better way to answer a
+// query can use it over their todos.
+func QueryTodos(todos map[string]Todo, q TodoQuery) (TodoPage, error) {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = DefaultSort
+	}
+
+	type keyed struct {
+		item   TodoItem
+		values []*string
+	}
+	var items []keyed
+	for id, t := range todos {
+		if q.Matches(t) {
+			items = append(items, keyed{TodoItem{Id: id, Todo: t}, sortValues(t, sort)})
+		}
+	}
+	compare := func(aValues []*string, aId string, bValues []*string, bId string) int {
+		for i, k := range sort {
+			if c := compareValues(aValues[i], bValues[i], k.Desc); c != 0 {
+				return c
+			}
+		}
+		// Ids make the order total, so pages never overlap or skip
+		return cmp.Compare(aId, bId)
+	}
+	slices.SortFunc(items, func(a, b keyed) int {
+		return compare(a.values, a.item.Id, b.values, b.item.Id)
+	})
+
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil || after.Sort != FormatSort(sort) || len(after.Values) != len(sort) {
+			return TodoPage{}, ErrInvalidCursor
+		}
+		start, _ := slices.BinarySearchFunc(items, after, func(item keyed, c cursor) int {
+			if compare(item.values, item.item.Id, c.Values, c.Id) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		items = items[start:]
+	}
+
+	page := TodoPage{Todos: []TodoItem{}}
+	if q.Limit > 0 && len(items) > q.Limit {
+		last := items[q.Limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: FormatSort(sort), Values: last.values, Id: last.item.Id})
+		items = items[:q.Limit]
+	}
+	for _, item := range items {
+		page.Todos = append(page.Todos, item.item)
+	}
+	return page, nil
+}
+
+// sortValues turns the todo's sort fields into strings that order the same
+// way, with nil for a value that isn't set.
+func sortValues(t Todo, sort []SortKey) []*string {
+	values := make([]*string, len(sort))
+	for i, k := range sort {
+		var v string
+		switch k.Field {
+		case "due":
+			if t.Due == nil {
+				continue
+			}
+			v = sortableTime(*t.Due)
+		case "updated":
+			v = sortableTime(t.Updated)
+		case "created":
+			if t.Created.IsZero() {
+				continue
+			}
+			v = sortableTime(t.Created)
+		case "description":
+			v = strings.ToLower(t.Description)
+		case "status":
+			v = fmt.Sprintf("%02d", slices.Index(Statuses, t.Status)+1)
+		case "list":
+			if t.List == "" {
+				continue
+			}
+			v = strings.ToLower(t.List)
+		case "priority":
+			if t.Priority == 0 {
+				continue
+			}
+			v = fmt.Sprintf("%02d", t.Priority)
+		}
+		values[i] = &v
+	}
+	return values
+}
+
+func sortableTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000")
+}
+
+// compareValues orders unset values last whichever the direction.
+func compareValues(a, b *string, desc bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case desc:
+		return strings.Compare(*b, *a)
+	default:
+		return strings.Compare(*a, *b)
+	}
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
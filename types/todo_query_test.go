@@ -0,0 +1,95 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQueryTodos(t *testing.T) {
+	day := func(d int) *time.Time {
+		due := time.Date(2026, 11, d, 0, 0, 0, 0, time.UTC)
+		return &due
+	}
+	todos := map[string]Todo{}
+	for i, due := range []*time.Time{day(3), nil, day(1), day(2), nil, day(1)} {
+		todo := NewTodo(fmt.Sprintf("Todo %d", i), due)
+		if i == 5 {
+			todo.SetStatus(Completed)
+		}
+		todos[fmt.Sprintf("id-%d", i)] = todo
+	}
+
+	t.Run("Sorts with unset values last and ids breaking ties", func(t *testing.T) {
+		sort, _ := ParseSort("due")
+		page, err := QueryTodos(todos, TodoQuery{Sort: sort, All: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertIds(t, page.Todos, "id-2", "id-5", "id-3", "id-0", "id-1", "id-4")
+
+		sort, _ = ParseSort("-due")
+		page, _ = QueryTodos(todos, TodoQuery{Sort: sort})
+		assertIds(t, page.Todos, "id-0", "id-3", "id-2", "id-1", "id-4")
+	})
+
+	t.Run("Pages through every todo exactly once", func(t *testing.T) {
+		sort, _ := ParseSort("due,-description")
+		q := TodoQuery{Sort: sort, All: true, Limit: 4}
+		var all []TodoItem
+		for {
+			page, err := QueryTodos(todos, q)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			all = append(all, page.Todos...)
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		assertIds(t, all, "id-5", "id-2", "id-3", "id-0", "id-4", "id-1")
+	})
+
+	t.Run("Rejects cursors from a different sort", func(t *testing.T) {
+		page, _ := QueryTodos(todos, TodoQuery{Limit: 1})
+		sort, _ := ParseSort("due")
+		_, err := QueryTodos(todos, TodoQuery{Sort: sort, Limit: 1, Cursor: page.NextCursor})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("got error %v want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("Sorts by priority, highest first when descending", func(t *testing.T) {
+		prioritised := map[string]Todo{}
+		for i, priority := range []int{2, 0, 5, 2} {
+			todo := NewTodo(fmt.Sprintf("Todo %d", i), nil)
+			todo.Priority = priority
+			prioritised[fmt.Sprintf("id-%d", i)] = todo
+		}
+		sort, err := ParseSort("-priority")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		page, _ := QueryTodos(prioritised, TodoQuery{Sort: sort})
+		assertIds(t, page.Todos, "id-2", "id-0", "id-3", "id-1")
+	})
+
+	t.Run("Rejects unknown sort fields", func(t *testing.T) {
+		if _, err := ParseSort("due,-colour"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func assertIds(t testing.TB, items []TodoItem, want ...string) {
+	t.Helper()
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Id)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got ids %v want %v", got, want)
+	}
+}
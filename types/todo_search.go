@@ -0,0 +1,17 @@
+package types
+
+// TextRange is the byte offsets [Start, End) of part of a string.
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchResult is a todo matching a search, with the part of its
+// description to show and where the matching words are in it.
+type SearchResult struct {
+	Id         string      `json:"id"`
+	Todo       Todo        `json:"todo"`
+	Score      float64     `json:"score"`
+	Snippet    string      `json:"snippet"`
+	Highlights []TextRange `json:"highlights,omitempty"`
+}
@@ -0,0 +1,222 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxStatsDays limits how many days a stats report can cover.
+	MaxStatsDays = 366
+	// DefaultStatsDays is the range reported on when no since is given.
+	DefaultStatsDays = 30
+	// StatsOldestOpen is how many of the oldest open todos are reported.
+	StatsOldestOpen = 5
+)
+
+type PeriodCount struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type OpenTodo struct {
+	Id          string    `json:"id"`
+	Description string    `json:"description"`
+	Status      Status    `json:"status"`
+	Created     time.Time `json:"created"`
+	AgeDays     int       `json:"age_days"`
+}
+
+type TodoStats struct {
+	Since    time.Time      `json:"since"`
+	Until    time.Time      `json:"until"`
+	Total    int            `json:"total"`
+	ByStatus map[Status]int `json:"by_status"`
+
+	CompletedPerDay  []PeriodCount `json:"completed_per_day"`
+	CompletedPerWeek []PeriodCount `json:"completed_per_week"`
+
+	// Average hours from creation to completion, and from being started to
+	// completion, for todos completed in the range. Todos without the
+	// timestamps needed aren't counted.
+	AverageLeadTimeHours  float64 `json:"average_lead_time_hours"`
+	LeadTimeSamples       int     `json:"lead_time_samples"`
+	AverageCycleTimeHours float64 `json:"average_cycle_time_hours"`
+	CycleTimeSamples      int     `json:"cycle_time_samples"`
+
+	// How many todos were overdue at the end of each day
+	OverduePerDay []PeriodCount `json:"overdue_per_day"`
+	OldestOpen    []OpenTodo    `json:"oldest_open"`
+}
+
+// ValidateStatsRange checks a since/until pair can be reported on.
+func ValidateStatsRange(since, until time.Time) error {
+	if !since.Before(until) {
+		return Invalid("since", "%s must be before until (%s)", since.Format(time.DateOnly), until.Format(time.DateOnly))
+	}
+	if until.Sub(since) > MaxStatsDays*24*time.Hour {
+		return Invalid("until", "stats can cover at most %d days", MaxStatsDays)
+	}
+	return nil
+}
+
+// ParseStatsRange parses since and until, each either a yyyy-mm-dd date in
+// loc or an RFC 3339 timestamp. until defaults to now and a date includes
+// the whole day. since defaults to DefaultStatsDays before until.
+func ParseStatsRange(since, until string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	end := now
+	if until != "" {
+		t, err := parseStatsTime(until, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, Invalid("until", "%v", err)
+		}
+		end = t
+		if len(until) == len(time.DateOnly) {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	y, m, d := end.In(loc).AddDate(0, 0, -DefaultStatsDays).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if since != "" {
+		t, err := parseStatsTime(since, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, Invalid("since", "%v", err)
+		}
+		start = t
+	}
+
+	if err := ValidateStatsRange(start, end); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+func parseStatsTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a yyyy-mm-dd date or RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// ComputeStats reports on todos between since and until, with days starting
+// at midnight in since's location. oldest is how many open todos to list.
+func ComputeStats(todos map[string]Todo, since, until, now time.Time, oldest int) TodoStats {
+	stats := TodoStats{
+		Since:    since,
+		Until:    until,
+		ByStatus: map[Status]int{},
+	}
+
+	days := dayStarts(since, until)
+	completedPerDay := make([]int, len(days))
+	overduePerDay := make([]int, len(days))
+
+	var leadTotal, cycleTotal time.Duration
+	var open []OpenTodo
+
+	for id, t := range todos {
+		created := t.createdOrUpdated()
+		if created.After(until) {
+			continue
+		}
+		stats.Total++
+		stats.ByStatus[t.Status]++
+
+		completedAt, completed := t.StatusTime(Completed)
+		completed = completed && t.Status == Completed
+
+		if completed && !completedAt.Before(since) && completedAt.Before(until) {
+			completedPerDay[dayIndex(days, completedAt)]++
+
+			if !t.Created.IsZero() {
+				leadTotal += completedAt.Sub(t.Created)
+				stats.LeadTimeSamples++
+			}
+			if startedAt, ok := t.StatusTime(Started); ok && startedAt.Before(completedAt) {
+				cycleTotal += completedAt.Sub(startedAt)
+				stats.CycleTimeSamples++
+			}
+		}
+
+		if t.Due != nil {
+			for i, day := range days {
+				end := day.AddDate(0, 0, 1)
+				stillOpen := !completed || completedAt.After(end)
+				if t.Due.Before(day) && !created.After(end) && stillOpen {
+					overduePerDay[i]++
+				}
+			}
+		}
+
+		if t.Status != Completed {
+			open = append(open, OpenTodo{
+				Id:          id,
+				Description: t.Description,
+				Status:      t.Status,
+				Created:     created,
+				AgeDays:     int(now.Sub(created).Hours() / 24),
+			})
+		}
+	}
+
+	if stats.LeadTimeSamples > 0 {
+		stats.AverageLeadTimeHours = leadTotal.Hours() / float64(stats.LeadTimeSamples)
+	}
+	if stats.CycleTimeSamples > 0 {
+		stats.AverageCycleTimeHours = cycleTotal.Hours() / float64(stats.CycleTimeSamples)
+	}
+
+	for i, day := range days {
+		stats.CompletedPerDay = append(stats.CompletedPerDay, PeriodCount{Start: day, Count: completedPerDay[i]})
+		stats.OverduePerDay = append(stats.OverduePerDay, PeriodCount{Start: day, Count: overduePerDay[i]})
+
+		// Weeks start on a Monday
+		weekday := (int(day.Weekday()) + 6) % 7
+		week := day.AddDate(0, 0, -weekday)
+		if n := len(stats.CompletedPerWeek); n == 0 || !stats.CompletedPerWeek[n-1].Start.Equal(week) {
+			stats.CompletedPerWeek = append(stats.CompletedPerWeek, PeriodCount{Start: week})
+		}
+		stats.CompletedPerWeek[len(stats.CompletedPerWeek)-1].Count += completedPerDay[i]
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].Created.Equal(open[j].Created) {
+			return open[i].Id < open[j].Id
+		}
+		return open[i].Created.Before(open[j].Created)
+	})
+	if len(open) > oldest {
+		open = open[:oldest]
+	}
+	stats.OldestOpen = open
+
+	return stats
+}
+
+// Todos saved before creation times were tracked fall back to their last update.
+func (t *Todo) createdOrUpdated() time.Time {
+	if t.Created.IsZero() {
+		return t.Updated
+	}
+	return t.Created
+}
+
+func dayStarts(since, until time.Time) []time.Time {
+	var days []time.Time
+	day := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, since.Location())
+	for day.Before(until) {
+		days = append(days, day)
+		day = day.AddDate(0, 0, 1)
+	}
+	return days
+}
+
+func dayIndex(days []time.Time, at time.Time) int {
+	return sort.Search(len(days), func(i int) bool { return days[i].After(at) }) - 1
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 9, 0, 0, 0, time.UTC) }
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	due := day(5)
+	todos := map[string]Todo{
+		"done": {
+			Description:   "Done todo",
+			Status:        Completed,
+			Created:       day(2),
+			StatusChanged: map[Status]time.Time{NotStarted: day(2), Started: day(3), Completed: day(4)},
+		},
+		"late": {
+			Description:   "Late todo",
+			Status:        Started,
+			Due:           &due,
+			Created:       day(1),
+			StatusChanged: map[Status]time.Time{NotStarted: day(1), Started: day(6)},
+		},
+		"new": {
+			Description:   "New todo",
+			Status:        NotStarted,
+			Created:       day(10),
+			StatusChanged: map[Status]time.Time{NotStarted: day(10)},
+		},
+	}
+
+	stats := ComputeStats(todos, since, until, day(14), 2)
+
+	t.Run("Counts todos per status", func(t *testing.T) {
+		if stats.Total != 3 || stats.ByStatus[Completed] != 1 || stats.ByStatus[Started] != 1 {
+			t.Errorf("got %d todos by status %v", stats.Total, stats.ByStatus)
+		}
+	})
+
+	t.Run("Buckets completions by day and week", func(t *testing.T) {
+		if len(stats.CompletedPerDay) != 14 {
+			t.Fatalf("got %d days want 14", len(stats.CompletedPerDay))
+		}
+		if stats.CompletedPerDay[3].Count != 1 {
+			t.Errorf("expected a completion on the 4th, got %v", stats.CompletedPerDay[3])
+		}
+		// 1st October 2026 is a Thursday, so the first week starts on 28th September
+		if want := time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC); !stats.CompletedPerWeek[0].Start.Equal(want) {
+			t.Errorf("got first week %v want %v", stats.CompletedPerWeek[0].Start, want)
+		}
+		if stats.CompletedPerWeek[0].Count != 1 {
+			t.Errorf("got %d completions in the first week want 1", stats.CompletedPerWeek[0].Count)
+		}
+	})
+
+	t.Run("Averages lead and cycle times", func(t *testing.T) {
+		if stats.AverageLeadTimeHours != 48 {
+			t.Errorf("got lead time %v hours want 48", stats.AverageLeadTimeHours)
+		}
+		if stats.AverageCycleTimeHours != 24 {
+			t.Errorf("got cycle time %v hours want 24", stats.AverageCycleTimeHours)
+		}
+	})
+
+	t.Run("Counts overdue todos each day", func(t *testing.T) {
+		if got := stats.OverduePerDay[4].Count; got != 0 {
+			t.Errorf("got %d overdue on the due date want 0", got)
+		}
+		if got := stats.OverduePerDay[5].Count; got != 1 {
+			t.Errorf("got %d overdue the day after the due date want 1", got)
+		}
+	})
+
+	t.Run("Lists the oldest open todos", func(t *testing.T) {
+		if len(stats.OldestOpen) != 2 || stats.OldestOpen[0].Id != "late" || stats.OldestOpen[0].AgeDays != 13 {
+			t.Errorf("got oldest open todos %v", stats.OldestOpen)
+		}
+	})
+}
+
+func TestParseStatsRange(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Defaults to the last 30 days", func(t *testing.T) {
+		since, until, err := ParseStatsRange("", "", now, time.UTC)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !until.Equal(now) || !since.Equal(time.Date(2026, 9, 19, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("got range %v to %v", since, until)
+		}
+	})
+
+	t.Run("Until dates include the whole day", func(t *testing.T) {
+		_, until, _ := ParseStatsRange("2026-10-01", "2026-10-10", now, time.UTC)
+		if !until.Equal(time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("got until %v", until)
+		}
+	})
+
+	t.Run("Rejects backwards ranges", func(t *testing.T) {
+		if _, _, err := ParseStatsRange("2026-10-10", "2026-10-01", now, time.UTC); err == nil {
+			t.Errorf("expected an error for since after until")
+		}
+	})
+}
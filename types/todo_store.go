@@ -9,4 +9,18 @@ type TodoStore interface {
 	GetTodosByStatus(ctx context.Context, status Status) map[string]Todo
 	GetOverdueTodos(ctx context.Context) map[string]Todo
 	GetAllTodos(ctx context.Context) map[string]Todo
+	// QueryTodos returns one sorted page of the todos matching the query
+	QueryTodos(ctx context.Context, q TodoQuery) (TodoPage, error)
+	// ApplyBulk applies every op or, returning ErrBulkFailed, none of them
+	ApplyBulk(ctx context.Context, ops []BulkOp) ([]BulkResult, error)
+}
+
+// AllTodos returns every todo in store, including the completed ones that
+// GetAllTodos leaves out.
+func AllTodos(ctx context.Context, store TodoStore) map[string]Todo {
+	todos := store.GetAllTodos(ctx)
+	for id, t := range store.GetTodosByStatus(ctx, Completed) {
+		todos[id] = t
+	}
+	return todos
 }
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxDescriptionLength = 500
+	MaxListLength        = 64
+	MaxTags              = 20
+	MaxTagLength         = 32
+	MaxPriority          = 5
+	// MaxDueYears is how far ahead a todo can be due.
+	MaxDueYears = 100
+)
+
+// MinDue is the earliest a todo can be due, which catches dates with the
+// year missing or mistyped.
+var MinDue = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// validator collects the invalid fields of a request.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.fields = append(v.fields, Invalid(field, format, args...).Fields...)
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+func (v *validator) description(desc string) {
+	switch {
+	case strings.TrimSpace(desc) == "":
+		v.add("description", "is required")
+	case utf8.RuneCountInString(desc) > MaxDescriptionLength:
+		v.add("description", "must be at most %d characters", MaxDescriptionLength)
+	}
+}
+
+func (v *validator) status(status Status) {
+	if _, err := ParseStatus(string(status)); err != nil {
+		v.add("status", "%v, expected one of %s", err, joinStatuses())
+	}
+}
+
+func (v *validator) due(due *time.Time, now time.Time) {
+	switch {
+	case due == nil:
+	case due.Before(MinDue):
+		v.add("due", "must be after %s", MinDue.Format(time.DateOnly))
+	case due.After(now.AddDate(MaxDueYears, 0, 0)):
+		v.add("due", "must be within %d years", MaxDueYears)
+	}
+}
+
+func (v *validator) list(list string) {
+	if utf8.RuneCountInString(list) > MaxListLength {
+		v.add("list", "must be at most %d characters", MaxListLength)
+	}
+}
+
+func (v *validator) tags(tags []string) {
+	if len(tags) > MaxTags {
+		v.add("tags", "can have at most %d tags", MaxTags)
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+			v.add("tags", "%q must be 1 to %d characters", tag, MaxTagLength)
+		}
+	}
+}
+
+func (v *validator) priority(priority int) {
+	if priority < 0 || priority > MaxPriority {
+		v.add("priority", "must be between 1 and %d, or 0 for none", MaxPriority)
+	}
+}
+
+func joinStatuses() string {
+	names := make([]string, len(Statuses))
+	for i, s := range Statuses {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Validate checks the fields of the todo that clients can set, returning a
+// *ValidationError listing every one that's invalid. A todo without a
+// status is valid, it's created not started.
+func (t Todo) Validate() error {
+	var v validator
+	t.validate(&v, time.Now())
+	return v.err()
+}
+
+func (t Todo) validate(v *validator, now time.Time) {
+	v.description(t.Description)
+	if t.Status != "" {
+		v.status(t.Status)
+	}
+	v.due(t.Due, now)
+	v.list(t.List)
+	v.tags(t.Tags)
+	v.priority(t.Priority)
+}
+
+// ValidateNew is Validate for a todo sent to be created, which also can't
+// set the fields the server manages.
+func (t Todo) ValidateNew() error {
+	var v validator
+	t.validate(&v, time.Now())
+	if !t.Created.IsZero() {
+		v.add("created", "is set by the server")
+	}
+	if !t.Updated.IsZero() {
+		v.add("updated", "is set by the server")
+	}
+	if t.StatusChanged != nil {
+		v.add("status_changed", "is set by the server")
+	}
+	if t.Version != 0 {
+		v.add("version", "is set by the server")
+	}
+	if t.Owner != "" {
+		v.add("owner", "is set by the server")
+	}
+	if t.Members != nil {
+		v.add("members", "is changed by sharing the todo once it's created")
+	}
+	return v.err()
+}
+
+// Writable is the todo with only the fields clients can set, the rest left
+// for the server.
+func (t Todo) Writable() Todo {
+	return Todo{Description: t.Description, Status: t.Status, Due: t.Due, List: t.List, Tags: t.Tags, Priority: t.Priority, Assignee: t.Assignee}
+}
+
+// Init fills in the fields the server manages for a todo being created, as
+// NewTodo would have.
+func (t *Todo) Init(now time.Time) {
+	if status, err := ParseStatus(string(t.Status)); err == nil {
+		t.Status = status
+	} else {
+		t.Status = NotStarted
+	}
+	t.Created = now
+	t.Updated = now
+	t.StatusChanged = map[Status]time.Time{t.Status: now}
+	t.Version = 1
+}
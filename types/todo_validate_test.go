@@ -0,0 +1,75 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTodo(t *testing.T) {
+	past := time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	farFuture := time.Now().AddDate(MaxDueYears+1, 0, 0)
+
+	cases := []struct {
+		name   string
+		todo   Todo
+		fields []string
+	}{
+		{"A todo from NewTodo is valid", NewTodo("Water the plants", nil), nil},
+		{"A todo without a status is valid", Todo{Description: "Water the plants"}, nil},
+		{"Statuses can be slugs", Todo{Description: "Water the plants", Status: "not-started"}, nil},
+		{"The description is required", Todo{Description: "  "}, []string{"description"}},
+		{"The description has a maximum length", Todo{Description: strings.Repeat("a", MaxDescriptionLength+1)}, []string{"description"}},
+		{"The status must be known", Todo{Description: "a", Status: "Sleeping"}, []string{"status"}},
+		{"Due dates must be sensible", Todo{Description: "a", Due: &past}, []string{"due"}},
+		{"Due dates can't be too far ahead", Todo{Description: "a", Due: &farFuture}, []string{"due"}},
+		{"Lists have a maximum length", Todo{Description: "a", List: strings.Repeat("l", MaxListLength+1)}, []string{"list"}},
+		{"Tags can't be empty", Todo{Description: "a", Tags: []string{"ops", ""}}, []string{"tags"}},
+		{"Every invalid field is listed", Todo{Status: "Sleeping", Tags: make([]string, MaxTags+1)}, []string{"description", "status", "tags"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assertInvalidFields(t, c.todo.Validate(), c.fields)
+		})
+	}
+
+	t.Run("New todos can't set the fields the server manages", func(t *testing.T) {
+		assertInvalidFields(t, NewTodo("Water the plants", nil).ValidateNew(), []string{"created", "updated", "status_changed", "version"})
+		assertInvalidFields(t, NewTodo("Water the plants", nil).Writable().ValidateNew(), nil)
+	})
+
+	t.Run("Init fills in the fields the server manages", func(t *testing.T) {
+		now := time.Now()
+		todo := Todo{Description: "Water the plants", Status: "started"}
+		todo.Init(now)
+		if todo.Status != Started || !todo.Created.Equal(now) || !todo.Updated.Equal(now) || todo.Version != 1 || !todo.StatusChanged[Started].Equal(now) {
+			t.Errorf("got %+v want a started todo created now", todo)
+		}
+	})
+}
+
+func assertInvalidFields(t testing.TB, err error, fields []string) {
+	t.Helper()
+	if fields == nil {
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		return
+	}
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("got %v want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range validation.Fields {
+		if len(got) == 0 || got[len(got)-1] != f.Field {
+			got = append(got, f.Field)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(fields, ",") {
+		t.Errorf("got invalid fields %v want %v", got, fields)
+	}
+}
@@ -0,0 +1,316 @@
+package users
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/types"
+)
+
+// Store keeps users and the hashes of their tokens, optionally persisting
+// them to a file. The server's admin commands change the file while the
+// server is running, so it's read again whenever it has changed.
+type Store struct {
+	lock sync.Mutex
+	path string
+	// modified is the file's modification time when it was last read or
+	// written
+	modified time.Time
+	users    map[string]User
+	// tokens by the hash of the token
+	tokens map[string]storedToken
+	// passwords are the hashes of users' passwords, by user id
+	passwords map[string]string
+}
+
+type storedToken struct {
+	Token
+	Hash string `json:"hash"`
+}
+
+type state struct {
+	Users     []User            `json:"users"`
+	Tokens    []storedToken     `json:"tokens"`
+	Passwords map[string]string `json:"passwords,omitempty"`
+}
+
+// NewStore loads users from path, which is created on the first change. An
+// empty path keeps them in memory.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, users: map[string]User{}, tokens: map[string]storedToken{}, passwords: map[string]string{}}
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddUser creates a user with a unique name.
+func (s *Store) AddUser(name string, admin bool) (User, error) {
+	if err := validateName("name", name, true); err != nil {
+		return User{}, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return User{}, err
+	}
+	if _, err := s.userNamedLocked(name); err == nil {
+		return User{}, types.Conflictf("user %q already exists", name)
+	}
+
+	u := User{Id: uuid.NewString(), Name: name, Admin: admin, Created: time.Now()}
+	s.users[u.Id] = u
+	return u, s.saveLocked()
+}
+
+// Users returns every user in name order.
+func (s *Store) Users() ([]User, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return s.sortedUsersLocked(), nil
+}
+
+// User finds a user by id.
+func (s *Store) User(id string) (User, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return User{}, err
+	}
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUnknownUser
+	}
+	return u, nil
+}
+
+// UserNamed finds a user by name.
+func (s *Store) UserNamed(name string) (User, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return User{}, err
+	}
+	return s.userNamedLocked(name)
+}
+
+// CreateToken creates a token for the user, returning it along with the
+// token itself, which can't be found out again.
+func (s *Store) CreateToken(userId, name string) (Token, string, error) {
+	if err := validateName("name", name, false); err != nil {
+		return Token{}, "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return Token{}, "", err
+	}
+	if _, ok := s.users[userId]; !ok {
+		return Token{}, "", ErrUnknownUser
+	}
+
+	secret := newSecret()
+	hash := hashToken(secret)
+	t := Token{Id: uuid.NewString(), UserId: userId, Name: name, Created: time.Now()}
+	s.tokens[hash] = storedToken{Token: t, Hash: hash}
+	if err := s.saveLocked(); err != nil {
+		return Token{}, "", err
+	}
+	return t, secret, nil
+}
+
+// Tokens returns the user's tokens, oldest first, or everyone's if userId
+// is empty.
+func (s *Store) Tokens(userId string) ([]Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+
+	tokens := []Token{}
+	for _, t := range s.tokens {
+		if userId == "" || t.UserId == userId {
+			tokens = append(tokens, t.Token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b Token) int {
+		return cmp.Or(a.Created.Compare(b.Created), cmp.Compare(a.Id, b.Id))
+	})
+	return tokens, nil
+}
+
+// RevokeToken deletes the token with the id, if it's the user's or userId
+// is empty.
+func (s *Store) RevokeToken(userId, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return err
+	}
+
+	for hash, t := range s.tokens {
+		if t.Id == id && (userId == "" || t.UserId == userId) {
+			delete(s.tokens, hash)
+			return s.saveLocked()
+		}
+	}
+	return ErrUnknownToken
+}
+
+// Authenticate returns the user the token belongs to.
+func (s *Store) Authenticate(secret string) (User, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return User{}, err
+	}
+
+	t, ok := s.tokens[hashToken(secret)]
+	if !ok {
+		return User{}, ErrInvalidToken
+	}
+	u, ok := s.users[t.UserId]
+	if !ok {
+		return User{}, ErrInvalidToken
+	}
+	return u, nil
+}
+
+// SetPassword sets the password the user signs in to the web pages with.
+func (s *Store) SetPassword(userId, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hash := hashPassword(password)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return err
+	}
+	if _, ok := s.users[userId]; !ok {
+		return ErrUnknownUser
+	}
+	s.passwords[userId] = hash
+	return s.saveLocked()
+}
+
+// Login returns the user with the name, if the password is theirs.
+func (s *Store) Login(name, password string) (User, error) {
+	s.lock.Lock()
+	if err := s.reloadLocked(); err != nil {
+		s.lock.Unlock()
+		return User{}, err
+	}
+	u, err := s.userNamedLocked(name)
+	hash := s.passwords[u.Id]
+	s.lock.Unlock()
+
+	// Hashing outside the lock, as it's slow on purpose, and even when
+	// there's nothing to check against so the time taken doesn't give away
+	// who has a password
+	if err != nil || hash == "" {
+		hashPassword(password)
+		return User{}, ErrInvalidLogin
+	}
+	if !checkPassword(hash, password) {
+		return User{}, ErrInvalidLogin
+	}
+	return u, nil
+}
+
+func (s *Store) userNamedLocked(name string) (User, error) {
+	for _, u := range s.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return User{}, ErrUnknownUser
+}
+
+func (s *Store) sortedUsersLocked() []User {
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b User) int { return cmp.Compare(a.Name, b.Name) })
+	return users
+}
+
+// reloadLocked reads the file again if it has changed since it was last
+// read or written.
+func (s *Store) reloadLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("problem reading users, %v", err)
+	}
+	if info.ModTime().Equal(s.modified) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("problem reading users, %v", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("problem parsing users, %v", err)
+	}
+	s.users = map[string]User{}
+	for _, u := range st.Users {
+		s.users[u.Id] = u
+	}
+	s.tokens = map[string]storedToken{}
+	for _, t := range st.Tokens {
+		s.tokens[t.Hash] = t
+	}
+	s.passwords = st.Passwords
+	if s.passwords == nil {
+		s.passwords = map[string]string{}
+	}
+	s.modified = info.ModTime()
+	return nil
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	st := state{Users: s.sortedUsersLocked(), Tokens: []storedToken{}, Passwords: s.passwords}
+	for _, t := range s.tokens {
+		st.Tokens = append(st.Tokens, t)
+	}
+	slices.SortFunc(st.Tokens, func(a, b storedToken) int { return cmp.Compare(a.Id, b.Id) })
+	if err := atomicfile.WriteJSON(s.path, st); err != nil {
+		return fmt.Errorf("problem saving users, %v", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("problem saving users, %v", err)
+	}
+	s.modified = info.ModTime()
+	return nil
+}
@@ -0,0 +1,124 @@
+package users
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alice, err := store.AddUser("alice", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, secret, err := store.CreateToken(alice.Id, "laptop")
+	if err != nil || !strings.HasPrefix(secret, TokenPrefix) {
+		t.Fatalf("got token %q and error %v", secret, err)
+	}
+
+	t.Run("A token authenticates its user", func(t *testing.T) {
+		got, err := store.Authenticate(secret)
+		if err != nil || got.Id != alice.Id {
+			t.Errorf("got %+v and error %v want %+v", got, err, alice)
+		}
+		if _, err := store.Authenticate(secret + "x"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("got %v want %v", err, ErrInvalidToken)
+		}
+	})
+
+	t.Run("Names are unique", func(t *testing.T) {
+		if _, err := store.AddUser("alice", false); !errors.Is(err, types.ErrConflict) {
+			t.Errorf("got %v want a conflict", err)
+		}
+		if _, err := store.AddUser("al ice", false); !errors.Is(err, types.ErrInvalid) {
+			t.Errorf("got %v want it to be invalid", err)
+		}
+	})
+
+	t.Run("Only the hash of a token is saved", func(t *testing.T) {
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), secret) || !strings.Contains(string(data), hashToken(secret)) {
+			t.Errorf("got file %s want the token's hash and not the token", data)
+		}
+	})
+
+	t.Run("Changes made by another process are seen", func(t *testing.T) {
+		admin, err := NewStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		bob, _ := admin.AddUser("bob", false)
+		_, bobSecret, _ := admin.CreateToken(bob.Id, "")
+		if got, err := store.Authenticate(bobSecret); err != nil || got.Name != "bob" {
+			t.Errorf("got %+v and error %v want bob", got, err)
+		}
+	})
+
+	t.Run("Users can only revoke their own tokens", func(t *testing.T) {
+		bob, _ := store.UserNamed("bob")
+		if err := store.RevokeToken(bob.Id, token.Id); !errors.Is(err, ErrUnknownToken) {
+			t.Errorf("got %v want %v", err, ErrUnknownToken)
+		}
+		if err := store.RevokeToken(alice.Id, token.Id); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := store.Authenticate(secret); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("got %v want a revoked token to be invalid", err)
+		}
+		tokens, _ := store.Tokens("")
+		if len(tokens) != 1 || tokens[0].UserId != bob.Id {
+			t.Errorf("got %+v want only bob's token", tokens)
+		}
+	})
+}
+
+func TestPasswords(t *testing.T) {
+	passwordIterations = 1000
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, _ := NewStore(path)
+	alice, _ := store.AddUser("alice", false)
+	store.AddUser("bob", false)
+
+	if err := store.SetPassword(alice.Id, "short"); !errors.Is(err, types.ErrInvalid) {
+		t.Errorf("got %v want a short password to be invalid", err)
+	}
+	if err := store.SetPassword(alice.Id, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("The right password logs in", func(t *testing.T) {
+		got, err := store.Login("alice", "correct horse")
+		if err != nil || got.Id != alice.Id {
+			t.Errorf("got %+v and error %v want %+v", got, err, alice)
+		}
+	})
+
+	t.Run("Anything else is the same error", func(t *testing.T) {
+		for _, login := range [][2]string{{"alice", "battery staple"}, {"bob", "correct horse"}, {"carol", "correct horse"}} {
+			if _, err := store.Login(login[0], login[1]); !errors.Is(err, ErrInvalidLogin) {
+				t.Errorf("got %v for %v want %v", err, login, ErrInvalidLogin)
+			}
+		}
+	})
+
+	t.Run("Only the hash of a password is saved", func(t *testing.T) {
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), "correct horse") || !strings.Contains(string(data), "pbkdf2-sha256$") {
+			t.Errorf("got file %s want the password's hash and not the password", data)
+		}
+		reopened, _ := NewStore(path)
+		if _, err := reopened.Login("alice", "correct horse"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
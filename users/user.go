@@ -0,0 +1,129 @@
+package users
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+// MaxNameLength applies to user and token names.
+const MaxNameLength = 64
+
+// MinPasswordLength and MaxPasswordLength apply to the passwords users sign
+// in to the web pages with.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 256
+)
+
+// TokenPrefix starts every token, so they're easy to recognise if one is
+// pasted somewhere it shouldn't be.
+const TokenPrefix = "todo_"
+
+var (
+	ErrUnknownUser  = types.NotFoundf("unknown user")
+	ErrUnknownToken = types.NotFoundf("unknown token")
+	// ErrInvalidToken is returned for a token that was never created or has
+	// been revoked
+	ErrInvalidToken = errors.New("invalid or revoked token")
+	// ErrInvalidLogin is returned for an unknown name, a wrong password and a
+	// user without a password alike, so as not to say which names exist
+	ErrInvalidLogin = errors.New("wrong name or password")
+)
+
+// passwordIterations is how many rounds of PBKDF2 a password is hashed
+// with. Unlike tokens, passwords are chosen by people, so the hash has to
+// be slow. Tests lower it.
+var passwordIterations = 600_000
+
+// User is someone who can use the API. They can only see their own todos,
+// and those shared with them. Admins can also manage webhooks, which see
+// everyone's.
+type User struct {
+	Id      string    `json:"id"`
+	Name    string    `json:"name"`
+	Admin   bool      `json:"admin,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Token is an API token as its user sees it. The token itself is only shown
+// when it's created, and only its hash is kept.
+type Token struct {
+	Id      string    `json:"id"`
+	UserId  string    `json:"user_id"`
+	Name    string    `json:"name,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func validateName(field, name string, required bool) error {
+	if (required && name == "") || len(name) > MaxNameLength {
+		return types.Invalid(field, "must be 1 to %d characters", MaxNameLength)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return types.Invalid(field, "%q may only contain letters, digits, -, _ and .", name)
+		}
+	}
+	return nil
+}
+
+// newSecret returns a token with 256 random bits.
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is what's stored for a token. The tokens are random rather than
+// chosen by people, so a fast hash is enough to make a leaked file useless.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return types.Invalid("password", "must be %d to %d characters", MinPasswordLength, MaxPasswordLength)
+	}
+	return nil
+}
+
+// hashPassword is what's stored for a password, with a random salt and the
+// number of iterations, so they can be raised without breaking old hashes.
+func hashPassword(password string) string {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, _ := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func checkPassword(hash, password string) bool {
+	var iterations int
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	if _, err := fmt.Sscan(parts[1], &iterations); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(got, want) == 1
+}
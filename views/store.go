@@ -0,0 +1,134 @@
+package views
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"grantjames.github.io/todo-app/internal/atomicfile"
+)
+
+// Store keeps each owner's saved views by name, optionally persisting them
+// to a file. Owners are user ids, or empty where there are no users.
+type Store struct {
+	lock  sync.Mutex
+	path  string
+	views map[viewKey]View
+}
+
+type viewKey struct {
+	owner, name string
+}
+
+type state struct {
+	Views []View `json:"views"`
+}
+
+// NewStore loads views from path, which is created on the first change. An
+// empty path keeps them in memory.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, views: map[viewKey]View{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem reading views, %v", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("problem parsing views, %v", err)
+	}
+	for _, v := range st.Views {
+		s.views[viewKey{v.Owner, v.Name}] = v
+	}
+	return s, nil
+}
+
+// Views returns owner's views in name order.
+func (s *Store) Views(owner string) []View {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	views := []View{}
+	for _, v := range s.sortedLocked() {
+		if v.Owner == owner {
+			views = append(views, v)
+		}
+	}
+	return views
+}
+
+func (s *Store) View(owner, name string) (View, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	v, ok := s.views[viewKey{owner, name}]
+	if !ok {
+		return View{}, ErrUnknownView
+	}
+	return v, nil
+}
+
+// Put saves v as one of owner's views, replacing any of theirs with the
+// same name. It reports whether the view is new.
+func (s *Store) Put(owner string, v View) (View, bool, error) {
+	if err := v.Validate(); err != nil {
+		return View{}, false, err
+	}
+	v.Owner = owner
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	old, exists := s.views[viewKey{owner, v.Name}]
+	v.Created, v.Updated = now, now
+	if exists {
+		v.Created = old.Created
+	}
+	s.views[viewKey{owner, v.Name}] = v
+	if err := s.saveLocked(); err != nil {
+		return View{}, false, err
+	}
+	return v, !exists, nil
+}
+
+func (s *Store) Delete(owner, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := viewKey{owner, name}
+	if _, ok := s.views[key]; !ok {
+		return ErrUnknownView
+	}
+	delete(s.views, key)
+	return s.saveLocked()
+}
+
+func (s *Store) sortedLocked() []View {
+	views := make([]View, 0, len(s.views))
+	for _, v := range s.views {
+		views = append(views, v)
+	}
+	slices.SortFunc(views, func(a, b View) int {
+		return cmp.Or(strings.Compare(a.Owner, b.Owner), strings.Compare(a.Name, b.Name))
+	})
+	return views
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := atomicfile.WriteJSON(s.path, state{Views: s.sortedLocked()}); err != nil {
+		return fmt.Errorf("problem saving views, %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package views
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, created, err := store.Put("", View{Name: "overdue-ops", Filter: "overdue:true AND tag:ops"})
+	if err != nil || !created {
+		t.Fatalf("got created %v and error %v want a new view", created, err)
+	}
+	again, created, err := store.Put("", View{Name: "overdue-ops", Filter: "tag:ops", Sort: "due"})
+	if err != nil || created || !again.Created.Equal(first.Created) {
+		t.Errorf("got %+v, created %v and error %v want the view replaced", again, created, err)
+	}
+	store.Put("", View{Name: "started", Filter: "status:started", Columns: []string{"id", "description"}})
+
+	t.Run("Views are kept between restarts", func(t *testing.T) {
+		reloaded, err := NewStore(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := reloaded.Views("")
+		if len(got) != 2 || got[0].Name != "overdue-ops" || got[0].Sort != "due" || got[1].Name != "started" {
+			t.Errorf("got %+v want both views in name order", got)
+		}
+	})
+
+	t.Run("Deleting an unknown view is an error", func(t *testing.T) {
+		if err := store.Delete("", "nope"); !errors.Is(err, ErrUnknownView) {
+			t.Errorf("got %v want %v", err, ErrUnknownView)
+		}
+		if err := store.Delete("", "started"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := store.View("", "started"); !errors.Is(err, ErrUnknownView) {
+			t.Errorf("got %v want %v", err, ErrUnknownView)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package views
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"grantjames.github.io/todo-app/filter"
+	"grantjames.github.io/todo-app/types"
+)
+
+// MaxNameLength keeps view names short enough for menus and URLs.
+const MaxNameLength = 64
+
+// Columns are the todo fields a view can show, in the CLI's table.
+var Columns = []string{"id", "status", "description", "due", "tags", "list", "priority", "created", "updated"}
+
+// DefaultColumns are shown by views that don't choose their own.
+var DefaultColumns = []string{"id", "status", "due", "description"}
+
+var ErrUnknownView = types.NotFoundf("unknown view")
+
+// View is a saved query: a filter expression and sort order, along with the
+// columns to show the results in.
+type View struct {
+	// Owner is the id of the user the view belongs to, set by the store
+	Owner   string    `json:"owner,omitempty"`
+	Name    string    `json:"name"`
+	Filter  string    `json:"filter,omitempty"`
+	Sort    string    `json:"sort,omitempty"`
+	Columns []string  `json:"columns,omitempty"`
+	Created time.Time `json:"created,omitzero"`
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// Validate checks the name is URL safe and that the filter, sort and
+// columns all parse.
+func (v View) Validate() error {
+	if v.Name == "" || len(v.Name) > MaxNameLength {
+		return types.Invalid("name", "must be 1 to %d characters", MaxNameLength)
+	}
+	for _, r := range v.Name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return types.Invalid("name", "%q may only contain letters, digits, - and _", v.Name)
+		}
+	}
+	if _, err := v.Query(); err != nil {
+		return err
+	}
+	for _, c := range v.Columns {
+		if !slices.Contains(Columns, c) {
+			return types.Invalid("columns", "unknown column %q, expected one of %s", c, strings.Join(Columns, ", "))
+		}
+	}
+	return nil
+}
+
+// Query is the todo query the view runs. A view without a filter lists
+// every todo that isn't completed, like the list endpoint.
+func (v View) Query() (types.TodoQuery, error) {
+	var q types.TodoQuery
+	if v.Filter != "" {
+		expr, err := filter.Parse(v.Filter)
+		if syntaxErr, ok := err.(*filter.SyntaxError); ok {
+			return q, types.Invalid("filter", "%v\n%s", err, syntaxErr.Caret(v.Filter))
+		}
+		if err != nil {
+			return q, types.Invalid("filter", "%v", err)
+		}
+		q.Filter = expr
+	}
+
+	var err error
+	q.Sort, err = types.ParseSort(v.Sort)
+	return q, err
+}
+
+// ShownColumns is the view's columns, or DefaultColumns if it has none.
+func (v View) ShownColumns() []string {
+	if len(v.Columns) == 0 {
+		return DefaultColumns
+	}
+	return v.Columns
+}
+
+// Run evaluates the view over todos, for clients without a server to ask.
+func (v View) Run(todos map[string]types.Todo) ([]types.TodoItem, error) {
+	q, err := v.Query()
+	if err != nil {
+		return nil, err
+	}
+	page, err := types.QueryTodos(todos, q)
+	return page.Todos, err
+}
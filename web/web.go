@@ -0,0 +1,21 @@
+// Package web has the templates and static files for the web pages. They're
+// embedded so the server can be started from any directory.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Templates are the pages, each filling in the "content" template of
+// layout.html.
+var Templates, _ = fs.Sub(templates, "templates")
+
+//go:embed static
+var static embed.FS
+
+// Static is served as it is, such as the about page and the stylesheet.
+var Static, _ = fs.Sub(static, "static")
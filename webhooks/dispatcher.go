@@ -0,0 +1,512 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"grantjames.github.io/todo-app/internal/atomicfile"
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+const (
+	// MaxDeliveryLog is how many recent deliveries are kept for inspection.
+	MaxDeliveryLog = 500
+	// MaxConcurrentDeliveries limits the requests in flight at once.
+	MaxConcurrentDeliveries = 8
+
+	DefaultOverdueInterval = time.Minute
+	DefaultTimeout         = 10 * time.Second
+
+	eventsBuffer = 256
+)
+
+var (
+	ErrUnknownSubscription = types.NotFoundf("unknown webhook subscription")
+	ErrUnknownDelivery     = types.NotFoundf("unknown dead-lettered delivery")
+)
+
+// RetryPolicy backs off exponentially from BaseDelay, capped at MaxDelay.
+// A delivery that fails MaxAttempts times is moved to the dead letters.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 6, BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Minute}
+
+// Delay is how long to wait after the given failed attempt, counting from 1.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+type DeliveryStatus string
+
+const (
+	Pending   DeliveryStatus = "pending"
+	Delivered DeliveryStatus = "delivered"
+	Failed    DeliveryStatus = "failed"
+)
+
+type Attempt struct {
+	Time       time.Time     `json:"time"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Delivery is one payload on its way to one subscription, along with every
+// attempt made to send it.
+type Delivery struct {
+	Id             string         `json:"id"`
+	SubscriptionId string         `json:"subscription_id"`
+	URL            string         `json:"url"`
+	Status         DeliveryStatus `json:"status"`
+	Payload        Payload        `json:"payload"`
+	Attempts       []Attempt      `json:"attempts"`
+	NextAttempt    time.Time      `json:"next_attempt,omitzero"`
+}
+
+func (d *Delivery) clone() Delivery {
+	c := *d
+	c.Attempts = slices.Clone(d.Attempts)
+	return c
+}
+
+// state is what the dispatcher persists between restarts.
+type state struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	DeadLetters   []Delivery     `json:"dead_letters"`
+	OverdueSent   []string       `json:"overdue_sent"`
+}
+
+// Dispatcher turns the actor's change events into webhook deliveries. It
+// POSTs signed JSON payloads to every interested subscription, retrying
+// failures in the background.
+type Dispatcher struct {
+	Retry           RetryPolicy
+	OverdueInterval time.Duration
+	Client          *http.Client
+
+	lock        sync.Mutex
+	path        string
+	subs        map[string]Subscription
+	log         []*Delivery
+	deadLetters []*Delivery
+	overdueSent map[string]bool
+
+	ctx     context.Context
+	sem     chan struct{}
+	wait    sync.WaitGroup
+	running chan struct{}
+}
+
+// NewDispatcher loads subscriptions and dead letters from path, which is
+// created on the first change. An empty path keeps everything in memory.
+func NewDispatcher(path string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		Retry:           DefaultRetryPolicy,
+		OverdueInterval: DefaultOverdueInterval,
+		Client:          &http.Client{Timeout: DefaultTimeout},
+		path:            path,
+		subs:            map[string]Subscription{},
+		overdueSent:     map[string]bool{},
+		ctx:             context.Background(),
+		sem:             make(chan struct{}, MaxConcurrentDeliveries),
+		running:         make(chan struct{}),
+	}
+	if path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem reading webhooks file %s, %v", path, err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("problem parsing webhooks file %s, %v", path, err)
+	}
+	for _, sub := range s.Subscriptions {
+		d.subs[sub.Id] = sub
+	}
+	for _, dl := range s.DeadLetters {
+		d.deadLetters = append(d.deadLetters, &dl)
+	}
+	for _, id := range s.OverdueSent {
+		d.overdueSent[id] = true
+	}
+	return d, nil
+}
+
+// Run feeds the actor's events to subscribers and checks for newly overdue
+// todos every OverdueInterval, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, actor *stores.TodoStoreActor) {
+	d.lock.Lock()
+	d.ctx = ctx
+	d.lock.Unlock()
+
+	broker := actor.Events()
+	lastId := broker.LastId()
+	sub, _, _ := broker.Subscribe(lastId, eventsBuffer)
+	close(d.running)
+
+	ticker := time.NewTicker(d.OverdueInterval)
+	defer ticker.Stop()
+	d.sweepOverdue(ctx, actor)
+
+	for {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+			return
+		case <-ticker.C:
+			d.sweepOverdue(ctx, actor)
+		case e, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind, pick up from the replay buffer
+				var missed []types.TodoEvent
+				var complete bool
+				sub, missed, complete = broker.Subscribe(lastId, eventsBuffer)
+				if !complete {
+					slog.WarnContext(ctx, "Webhook dispatcher missed events", slog.Uint64("last_id", lastId))
+				}
+				for _, e := range missed {
+					d.handleEvent(e)
+					lastId = e.Id
+				}
+				continue
+			}
+			d.handleEvent(e)
+			lastId = e.Id
+		}
+	}
+}
+
+// Wait blocks until deliveries in flight have finished or given up.
+func (d *Dispatcher) Wait() {
+	d.wait.Wait()
+}
+
+func (d *Dispatcher) handleEvent(e types.TodoEvent) {
+	switch {
+	case e.Type == types.EventAdded:
+		d.Notify(TodoCreated, e.TodoId, e.Todo)
+	case e.Type == types.EventUpdated && e.Todo.Status == types.Completed:
+		// Only the update that completed the todo, not later ones
+		if completed, ok := e.Todo.StatusTime(types.Completed); ok && completed.Equal(e.Todo.Updated) {
+			d.Notify(TodoCompleted, e.TodoId, e.Todo)
+		}
+	}
+}
+
+func (d *Dispatcher) sweepOverdue(ctx context.Context, actor *stores.TodoStoreActor) {
+	resp := make(chan types.GetOverDueTodosResponse, 1)
+	actor.Send(types.GetOverDueTodosRequest{Ctx: ctx, Resp: resp})
+
+	var todos map[string]types.Todo
+	select {
+	case res := <-resp:
+		todos = res.Todos
+	case <-ctx.Done():
+		return
+	}
+
+	d.lock.Lock()
+	var newlyOverdue []string
+	for id := range todos {
+		if !d.overdueSent[id] {
+			d.overdueSent[id] = true
+			newlyOverdue = append(newlyOverdue, id)
+		}
+	}
+	changed := len(newlyOverdue) > 0
+	for id := range d.overdueSent {
+		if _, ok := todos[id]; !ok {
+			// No longer overdue, so it can be reported again if it slips
+			delete(d.overdueSent, id)
+			changed = true
+		}
+	}
+	if changed {
+		d.saveLocked()
+	}
+	d.lock.Unlock()
+
+	for _, id := range newlyOverdue {
+		d.Notify(TodoOverdue, id, todos[id])
+	}
+}
+
+// Notify queues a delivery of the event to every subscription that wants it.
+func (d *Dispatcher) Notify(event EventType, todoId string, todo types.Todo) {
+	d.queue(Payload{Event: event, TodoId: todoId, Todo: todo})
+}
+
+// Remind sends a todo.reminder event, describing which reminder it is.
+func (d *Dispatcher) Remind(todoId string, todo types.Todo, reminder string) {
+	d.queue(Payload{Event: TodoReminder, TodoId: todoId, Todo: todo, Reminder: reminder})
+}
+
+func (d *Dispatcher) queue(payload Payload) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	payload.Time = time.Now()
+	for _, sub := range d.subs {
+		if !sub.Wants(payload.Event) {
+			continue
+		}
+		delivery := &Delivery{
+			Id:             uuid.NewString(),
+			SubscriptionId: sub.Id,
+			URL:            sub.URL,
+			Status:         Pending,
+			Payload:        payload,
+			NextAttempt:    payload.Time,
+		}
+		delivery.Payload.DeliveryId = delivery.Id
+		d.appendLogLocked(delivery)
+		d.startLocked(delivery, sub.Secret)
+	}
+}
+
+func (d *Dispatcher) appendLogLocked(delivery *Delivery) {
+	d.log = append(d.log, delivery)
+	if len(d.log) > MaxDeliveryLog {
+		d.log = d.log[len(d.log)-MaxDeliveryLog:]
+	}
+}
+
+func (d *Dispatcher) startLocked(delivery *Delivery, secret string) {
+	ctx := d.ctx
+	d.wait.Add(1)
+	go func() {
+		defer d.wait.Done()
+		d.deliver(ctx, delivery, secret)
+	}()
+}
+
+// deliver makes attempts until one succeeds or the retry policy gives up.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery, secret string) {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode webhook payload", slog.String("delivery_id", delivery.Id), slog.String("error", err.Error()))
+		return
+	}
+
+	// Earlier attempts stay in the log, a redelivery gets the full policy again
+	d.lock.Lock()
+	previous := len(delivery.Attempts)
+	d.lock.Unlock()
+
+	for {
+		d.lock.Lock()
+		wait := time.Until(delivery.NextAttempt)
+		d.lock.Unlock()
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		select {
+		case d.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		attempt := d.attempt(ctx, delivery, secret, body)
+		<-d.sem
+
+		d.lock.Lock()
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		n := len(delivery.Attempts) - previous
+		switch {
+		case attempt.Error == "":
+			delivery.Status = Delivered
+			delivery.NextAttempt = time.Time{}
+			d.lock.Unlock()
+			return
+		case n >= d.Retry.MaxAttempts:
+			delivery.Status = Failed
+			delivery.NextAttempt = time.Time{}
+			d.deadLetters = append(d.deadLetters, delivery)
+			slog.WarnContext(ctx, "Webhook delivery dead-lettered", slog.String("delivery_id", delivery.Id), slog.String("url", delivery.URL), slog.String("error", attempt.Error))
+			d.saveLocked()
+			d.lock.Unlock()
+			return
+		default:
+			delivery.NextAttempt = time.Now().Add(d.Retry.Delay(n))
+			d.lock.Unlock()
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery, secret string, body []byte) Attempt {
+	start := time.Now()
+	attempt := Attempt{Time: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks")
+	req.Header.Set(EventHeader, string(delivery.Payload.Event))
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(secret, start, body))
+
+	resp, err := d.Client.Do(req)
+	attempt.Duration = time.Since(start)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("receiver responded with status code %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// AddSubscription validates and stores a subscription, giving it an Id and
+// generating a secret if it doesn't have one.
+func (d *Dispatcher) AddSubscription(sub Subscription) (Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return Subscription{}, err
+	}
+	sub.Id = uuid.NewString()
+	sub.Created = time.Now()
+	if sub.Secret == "" {
+		secret := make([]byte, 24)
+		rand.Read(secret)
+		sub.Secret = hex.EncodeToString(secret)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.subs[sub.Id] = sub
+	return sub, d.saveLocked()
+}
+
+func (d *Dispatcher) RemoveSubscription(id string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.subs[id]; !ok {
+		return ErrUnknownSubscription
+	}
+	delete(d.subs, id)
+	return d.saveLocked()
+}
+
+func (d *Dispatcher) Subscription(id string) (Subscription, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	sub, ok := d.subs[id]
+	return sub, ok
+}
+
+// Subscriptions returns every subscription, oldest first.
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	subs := slices.Collect(maps.Values(d.subs))
+	slices.SortFunc(subs, func(a, b Subscription) int { return a.Created.Compare(b.Created) })
+	return subs
+}
+
+// Deliveries returns the logged deliveries for a subscription, newest first.
+func (d *Dispatcher) Deliveries(subscriptionId string) []Delivery {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var deliveries []Delivery
+	for _, delivery := range slices.Backward(d.log) {
+		if delivery.SubscriptionId == subscriptionId {
+			deliveries = append(deliveries, delivery.clone())
+		}
+	}
+	return deliveries
+}
+
+// DeadLetters returns the deliveries that ran out of attempts, oldest first.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	deliveries := make([]Delivery, 0, len(d.deadLetters))
+	for _, delivery := range d.deadLetters {
+		deliveries = append(deliveries, delivery.clone())
+	}
+	return deliveries
+}
+
+// Redeliver takes a delivery off the dead letters and tries it again with a
+// fresh set of attempts. The subscription's current URL and secret are used.
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	i := slices.IndexFunc(d.deadLetters, func(dl *Delivery) bool { return dl.Id == id })
+	if i < 0 {
+		return Delivery{}, ErrUnknownDelivery
+	}
+	delivery := d.deadLetters[i]
+	sub, ok := d.subs[delivery.SubscriptionId]
+	if !ok {
+		return Delivery{}, ErrUnknownSubscription
+	}
+
+	d.deadLetters = slices.Delete(d.deadLetters, i, i+1)
+	delivery.URL = sub.URL
+	delivery.Status = Pending
+	delivery.NextAttempt = time.Now()
+	if !slices.Contains(d.log, delivery) {
+		d.appendLogLocked(delivery)
+	}
+	d.startLocked(delivery, sub.Secret)
+	return delivery.clone(), d.saveLocked()
+}
+
+func (d *Dispatcher) saveLocked() error {
+	if d.path == "" {
+		return nil
+	}
+
+	s := state{Subscriptions: slices.Collect(maps.Values(d.subs)), OverdueSent: slices.Sorted(maps.Keys(d.overdueSent))}
+	for _, dl := range d.deadLetters {
+		s.DeadLetters = append(s.DeadLetters, dl.clone())
+	}
+	if err := atomicfile.WriteJSON(d.path, s); err != nil {
+		return fmt.Errorf("problem saving webhooks, %v", err)
+	}
+	return nil
+}
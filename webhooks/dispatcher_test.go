@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"grantjames.github.io/todo-app/stores"
+	"grantjames.github.io/todo-app/types"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Delivers signed payloads for created and completed todos", func(t *testing.T) {
+		receiver := newReceiver(t, "secret", 0)
+		d, actor := startDispatcher(t, "")
+		d.AddSubscription(Subscription{URL: receiver.URL, Secret: "secret", Events: []EventType{TodoCreated, TodoCompleted}})
+
+		id := addTodo(actor, types.NewTodo("Webhook todo", nil))
+		updateStatus(actor, id, types.Started)
+		updateStatus(actor, id, types.Completed)
+
+		payloads := receiver.wait(t, 2)
+		if payloads[0].Event != TodoCreated || payloads[0].TodoId != id {
+			t.Errorf("got %v want the todo being created", payloads[0])
+		}
+		if payloads[1].Event != TodoCompleted || payloads[1].Todo.Status != types.Completed {
+			t.Errorf("got %v want the todo being completed", payloads[1])
+		}
+	})
+
+	t.Run("Sends overdue todos once", func(t *testing.T) {
+		receiver := newReceiver(t, "secret", 0)
+		d, actor := startDispatcher(t, "")
+		d.AddSubscription(Subscription{URL: receiver.URL, Secret: "secret", Events: []EventType{TodoOverdue}})
+
+		due := time.Now().AddDate(0, 0, -2)
+		id := addTodo(actor, types.NewTodo("Late todo", &due))
+
+		payloads := receiver.wait(t, 1)
+		if payloads[0].Event != TodoOverdue || payloads[0].TodoId != id {
+			t.Errorf("got %v want the todo being overdue", payloads[0])
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if n := receiver.count(); n != 1 {
+			t.Errorf("got %d deliveries want 1", n)
+		}
+	})
+
+	t.Run("Retries failures then dead-letters them for redelivery", func(t *testing.T) {
+		receiver := newReceiver(t, "secret", 3)
+		d, actor := startDispatcher(t, filepath.Join(t.TempDir(), "webhooks.json"))
+		sub, _ := d.AddSubscription(Subscription{URL: receiver.URL, Secret: "secret"})
+
+		addTodo(actor, types.NewTodo("Unlucky todo", nil))
+		waitFor(t, func() bool { return len(d.DeadLetters()) == 1 })
+
+		dead := d.DeadLetters()[0]
+		if len(dead.Attempts) != d.Retry.MaxAttempts || dead.Status != Failed {
+			t.Fatalf("got %d attempts and status %q want %d and failed", len(dead.Attempts), dead.Status, d.Retry.MaxAttempts)
+		}
+		if dead.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("got status code %d logged want 503", dead.Attempts[0].StatusCode)
+		}
+
+		if _, err := d.Redeliver(dead.Id); err != nil {
+			t.Fatalf("unexpected error redelivering: %v", err)
+		}
+		receiver.wait(t, 1)
+		waitFor(t, func() bool { return d.Deliveries(sub.Id)[0].Status == Delivered })
+
+		delivered := d.Deliveries(sub.Id)[0]
+		if len(delivered.Attempts) != d.Retry.MaxAttempts+1 {
+			t.Errorf("got %d attempts logged want %d", len(delivered.Attempts), d.Retry.MaxAttempts+1)
+		}
+		if len(d.DeadLetters()) != 0 {
+			t.Errorf("got %d dead letters want 0", len(d.DeadLetters()))
+		}
+	})
+
+	t.Run("Keeps subscriptions and dead letters across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "webhooks.json")
+		d, _ := NewDispatcher(path)
+		sub, err := d.AddSubscription(Subscription{URL: "http://example.com/hook"})
+		if err != nil {
+			t.Fatalf("unexpected error adding subscription: %v", err)
+		}
+
+		d, err = NewDispatcher(path)
+		if err != nil {
+			t.Fatalf("unexpected error loading: %v", err)
+		}
+		loaded, ok := d.Subscription(sub.Id)
+		if !ok || loaded.Secret != sub.Secret || loaded.Secret == "" {
+			t.Errorf("got %v want %v with a generated secret", loaded, sub)
+		}
+	})
+
+	t.Run("Rejects invalid subscriptions", func(t *testing.T) {
+		d, _ := NewDispatcher("")
+		for _, sub := range []Subscription{
+			{URL: "/relative"},
+			{URL: "ftp://example.com"},
+			{URL: "http://example.com", Events: []EventType{"todo.deleted"}},
+		} {
+			if _, err := d.AddSubscription(sub); err == nil {
+				t.Errorf("expected an error adding %v", sub)
+			}
+		}
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("attempt %d: got delay %v want %v", i+1, got, w)
+		}
+	}
+}
+
+func startDispatcher(t *testing.T, path string) (*Dispatcher, *stores.TodoStoreActor) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	actor := stores.NewTodoStoreActor(stores.NewInMemoryTodoStore())
+	go actor.Run(ctx)
+
+	d, err := NewDispatcher(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating dispatcher: %v", err)
+	}
+	d.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	d.OverdueInterval = 10 * time.Millisecond
+	go d.Run(ctx, actor)
+	<-d.running
+
+	t.Cleanup(func() {
+		cancel()
+		d.Wait()
+	})
+	return d, actor
+}
+
+type receiver struct {
+	*httptest.Server
+	lock     sync.Mutex
+	payloads []Payload
+	failures int
+}
+
+// newReceiver verifies signatures and fails the first failures requests.
+func newReceiver(t *testing.T, secret string, failures int) *receiver {
+	rec := &receiver{failures: failures}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify(secret, r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body) {
+			t.Errorf("got a delivery with an invalid signature")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rec.lock.Lock()
+		defer rec.lock.Unlock()
+		if rec.failures > 0 {
+			rec.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p Payload
+		json.Unmarshal(body, &p)
+		rec.payloads = append(rec.payloads, p)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) count() int {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	return len(rec.payloads)
+}
+
+func (rec *receiver) wait(t *testing.T, n int) []Payload {
+	t.Helper()
+	waitFor(t, func() bool { return rec.count() >= n })
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	// Deliveries are sent concurrently, so put them back in event order
+	slices.SortFunc(rec.payloads, func(a, b Payload) int { return a.Time.Compare(b.Time) })
+	return rec.payloads
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func addTodo(actor *stores.TodoStoreActor, todo types.Todo) string {
+	resp := make(chan types.AddTodoResponse)
+	actor.Send(types.AddTodoRequest{Ctx: context.Background(), Todo: todo, Resp: resp})
+	return (<-resp).Id
+}
+
+func updateStatus(actor *stores.TodoStoreActor, id string, status types.Status) {
+	resp := make(chan types.UpdateTodoStatusResponse)
+	actor.Send(types.UpdateTodoStatusRequest{Ctx: context.Background(), Id: id, Status: status, Resp: resp})
+	<-resp
+}
@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"grantjames.github.io/todo-app/types"
+)
+
+type EventType string
+
+const (
+	TodoCreated   EventType = "todo.created"
+	TodoCompleted EventType = "todo.completed"
+	TodoOverdue   EventType = "todo.overdue"
+	// TodoReminder is sent by the reminder scheduler
+	TodoReminder EventType = "todo.reminder"
+)
+
+var EventTypes = []EventType{TodoCreated, TodoCompleted, TodoOverdue, TodoReminder}
+
+const (
+	SignatureHeader = "X-Todo-Signature"
+	TimestampHeader = "X-Todo-Timestamp"
+	EventHeader     = "X-Todo-Event"
+	DeliveryHeader  = "X-Todo-Delivery"
+)
+
+// Subscription asks for events to be POSTed to URL. An empty Events list
+// subscribes to every event type.
+type Subscription struct {
+	Id      string      `json:"id"`
+	URL     string      `json:"url"`
+	Events  []EventType `json:"events,omitempty"`
+	Secret  string      `json:"secret,omitempty"`
+	Created time.Time   `json:"created"`
+}
+
+func (s Subscription) Wants(e EventType) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, e)
+}
+
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return types.Invalid("url", "must be an absolute http or https URL, got %q", s.URL)
+	}
+	for _, e := range s.Events {
+		if !slices.Contains(EventTypes, e) {
+			return types.Invalid("events", "unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+// Payload is the JSON body POSTed to subscribers.
+type Payload struct {
+	DeliveryId string     `json:"delivery_id"`
+	Event      EventType  `json:"event"`
+	TodoId     string     `json:"todo_id"`
+	Todo       types.Todo `json:"todo"`
+	Reminder   string     `json:"reminder,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// Sign returns the signature header value for body sent at timestamp. The
+// HMAC-SHA256 covers "<timestamp>.<body>" so a captured request can't be
+// replayed later with a new timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a received delivery.
+func Verify(secret, signature, timestamp string, body []byte) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	expected := Sign(secret, time.Unix(unix, 0), body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}